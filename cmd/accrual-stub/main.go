@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/accrualstub"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
+
+func main() {
+	var (
+		address        string
+		defaultAccrual float64
+		cfg            accrualstub.Config
+	)
+
+	flag.StringVar(&address, "a", ":8888", "server address")
+	flag.DurationVar(&cfg.Latency, "latency", 0, "delay before every response")
+	flag.DurationVar(&cfg.StepDuration, "step", 2*time.Second, "time spent in REGISTERED and then in PROCESSING")
+	flag.IntVar(&cfg.RateLimit, "rate-limit", 0, "max GET /api/orders/{number} requests per minute, 0 disables 429")
+	flag.DurationVar(&cfg.RetryAfter, "retry-after", time.Minute, "Retry-After sent with 429")
+	flag.BoolVar(&cfg.AutoRegister, "auto-register", true, "register unknown orders on first poll")
+	flag.Float64Var(&defaultAccrual, "default-accrual", 100, "accrual for auto-registered orders")
+	flag.Parse()
+
+	cfg.DefaultAccrual = money.FromFloat(defaultAccrual)
+
+	log.Printf("Starting accrual stub on %s (step %s, latency %s, rate limit %d/min)",
+		address, cfg.StepDuration, cfg.Latency, cfg.RateLimit)
+	if err := http.ListenAndServe(address, accrualstub.New(cfg)); err != nil {
+		log.Fatalf("accrual stub stopped: %v", err)
+	}
+}
@@ -1,21 +1,28 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/GarikMirzoyan/gophermart/internal/app"
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	appInstance, err := app.New()
 	if err != nil {
 		log.Fatalf("error initializing app: %v", err)
 	}
 
-	err = appInstance.Run()
+	log.Printf("App initialized with config: %+v", appInstance.Config)
+
+	err = appInstance.Run(ctx)
 	if err != nil {
 		log.Fatalf("error running app: %v", err)
 	}
-
-	log.Printf("App initialized with config: %+v", appInstance.Config)
 }
@@ -0,0 +1,280 @@
+// Package accrualstub — локальная замена системы расчёта начислений для разработки и тестов.
+// Реализует API из спецификации: регистрацию механик вознаграждения (POST /api/goods),
+// регистрацию заказов (POST /api/orders) и опрос расчёта (GET /api/orders/{number}).
+package accrualstub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+	"github.com/go-chi/chi/v5"
+)
+
+type Config struct {
+	Latency        time.Duration // задержка перед каждым ответом
+	StepDuration   time.Duration // сколько заказ проводит в REGISTERED и затем в PROCESSING
+	RateLimit      int           // максимум GET /api/orders/{number} в минуту, 0 — без ограничения
+	RetryAfter     time.Duration // значение Retry-After в ответе 429
+	AutoRegister   bool          // регистрировать неизвестные заказы при первом опросе
+	DefaultAccrual money.Amount  // начисление для автоматически зарегистрированных заказов
+	Now            func() time.Time
+}
+
+type RewardType string
+
+const (
+	RewardPercent RewardType = "%"
+	RewardPoints  RewardType = "pt"
+)
+
+type rule struct {
+	Match      string       `json:"match"`
+	Reward     money.Amount `json:"reward"`
+	RewardType RewardType   `json:"reward_type"`
+}
+
+type good struct {
+	Description string       `json:"description"`
+	Price       money.Amount `json:"price"`
+}
+
+type registeredOrder struct {
+	number       string
+	registeredAt time.Time
+	accrual      *money.Amount // nil — ни один товар не подошёл под механики, заказ будет INVALID
+}
+
+type Server struct {
+	cfg    Config
+	router chi.Router
+
+	mu     sync.Mutex
+	rules  []rule
+	orders map[string]*registeredOrder
+
+	window      time.Time // начало текущей минуты для ограничения частоты
+	windowCalls int
+}
+
+func New(cfg Config) *Server {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Minute
+	}
+
+	s := &Server{
+		cfg:    cfg,
+		orders: make(map[string]*registeredOrder),
+	}
+
+	r := chi.NewRouter()
+	r.Use(s.latency)
+	r.Post("/api/goods", s.registerRule)
+	r.Post("/api/orders", s.registerOrder)
+	r.Get("/api/orders/{number}", s.getOrder)
+	s.router = r
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+func (s *Server) latency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.Latency > 0 {
+			select {
+			case <-time.After(s.cfg.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) registerRule(w http.ResponseWriter, r *http.Request) {
+	var req rule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Match == "" || !req.Reward.IsPositive() || (req.RewardType != RewardPercent && req.RewardType != RewardPoints) {
+		http.Error(w, "invalid reward rule", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.rules {
+		if existing.Match == req.Match {
+			http.Error(w, "match already registered", http.StatusConflict)
+			return
+		}
+	}
+	s.rules = append(s.rules, req)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) registerOrder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Order string `json:"order"`
+		Goods []good `json:"goods"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if !validNumber(req.Order) {
+		http.Error(w, "invalid order number", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orders[req.Order]; ok {
+		http.Error(w, "order already registered", http.StatusConflict)
+		return
+	}
+	s.orders[req.Order] = &registeredOrder{
+		number:       req.Order,
+		registeredAt: s.cfg.Now(),
+		accrual:      s.calculate(req.Goods),
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+	number := chi.URLParam(r, "number")
+
+	s.mu.Lock()
+	if !s.allow() {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.cfg.RetryAfter.Seconds())))
+		http.Error(w, fmt.Sprintf("No more than %d requests per minute allowed", s.cfg.RateLimit), http.StatusTooManyRequests)
+		return
+	}
+
+	o, ok := s.orders[number]
+	if !ok && s.cfg.AutoRegister && validNumber(number) {
+		accrual := s.cfg.DefaultAccrual
+		o = &registeredOrder{number: number, registeredAt: s.cfg.Now(), accrual: &accrual}
+		s.orders[number] = o
+		ok = true
+	}
+	var resp loyalty.OrderAccrual
+	if ok {
+		resp = s.status(o)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// status вычисляет состояние расчёта по времени, прошедшему с регистрации:
+// REGISTERED → PROCESSING → PROCESSED/INVALID, по StepDuration на каждый шаг
+func (s *Server) status(o *registeredOrder) loyalty.OrderAccrual {
+	elapsed := s.cfg.Now().Sub(o.registeredAt)
+	resp := loyalty.OrderAccrual{Order: o.number}
+
+	switch {
+	case elapsed < s.cfg.StepDuration:
+		resp.Status = loyalty.StatusRegistered
+	case elapsed < 2*s.cfg.StepDuration:
+		resp.Status = loyalty.StatusProcessing
+	case o.accrual == nil:
+		resp.Status = loyalty.StatusInvalid
+	default:
+		resp.Status = loyalty.StatusProcessed
+		accrual := *o.accrual
+		resp.Accrual = &accrual
+	}
+	return resp
+}
+
+// allow считает запросы в текущей минуте. Вызывается под s.mu.
+func (s *Server) allow() bool {
+	if s.cfg.RateLimit <= 0 {
+		return true
+	}
+
+	now := s.cfg.Now()
+	if now.Sub(s.window) >= time.Minute {
+		s.window = now
+		s.windowCalls = 0
+	}
+	if s.windowCalls >= s.cfg.RateLimit {
+		log.Printf("[ACCRUAL STUB] rate limit exceeded")
+		return false
+	}
+	s.windowCalls++
+	return true
+}
+
+// calculate применяет к каждому товару первую подходящую механику. Вызывается под s.mu.
+func (s *Server) calculate(goods []good) *money.Amount {
+	var total money.Amount
+	matched := false
+
+	for _, g := range goods {
+		for _, rl := range s.rules {
+			if !strings.Contains(g.Description, rl.Match) {
+				continue
+			}
+			matched = true
+			switch rl.RewardType {
+			case RewardPercent:
+				total += percentOf(g.Price, rl.Reward)
+			case RewardPoints:
+				total += rl.Reward
+			}
+			break
+		}
+	}
+
+	if !matched {
+		return nil
+	}
+	return &total
+}
+
+// percentOf возвращает percent% от price с округлением до сотых
+func percentOf(price, percent money.Amount) money.Amount {
+	// price и percent в сотых: price * percent / 100 / 100, округление половиной вверх
+	v := price.Minor() * percent.Minor()
+	return money.FromMinor((v + 5000) / 10000)
+}
+
+func validNumber(number string) bool {
+	if number == "" {
+		return false
+	}
+	for _, c := range number {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return order.ValidateLuhn(number)
+}
@@ -0,0 +1,133 @@
+package accrualstub_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/accrualstub"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func post(t *testing.T, url, body string) int {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestOrderStatusProgression(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	srv := httptest.NewServer(accrualstub.New(accrualstub.Config{StepDuration: time.Second, Now: clock.Now}))
+	defer srv.Close()
+	client := loyalty.NewClient(srv.URL)
+
+	assert.Equal(t, http.StatusOK, post(t, srv.URL+"/api/goods", `{"match": "Bork", "reward": 10, "reward_type": "%"}`))
+	assert.Equal(t, http.StatusOK, post(t, srv.URL+"/api/goods", `{"match": "Miele", "reward": 5.5, "reward_type": "pt"}`))
+	assert.Equal(t, http.StatusConflict, post(t, srv.URL+"/api/goods", `{"match": "Bork", "reward": 1, "reward_type": "pt"}`))
+
+	assert.Equal(t, http.StatusAccepted, post(t, srv.URL+"/api/orders",
+		`{"order": "12345678903", "goods": [{"description": "Чайник Bork", "price": 7000}, {"description": "Пылесос Miele", "price": 100}]}`))
+	assert.Equal(t, http.StatusConflict, post(t, srv.URL+"/api/orders", `{"order": "12345678903", "goods": []}`))
+	assert.Equal(t, http.StatusBadRequest, post(t, srv.URL+"/api/orders", `{"order": "12345678900", "goods": []}`))
+
+	accrual, err := client.GetAccrual(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, loyalty.StatusRegistered, accrual.Status)
+
+	clock.Advance(time.Second)
+	accrual, err = client.GetAccrual(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, loyalty.StatusProcessing, accrual.Status)
+	assert.Nil(t, accrual.Accrual)
+
+	clock.Advance(time.Second)
+	accrual, err = client.GetAccrual(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, loyalty.StatusProcessed, accrual.Status)
+	require.NotNil(t, accrual.Accrual)
+	assert.Equal(t, money.FromFloat(705.5), *accrual.Accrual)
+}
+
+func TestOrderWithoutMatchingGoodsIsInvalid(t *testing.T) {
+	srv := httptest.NewServer(accrualstub.New(accrualstub.Config{}))
+	defer srv.Close()
+
+	assert.Equal(t, http.StatusAccepted, post(t, srv.URL+"/api/orders",
+		`{"order": "79927398713", "goods": [{"description": "Стол", "price": 100}]}`))
+
+	accrual, err := loyalty.NewClient(srv.URL).GetAccrual(context.Background(), "79927398713")
+	require.NoError(t, err)
+	assert.Equal(t, loyalty.StatusInvalid, accrual.Status)
+}
+
+func TestUnknownOrder(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(accrualstub.New(accrualstub.Config{}))
+	defer srv.Close()
+
+	accrual, err := loyalty.NewClient(srv.URL).GetAccrual(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Nil(t, accrual)
+
+	auto := httptest.NewServer(accrualstub.New(accrualstub.Config{AutoRegister: true, DefaultAccrual: money.FromFloat(42)}))
+	defer auto.Close()
+
+	accrual, err = loyalty.NewClient(auto.URL).GetAccrual(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, loyalty.StatusProcessed, accrual.Status)
+	assert.Equal(t, money.FromFloat(42), *accrual.Accrual)
+}
+
+func TestRateLimitInjection(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	srv := httptest.NewServer(accrualstub.New(accrualstub.Config{
+		RateLimit:    2,
+		RetryAfter:   30 * time.Second,
+		AutoRegister: true,
+		Now:          clock.Now,
+	}))
+	defer srv.Close()
+	client := loyalty.NewClient(srv.URL)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetAccrual(ctx, "12345678903")
+		require.NoError(t, err)
+	}
+
+	_, err := client.GetAccrual(ctx, "12345678903")
+	retryAfter, ok := loyalty.AsRateLimit(err)
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	clock.Advance(time.Minute)
+	_, err = client.GetAccrual(ctx, "12345678903")
+	assert.NoError(t, err)
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,14 +12,23 @@ import (
 	"github.com/GarikMirzoyan/gophermart/internal/config"
 	delivery "github.com/GarikMirzoyan/gophermart/internal/delivery/http"
 	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/handler"
+	domainorder "github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	domainthrottle "github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
 	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/auth"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/events"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/notify"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/password"
 	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
 	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
 	LoyaltyHandler "github.com/GarikMirzoyan/gophermart/internal/loyalty/handler"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/admin"
 	authusecase "github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
 	"github.com/GarikMirzoyan/gophermart/internal/usecase/balance"
 	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/throttle"
 	"github.com/GarikMirzoyan/gophermart/internal/usecase/withdrawal"
+	"github.com/GarikMirzoyan/gophermart/internal/worker"
 	"github.com/joho/godotenv"
 	"github.com/pressly/goose/v3"
 
@@ -30,9 +40,17 @@ type App struct {
 	JWTManager        *auth.JWTManager
 	OrderService      *order.Service
 	AuthService       *authusecase.Service
+	TokenService      *authusecase.TokenService
+	PasswordService   *authusecase.PasswordService
+	ThrottleService   *throttle.Service
 	BalanceService    balance.IService
 	WithdrawalService *withdrawal.Service
 	LoyaltyService    *loyalty.Service
+	AdminService      *admin.Service
+	IdempotencyKeys   *storage.IdempotencyPG
+	Events            *events.Broker
+	EventsRelay       *events.PGRelay
+	AccrualPool       *worker.AccrualPool
 	DB                *sql.DB
 }
 
@@ -46,7 +64,7 @@ func New() (*App, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	db, err := sql.Open("postgres", cfg.DatabaseURI)
+	db, err := sql.Open("postgres", string(cfg.DatabaseURI))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
@@ -55,16 +73,52 @@ func New() (*App, error) {
 		log.Fatalf("failed to apply migrations: %v", err)
 	}
 
-	// TODO: сделать секрет ключ из переменной окружения
-	jwtManager := auth.NewJWTManager("supersecretkey", time.Hour*24)
+	keys, err := loadJWTKeys(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+	jwtManager := auth.NewJWTManager(keys, cfg.AccessTokenTTL)
 
 	// Для работы с пользователями
 	userRepo := storage.NewUserPG(db)
-	authService := authusecase.New(userRepo)
+	passwordPolicy := newPasswordPolicy(cfg)
+	passwordHasher := newPasswordHasher(cfg)
+	authService := authusecase.New(userRepo, passwordHasher, passwordPolicy)
+
+	// Для работы с сессиями
+	tokenRepo := storage.NewTokenPG(db)
+	tokenService := authusecase.NewTokenService(tokenRepo, userRepo, jwtManager, cfg.RefreshTokenTTL)
+
+	// Защита от подбора пароля
+	var throttleStore domainthrottle.Store = storage.NewThrottlePG(db)
+	if cfg.LoginThrottleStore == "memory" {
+		throttleStore = storage.NewThrottleMemory()
+	}
+	throttleService := throttle.New(throttleStore, throttle.Config{
+		LoginPolicy:   domainthrottle.DefaultLoginPolicy,
+		IPPolicy:      domainthrottle.DefaultIPPolicy,
+		ResetPolicy:   domainthrottle.DefaultResetPolicy,
+		ResetIPPolicy: domainthrottle.DefaultResetIPPolicy,
+	})
+
+	// Смена и сброс пароля. Без явно настроенной доставки сброс пароля отключён,
+	// вывод токенов в лог включается только флагом для локальной разработки
+	var notifier authusecase.Notifier
+	switch {
+	case cfg.NotifyFile != "":
+		notifier = notify.NewFileNotifier(cfg.NotifyFile)
+	case cfg.NotifyLog:
+		log.Printf("WARNING: NOTIFY_LOG is set, password reset tokens are written to the log")
+		notifier = notify.NewLogNotifier()
+	default:
+		log.Printf("Password reset is disabled: set NOTIFY_FILE to deliver reset tokens")
+	}
+	passwordService := authusecase.NewPasswordService(userRepo, userRepo, notifier, throttleService, passwordHasher, passwordPolicy, cfg.PasswordResetTTL)
 
 	// Для работы с балансом
 	balanceRepo := storage.NewBalancePG(db)
-	balanceService := balance.New(balanceRepo)
+	ledgerRepo := storage.NewLedgerPG(db)
+	balanceService := balance.New(balanceRepo, ledgerRepo)
 
 	// Для работы с выводами
 	withdrawalRepo := storage.NewWithdrawalPG(db)
@@ -74,45 +128,231 @@ func New() (*App, error) {
 	// Для работы с баллами
 	loyaltyService := loyalty.New(loyaltyClient)
 
+	// События заказов для потока /api/user/events, общие для всех реплик
+	eventBroker := events.NewBroker(events.DefaultBuffer)
+	eventsRelay, err := events.NewPGRelay(db, eventBroker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create events relay: %w", err)
+	}
+
 	// Для работы с заказами
 	orderRepo := storage.NewOrderPG(db)
-	orderService := order.New(orderRepo, loyaltyService, balanceService)
+	orderService := order.New(orderRepo, loyaltyService, domainorder.Backoff{
+		Base: cfg.AccrualBackoffBase,
+		Max:  cfg.AccrualBackoffMax,
+	}, domainorder.ClawbackPolicy(cfg.ClawbackPolicy), eventsRelay)
+
+	// Администрирование
+	auditRepo := storage.NewAuditPG(db)
+	adminService := admin.New(userRepo, orderRepo, withdrawalRepo, balanceService, tokenService, withdrawalService, orderService, auditRepo)
+	if err := adminService.SyncAdmins(context.Background(), cfg.AdminUserIDs); err != nil {
+		return nil, fmt.Errorf("failed to sync admins: %w", err)
+	}
+
+	// Пул опроса системы начислений
+	accrualPool := worker.NewAccrualPool(orderService, worker.Config{
+		InstanceID:   cfg.InstanceID,
+		Workers:      cfg.AccrualWorkers,
+		BatchSize:    cfg.AccrualBatchSize,
+		PollInterval: cfg.AccrualPollInterval,
+		JobTimeout:   cfg.AccrualJobTimeout,
+		Lease:        cfg.AccrualLease,
+	})
 
 	return &App{
 		Config:            cfg,
 		JWTManager:        jwtManager,
 		OrderService:      orderService,
 		AuthService:       authService,
+		TokenService:      tokenService,
+		PasswordService:   passwordService,
+		ThrottleService:   throttleService,
 		BalanceService:    balanceService,
 		WithdrawalService: withdrawalService,
 		LoyaltyService:    loyaltyService,
+		AdminService:      adminService,
+		IdempotencyKeys:   storage.NewIdempotencyPG(db, cfg.IdempotencyTTL, cfg.IdempotencyLease),
+		Events:            eventBroker,
+		EventsRelay:       eventsRelay,
+		AccrualPool:       accrualPool,
 		DB:                db,
 	}, nil
 }
 
-func (a *App) Run() error {
-	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
+func newPasswordHasher(cfg *config.Config) *password.Hasher {
+	if cfg.PasswordHasher == "bcrypt" {
+		return password.NewBcrypt(cfg.BcryptCost, cfg.PasswordHashConcurrency)
+	}
+	return password.NewArgon2id(password.Argon2idParams{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+	}, cfg.PasswordHashConcurrency)
+}
+
+func newPasswordPolicy(cfg *config.Config) user.PasswordPolicy {
+	policy := user.PasswordPolicy{MinLength: cfg.PasswordMinLength}
+	for _, class := range cfg.PasswordRequire {
+		switch class {
+		case "upper":
+			policy.RequireUpper = true
+		case "lower":
+			policy.RequireLower = true
+		case "digit":
+			policy.RequireDigit = true
+		case "symbol":
+			policy.RequireSymbol = true
+		}
+	}
+	return policy
+}
+
+// loadJWTKeys выбирает источник ключей подписи. Без настроек генерируется случайный
+// ключ: токены не переживут перезапуск и не будут приняты другими репликами.
+func loadJWTKeys(cfg *config.Config) (*auth.KeySet, error) {
+	switch {
+	case cfg.JWTKeysFile != "":
+		return auth.LoadKeySetFile(cfg.JWTKeysFile)
+	case cfg.JWTSecret != "":
+		previous := make([]string, 0, len(cfg.JWTPreviousSecrets))
+		for _, secret := range cfg.JWTPreviousSecrets {
+			previous = append(previous, string(secret))
+		}
+		return auth.NewSecretKeySet(string(cfg.JWTSecret), previous...)
+	default:
+		log.Printf("WARNING: neither JWT_SECRET nor JWT_KEYS_FILE is set, using an ephemeral signing key")
+		return auth.NewEphemeralKeySet()
+	}
+}
+
+// purger удаляет истёкшие записи и возвращает их число
+type purger interface {
+	Purge(ctx context.Context) (int64, error)
+}
 
-		for t := range ticker.C {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// runCleanup раз в interval удаляет истёкшие записи служебных таблиц, пока не отменён ctx
+func runCleanup(ctx context.Context, interval time.Duration, purgers map[string]purger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-			log.Printf("[ACCRUAL WORKER] TICK at %s", t.Format(time.RFC3339))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for name, p := range purgers {
+				n, err := p.Purge(ctx)
+				if err != nil {
+					log.Printf("failed to purge expired %s: %v", name, err)
+					continue
+				}
+				if n > 0 {
+					log.Printf("Purged %d expired %s", n, name)
+				}
+			}
+		}
+	}
+}
+
+// runReconcile раз в interval сверяет сохранённые балансы с журналом проводок, пока не отменён ctx.
+// Расхождения только пишутся в лог: исправлять их должен человек.
+func runReconcile(ctx context.Context, interval time.Duration, balances balance.IService) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-			a.OrderService.ProcessPendingOrders(ctx)
-			cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			mismatched, err := balances.ReconcileAll(ctx)
+			if err != nil {
+				log.Printf("failed to reconcile balances: %v", err)
+				continue
+			}
+			if mismatched > 0 {
+				log.Printf("WARNING: %d balances do not match the ledger", mismatched)
+			}
+		}
+	}
+}
+
+// Run запускает HTTP-сервер и пул начислений и блокируется до отмены ctx.
+// Остановка идёт по порядку: сервер перестаёт принимать запросы и дожидается текущих,
+// пул дообрабатывает начатые заказы, затем закрывается пул соединений с БД.
+// На всё отводится Config.ShutdownTimeout.
+func (a *App) Run(ctx context.Context) error {
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		a.AccrualPool.Run(workerCtx)
+	}()
+	go runCleanup(workerCtx, a.Config.CleanupInterval, map[string]purger{
+		"idempotency keys": a.IdempotencyKeys,
+		"login attempts":   a.ThrottleService,
+	})
+	go runReconcile(workerCtx, a.Config.ReconcileInterval, a.BalanceService)
+	go func() {
+		if err := a.EventsRelay.Listen(workerCtx, string(a.Config.DatabaseURI)); err != nil {
+			log.Printf("Order events from other replicas are unavailable: %v", err)
 		}
 	}()
 
-	authHandler := handler.NewAuthHandler(a.AuthService, a.JWTManager)
+	authHandler := handler.NewAuthHandler(a.AuthService, a.TokenService, a.ThrottleService, a.Config.TrustedProxies)
+	passwordHandler := handler.NewPasswordHandler(a.PasswordService, a.TokenService, a.ThrottleService, a.Config.TrustedProxies)
 	orderHandler := handler.NewOrderHandler(a.OrderService)
 	balanceHandler := handler.NewBalanceHandler(a.BalanceService)
 	withdrawalHandler := handler.NewWithdrawalHandler(a.WithdrawalService)
 	loyaltyHandler := LoyaltyHandler.NewLoyaltyHandler(a.LoyaltyService)
-	router := delivery.NewRouter(authHandler, orderHandler, balanceHandler, withdrawalHandler, loyaltyHandler, a.JWTManager)
+	keysHandler := handler.NewKeysHandler(a.JWTManager)
+	adminHandler := handler.NewAdminHandler(a.AdminService)
+	webhookHandler := handler.NewAccrualWebhookHandler(a.OrderService, string(a.Config.AccrualWebhookSecret))
+	eventsHandler := handler.NewEventsHandler(a.OrderService, a.Events, a.TokenService, a.Config.EventsHeartbeat)
+	router := delivery.NewRouter(authHandler, passwordHandler, orderHandler, balanceHandler, withdrawalHandler, loyaltyHandler, keysHandler, adminHandler, webhookHandler, eventsHandler, a.JWTManager, a.TokenService, a.IdempotencyKeys)
+
+	server := &http.Server{
+		Addr:    a.Config.RunAddress,
+		Handler: router,
+	}
+	// Потоки событий не завершаются сами: закрытие подписок отпускает их при Shutdown
+	server.RegisterOnShutdown(a.Events.Close)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on %s...", a.Config.RunAddress)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Printf("Shutting down, draining for up to %s...", a.Config.ShutdownTimeout)
+	case runErr = <-serverErr:
+		log.Printf("Server stopped: %v", runErr)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+
+	stopWorker()
+	select {
+	case <-workerDone:
+	case <-shutdownCtx.Done():
+		log.Printf("Accrual worker did not stop within %s", a.Config.ShutdownTimeout)
+	}
+
+	if err := a.DB.Close(); err != nil {
+		log.Printf("failed to close DB: %v", err)
+	}
 
-	defer a.DB.Close()
-	log.Printf("Starting server on %s...", a.Config.RunAddress)
-	return http.ListenAndServe(a.Config.RunAddress, router)
+	log.Printf("Shutdown complete")
+	return runErr
 }
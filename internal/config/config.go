@@ -4,40 +4,352 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
 	ErrMissingConfig = errors.New("missing required configuration")
+	ErrInvalidConfig = errors.New("invalid configuration")
 )
 
+// Secret — строка, которая не попадает в логи при выводе конфигурации
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
+
+// DSN — строка подключения к БД; при выводе конфигурации пароль скрывается
+type DSN string
+
+var dsnPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+func (d DSN) String() string {
+	s := string(d)
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" {
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.Redacted()
+	}
+	// Формат "host=... password=..."
+	return dsnPassword.ReplaceAllString(s, "${1}xxxxx")
+}
+
 type Config struct {
 	RunAddress     string
-	DatabaseURI    string
+	DatabaseURI    DSN
 	AccrualAddress string
+
+	// Идентификатор экземпляра; по умолчанию hostname-pid
+	InstanceID string
+
+	// Пул опроса системы начислений
+	AccrualWorkers      int
+	AccrualBatchSize    int
+	AccrualPollInterval time.Duration
+	AccrualJobTimeout   time.Duration
+	AccrualLease        time.Duration
+	AccrualBackoffBase  time.Duration
+	AccrualBackoffMax   time.Duration
+
+	// Ключи подписи токенов: либо секрет HS256 (с предыдущими секретами на время
+	// ротации), либо JSON-файл с набором ключей
+	JWTSecret          Secret
+	JWTPreviousSecrets []Secret
+	JWTKeysFile        string
+
+	// Где хранить счётчики неудачных входов: postgres или memory
+	LoginThrottleStore string
+
+	// Адреса обратных прокси, которым доверяется X-Forwarded-For при определении IP клиента
+	TrustedProxies []netip.Prefix
+
+	// Пользователи с ролью администратора. При старте роль приводится к этому списку:
+	// отсутствующий id останавливает запуск, администраторы не из списка понижаются.
+	AdminUserIDs []int64
+
+	// Требования к паролю: минимальная длина и обязательные классы символов
+	// (upper, lower, digit, symbol)
+	PasswordMinLength int
+	PasswordRequire   []string
+
+	// Алгоритм хеширования паролей (argon2id или bcrypt) и его параметры
+	PasswordHasher    string
+	BcryptCost        int
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+
+	// Сколько хешей паролей считается одновременно. Пиковая память argon2id —
+	// PasswordHashConcurrency * Argon2Memory КиБ.
+	PasswordHashConcurrency int
+
+	// Время жизни токена сброса пароля и файл для уведомлений. NotifyLog пишет уведомления
+	// с токенами в лог — только для локальной разработки. Без файла и NotifyLog сброс пароля отключён.
+	PasswordResetTTL time.Duration
+	NotifyFile       string
+	NotifyLog        bool
+
+	// Время жизни токенов доступа и refresh-токенов
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Что делать, если начисление по возвращённому заказу уже потрачено:
+	// negative — увести баланс в минус, debt — записать долг
+	ClawbackPolicy string
+
+	// Секрет для проверки подписи webhook системы начислений; без него webhook отключён
+	AccrualWebhookSecret Secret
+
+	// Сколько хранится ответ на запрос с заголовком Idempotency-Key
+	IdempotencyTTL time.Duration
+
+	// Сколько ключ может оставаться «в работе»: после падения реплики посреди запроса
+	// ключ освобождается по истечении этого срока, а не IdempotencyTTL
+	IdempotencyLease time.Duration
+
+	// Как часто удаляются истёкшие записи служебных таблиц
+	CleanupInterval time.Duration
+
+	// Как часто сохранённые балансы сверяются с журналом проводок
+	ReconcileInterval time.Duration
+
+	// Как часто в потоке событий пишется heartbeat, чтобы прокси не закрывали соединение
+	EventsHeartbeat time.Duration
+
+	// Сколько ждать завершения запросов и начатых заказов при остановке
+	ShutdownTimeout time.Duration
 }
 
 func Load() (*Config, error) {
 	cfg := &Config{}
 
 	flag.StringVar(&cfg.RunAddress, "a", getEnv("RUN_ADDRESS", ":8080"), "server address")
-	flag.StringVar(&cfg.DatabaseURI, "d", getEnv("DATABASE_URI", ""), "database URI")
+	databaseURI := flag.String("d", getEnv("DATABASE_URI", ""), "database URI")
 	flag.StringVar(&cfg.AccrualAddress, "r", getEnv("ACCRUAL_SYSTEM_ADDRESS", ""), "accrual system address")
+
+	flag.StringVar(&cfg.InstanceID, "instance-id", getEnv("INSTANCE_ID", ""), "instance id used to claim orders")
+
+	jwtSecret := flag.String("jwt-secret", getEnv("JWT_SECRET", ""), "HS256 secret for signing tokens")
+	flag.StringVar(&cfg.JWTKeysFile, "jwt-keys-file", getEnv("JWT_KEYS_FILE", ""), "path to JSON file with token signing keys")
+	adminUserIDs := flag.String("admin-user-ids", getEnv("ADMIN_USER_IDS", ""), "comma-separated ids of users holding the admin role")
+	passwordRequire := flag.String("password-require", getEnv("PASSWORD_REQUIRE", ""), "comma-separated required password character classes: upper, lower, digit, symbol")
+	trustedProxies := flag.String("trusted-proxies", getEnv("TRUSTED_PROXIES", ""), "comma-separated addresses or CIDRs of proxies whose X-Forwarded-For is trusted")
+	previousSecrets := flag.String("jwt-previous-secrets", getEnv("JWT_PREVIOUS_SECRETS", ""), "comma-separated HS256 secrets still accepted during rotation")
+
+	flag.StringVar(&cfg.LoginThrottleStore, "login-throttle-store", getEnv("LOGIN_THROTTLE_STORE", "postgres"), "failed login counters storage: postgres or memory")
+
+	flag.StringVar(&cfg.PasswordHasher, "password-hasher", getEnv("PASSWORD_HASHER", "argon2id"), "password hashing algorithm: argon2id or bcrypt")
+	flag.StringVar(&cfg.ClawbackPolicy, "clawback-policy", getEnv("CLAWBACK_POLICY", "negative"), "how to claw back spent accruals of returned orders: negative or debt")
+	webhookSecret := flag.String("accrual-webhook-secret", getEnv("ACCRUAL_WEBHOOK_SECRET", ""), "HMAC secret for accrual system webhooks")
+	flag.StringVar(&cfg.NotifyFile, "notify-file", getEnv("NOTIFY_FILE", ""), "file to append user notifications to")
+
+	var errs []error
+	flag.IntVar(&cfg.AccrualWorkers, "accrual-workers", getEnvInt("ACCRUAL_WORKERS", 4, &errs), "number of accrual workers")
+	flag.IntVar(&cfg.AccrualBatchSize, "accrual-batch-size", getEnvInt("ACCRUAL_BATCH_SIZE", 20, &errs), "max orders queued for accrual at once")
+	flag.DurationVar(&cfg.AccrualPollInterval, "accrual-poll-interval", getEnvDuration("ACCRUAL_POLL_INTERVAL", time.Second, &errs), "how often to refill the accrual queue")
+	flag.DurationVar(&cfg.AccrualJobTimeout, "accrual-job-timeout", getEnvDuration("ACCRUAL_JOB_TIMEOUT", 10*time.Second, &errs), "timeout for processing one order")
+	flag.DurationVar(&cfg.AccrualLease, "accrual-lease", getEnvDuration("ACCRUAL_LEASE", time.Minute, &errs), "how long a claimed order is reserved for this instance")
+	flag.DurationVar(&cfg.AccrualBackoffBase, "accrual-backoff-base", getEnvDuration("ACCRUAL_BACKOFF_BASE", time.Second, &errs), "initial delay between polls of one order")
+	flag.DurationVar(&cfg.AccrualBackoffMax, "accrual-backoff-max", getEnvDuration("ACCRUAL_BACKOFF_MAX", 5*time.Minute, &errs), "max delay between polls of one order")
+	flag.DurationVar(&cfg.AccessTokenTTL, "access-token-ttl", getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute, &errs), "access token lifetime")
+	flag.DurationVar(&cfg.RefreshTokenTTL, "refresh-token-ttl", getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour, &errs), "refresh token lifetime")
+	flag.IntVar(&cfg.PasswordMinLength, "password-min-length", getEnvInt("PASSWORD_MIN_LENGTH", 8, &errs), "minimum password length")
+	flag.IntVar(&cfg.BcryptCost, "bcrypt-cost", getEnvInt("BCRYPT_COST", 10, &errs), "bcrypt cost")
+	flag.IntVar(&cfg.Argon2Memory, "argon2-memory", getEnvInt("ARGON2_MEMORY", 64*1024, &errs), "argon2id memory in KiB")
+	flag.IntVar(&cfg.Argon2Iterations, "argon2-iterations", getEnvInt("ARGON2_ITERATIONS", 3, &errs), "argon2id iterations")
+	flag.IntVar(&cfg.Argon2Parallelism, "argon2-parallelism", getEnvInt("ARGON2_PARALLELISM", 2, &errs), "argon2id parallelism")
+	flag.IntVar(&cfg.PasswordHashConcurrency, "password-hash-concurrency", getEnvInt("PASSWORD_HASH_CONCURRENCY", runtime.NumCPU(), &errs), "max password hashes computed at once")
+	flag.BoolVar(&cfg.NotifyLog, "notify-log", getEnvBool("NOTIFY_LOG", false, &errs), "write user notifications with reset tokens to the log (local development only)")
+	flag.DurationVar(&cfg.PasswordResetTTL, "password-reset-ttl", getEnvDuration("PASSWORD_RESET_TTL", time.Hour, &errs), "password reset token lifetime")
+	flag.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour, &errs), "how long responses to requests with Idempotency-Key are kept")
+	flag.DurationVar(&cfg.IdempotencyLease, "idempotency-lease", getEnvDuration("IDEMPOTENCY_LEASE", time.Minute, &errs), "how long an Idempotency-Key stays reserved by an unfinished request")
+	flag.DurationVar(&cfg.CleanupInterval, "cleanup-interval", getEnvDuration("CLEANUP_INTERVAL", time.Hour, &errs), "how often expired rows are purged")
+	flag.DurationVar(&cfg.ReconcileInterval, "reconcile-interval", getEnvDuration("RECONCILE_INTERVAL", 24*time.Hour, &errs), "how often stored balances are checked against the ledger")
+	flag.DurationVar(&cfg.EventsHeartbeat, "events-heartbeat", getEnvDuration("EVENTS_HEARTBEAT", 15*time.Second, &errs), "heartbeat interval of the order events stream")
+	flag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", getEnvDuration("SHUTDOWN_TIMEOUT", 15*time.Second, &errs), "graceful shutdown drain timeout")
 	flag.Parse()
 
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, item := range splitList(*trustedProxies) {
+		prefix, err := parsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("%w: TRUSTED_PROXIES must be comma-separated addresses or CIDRs", ErrInvalidConfig)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, prefix)
+	}
+	for _, item := range splitList(*adminUserIDs) {
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: ADMIN_USER_IDS must be comma-separated user ids", ErrInvalidConfig)
+		}
+		cfg.AdminUserIDs = append(cfg.AdminUserIDs, id)
+	}
+	cfg.PasswordRequire = splitList(*passwordRequire)
+	cfg.DatabaseURI = DSN(*databaseURI)
+	cfg.JWTSecret = Secret(*jwtSecret)
+	cfg.AccrualWebhookSecret = Secret(*webhookSecret)
+	for _, secret := range splitList(*previousSecrets) {
+		cfg.JWTPreviousSecrets = append(cfg.JWTPreviousSecrets, Secret(secret))
+	}
 	if cfg.DatabaseURI == "" {
 		return nil, fmt.Errorf("%w: DATABASE_URI is required", ErrMissingConfig)
 	}
 	if cfg.AccrualAddress == "" {
 		return nil, fmt.Errorf("%w: ACCRUAL_SYSTEM_ADDRESS is required", ErrMissingConfig)
 	}
+	if cfg.AccrualWorkers <= 0 || cfg.AccrualBatchSize <= 0 {
+		return nil, fmt.Errorf("%w: accrual workers and batch size must be positive", ErrInvalidConfig)
+	}
+	if cfg.AccrualJobTimeout <= 0 {
+		return nil, fmt.Errorf("%w: ACCRUAL_JOB_TIMEOUT must be positive", ErrInvalidConfig)
+	}
+	// Захваченный заказ ждёт в очереди, пока воркеры разберут заказы перед ним, — до
+	// ceil(BatchSize/Workers) заказов по JobTimeout. Аренда должна пережить это ожидание,
+	// иначе заказ заберёт другой экземпляр
+	queueWait := time.Duration((cfg.AccrualBatchSize+cfg.AccrualWorkers-1)/cfg.AccrualWorkers) * cfg.AccrualJobTimeout
+	if cfg.AccrualLease <= queueWait {
+		return nil, fmt.Errorf("%w: ACCRUAL_LEASE must exceed ceil(ACCRUAL_BATCH_SIZE/ACCRUAL_WORKERS)*ACCRUAL_JOB_TIMEOUT (%s)", ErrInvalidConfig, queueWait)
+	}
+
+	if cfg.PasswordMinLength < 1 {
+		return nil, fmt.Errorf("%w: PASSWORD_MIN_LENGTH must be positive", ErrInvalidConfig)
+	}
+	for _, class := range cfg.PasswordRequire {
+		switch class {
+		case "upper", "lower", "digit", "symbol":
+		default:
+			return nil, fmt.Errorf("%w: unknown password character class %q", ErrInvalidConfig, class)
+		}
+	}
+	if cfg.PasswordHashConcurrency < 1 {
+		return nil, fmt.Errorf("%w: PASSWORD_HASH_CONCURRENCY must be positive", ErrInvalidConfig)
+	}
+	switch cfg.PasswordHasher {
+	case "argon2id":
+		if cfg.Argon2Memory < 8 || cfg.Argon2Iterations < 1 || cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > 255 {
+			return nil, fmt.Errorf("%w: invalid argon2id parameters", ErrInvalidConfig)
+		}
+	case "bcrypt":
+		if cfg.BcryptCost < 4 || cfg.BcryptCost > 31 {
+			return nil, fmt.Errorf("%w: BCRYPT_COST must be between 4 and 31", ErrInvalidConfig)
+		}
+	default:
+		return nil, fmt.Errorf("%w: PASSWORD_HASHER must be argon2id or bcrypt", ErrInvalidConfig)
+	}
+	if cfg.LoginThrottleStore != "postgres" && cfg.LoginThrottleStore != "memory" {
+		return nil, fmt.Errorf("%w: LOGIN_THROTTLE_STORE must be postgres or memory", ErrInvalidConfig)
+	}
+	if cfg.JWTKeysFile != "" && (cfg.JWTSecret != "" || len(cfg.JWTPreviousSecrets) > 0) {
+		return nil, fmt.Errorf("%w: JWT_KEYS_FILE and JWT_SECRET are mutually exclusive", ErrInvalidConfig)
+	}
+	if cfg.JWTSecret == "" && len(cfg.JWTPreviousSecrets) > 0 {
+		return nil, fmt.Errorf("%w: JWT_PREVIOUS_SECRETS requires JWT_SECRET", ErrInvalidConfig)
+	}
+	if cfg.ClawbackPolicy != "negative" && cfg.ClawbackPolicy != "debt" {
+		return nil, fmt.Errorf("%w: CLAWBACK_POLICY must be negative or debt", ErrInvalidConfig)
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		return nil, fmt.Errorf("%w: IDEMPOTENCY_TTL must be positive", ErrInvalidConfig)
+	}
+	if cfg.IdempotencyLease <= 0 || cfg.IdempotencyLease > cfg.IdempotencyTTL {
+		return nil, fmt.Errorf("%w: IDEMPOTENCY_LEASE must be positive and not exceed IDEMPOTENCY_TTL", ErrInvalidConfig)
+	}
+	if cfg.CleanupInterval <= 0 {
+		return nil, fmt.Errorf("%w: CLEANUP_INTERVAL must be positive", ErrInvalidConfig)
+	}
+	if cfg.ReconcileInterval <= 0 {
+		return nil, fmt.Errorf("%w: RECONCILE_INTERVAL must be positive", ErrInvalidConfig)
+	}
+	if cfg.EventsHeartbeat <= 0 {
+		return nil, fmt.Errorf("%w: EVENTS_HEARTBEAT must be positive", ErrInvalidConfig)
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		return nil, fmt.Errorf("%w: SHUTDOWN_TIMEOUT must be positive", ErrInvalidConfig)
+	}
 
 	return cfg, nil
 }
 
+// parsePrefix принимает подсеть или одиночный адрес
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int, errs *[]error) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%w: %s must be an integer", ErrInvalidConfig, key))
+		return fallback
+	}
+	return n
+}
+
+func getEnvBool(key string, fallback bool, errs *[]error) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%w: %s must be a boolean", ErrInvalidConfig, key))
+		return fallback
+	}
+	return b
+}
+
+func getEnvDuration(key string, fallback time.Duration, errs *[]error) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%w: %s must be a duration", ErrInvalidConfig, key))
+		return fallback
+	}
+	return d
+}
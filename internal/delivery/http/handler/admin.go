@@ -0,0 +1,401 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/admin"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	AdminService *admin.Service
+}
+
+func NewAdminHandler(adminService *admin.Service) *AdminHandler {
+	return &AdminHandler{AdminService: adminService}
+}
+
+type adminUserResponse struct {
+	ID        int64      `json:"id"`
+	Login     string     `json:"login"`
+	Role      user.Role  `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	BlockedAt *time.Time `json:"blocked_at,omitempty"`
+}
+
+func toAdminUser(u *user.User) adminUserResponse {
+	return adminUserResponse{
+		ID:        u.ID,
+		Login:     u.Login,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+		BlockedAt: u.BlockedAt,
+	}
+}
+
+func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	users, err := h.AdminService.SearchUsers(r.Context(), actorID, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+
+	response := make([]adminUserResponse, 0, len(users))
+	for _, u := range users {
+		response = append(response, toAdminUser(u))
+	}
+	writeJSON(w, response)
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := h.AdminService.GetUser(r.Context(), actorID, userID)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+	writeJSON(w, toAdminUser(u))
+}
+
+func (h *AdminHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.AdminService.GetUserOrders(r.Context(), actorID, userID, filter)
+	if err != nil {
+		if errors.Is(err, order.ErrInvalidFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.serverError(w, err)
+		return
+	}
+	if len(page.Items) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if page.Next != nil {
+		setNextPage(w, r, page.Next.Encode())
+	}
+	writeJSON(w, toOrderResponses(page.Items))
+}
+
+func (h *AdminHandler) GetUserWithdrawals(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+
+	withdrawals, err := h.AdminService.GetUserWithdrawals(r.Context(), actorID, userID)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+	if len(withdrawals) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, toWithdrawalResponses(withdrawals))
+}
+
+func (h *AdminHandler) GetUserBalance(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+
+	bal, err := h.AdminService.GetUserBalance(r.Context(), actorID, userID)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+	writeJSON(w, struct {
+		Current   money.Amount `json:"current"`
+		Withdrawn money.Amount `json:"withdrawn"`
+	}{
+		Current:   bal.Current,
+		Withdrawn: bal.Withdrawn,
+	})
+}
+
+type adjustmentRequest struct {
+	Amount money.Amount `json:"amount"`
+	Reason string       `json:"reason"`
+	Force  bool         `json:"force"`
+}
+
+// AdjustBalance — ручная корректировка баланса: положительная сумма начисляет баллы,
+// отрицательная списывает
+func (h *AdminHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+
+	var req adjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	bal, err := h.AdminService.AdjustBalance(r.Context(), actorID, userID, req.Amount, req.Reason, req.Force)
+	switch {
+	case errors.Is(err, balance.ErrZeroAdjustment), errors.Is(err, balance.ErrReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, balance.ErrNegativeBalance):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.serverError(w, err)
+		return
+	}
+
+	writeJSON(w, struct {
+		Current   money.Amount `json:"current"`
+		Withdrawn money.Amount `json:"withdrawn"`
+	}{
+		Current:   bal.Current,
+		Withdrawn: bal.Withdrawn,
+	})
+}
+
+type reasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *AdminHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	h.setBlocked(w, r, true)
+}
+
+func (h *AdminHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	h.setBlocked(w, r, false)
+}
+
+func (h *AdminHandler) setBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	actorID, userID, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+
+	var req reasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if blocked {
+		err = h.AdminService.BlockUser(r.Context(), actorID, userID, req.Reason)
+	} else {
+		err = h.AdminService.UnblockUser(r.Context(), actorID, userID, req.Reason)
+	}
+	if errors.Is(err, admin.ErrCannotBlockSelf) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReverseWithdrawal отменяет списание по номеру заказа и возвращает баллы пользователю
+func (h *AdminHandler) ReverseWithdrawal(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req reasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	reversed, err := h.AdminService.ReverseWithdrawal(r.Context(), actorID, chi.URLParam(r, "order"), req.Reason)
+	switch {
+	case errors.Is(err, withdrawal.ErrReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, withdrawal.ErrWithdrawalNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, withdrawal.ErrAlreadyReversed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.serverError(w, err)
+		return
+	}
+
+	writeJSON(w, toWithdrawalResponse(reversed))
+}
+
+type orderReturnResponse struct {
+	Order   string       `json:"order"`
+	UserID  int          `json:"user_id"`
+	Debited money.Amount `json:"debited"`
+	Debt    money.Amount `json:"debt"`
+}
+
+// ReturnOrder отменяет заказ и списывает начисленные по нему баллы
+func (h *AdminHandler) ReturnOrder(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req reasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	number := chi.URLParam(r, "number")
+	result, err := h.AdminService.ReturnOrder(r.Context(), actorID, number, req.Reason)
+	switch {
+	case errors.Is(err, order.ErrOrderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, order.ErrAlreadyReturned), errors.Is(err, order.ErrNotReturnable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.serverError(w, err)
+		return
+	}
+
+	writeJSON(w, orderReturnResponse{
+		Order:   number,
+		UserID:  result.UserID,
+		Debited: result.Debited,
+		Debt:    result.Debt,
+	})
+}
+
+type auditEntryResponse struct {
+	ID           int64             `json:"id"`
+	ActorID      int               `json:"actor_id"`
+	Action       audit.Action      `json:"action"`
+	TargetUserID int               `json:"target_user_id,omitempty"`
+	Details      map[string]string `json:"details,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var userID, limit int
+	for name, dst := range map[string]*int{"user_id": &userID, "limit": &limit} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dst = n
+	}
+
+	entries, err := h.AdminService.GetAuditLog(r.Context(), actorID, userID, limit)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+	if len(entries) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	response := make([]auditEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		response = append(response, auditEntryResponse{
+			ID:           e.ID,
+			ActorID:      e.ActorID,
+			Action:       e.Action,
+			TargetUserID: e.TargetUserID,
+			Details:      e.Details,
+			CreatedAt:    e.CreatedAt,
+		})
+	}
+	writeJSON(w, response)
+}
+
+// ids возвращает идентификатор администратора и пользователя из пути
+func (h *AdminHandler) ids(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return actorID, userID, true
+}
+
+func (h *AdminHandler) serverError(w http.ResponseWriter, err error) {
+	if errors.Is(err, user.ErrUserNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Printf("admin request failed: %v", err)
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -4,21 +4,36 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
 
-	infraauth "github.com/GarikMirzoyan/gophermart/internal/infrastructure/auth"
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/token"
+	domainuser "github.com/GarikMirzoyan/gophermart/internal/domain/user"
 	"github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
+	throttleusecase "github.com/GarikMirzoyan/gophermart/internal/usecase/throttle"
 )
 
 type AuthHandler struct {
-	AuthService *auth.Service
-	JWTManager  *infraauth.JWTManager
+	AuthService     *auth.Service
+	TokenService    *auth.TokenService
+	ThrottleService *throttleusecase.Service
+	trustedProxies  []netip.Prefix
 }
 
-func NewAuthHandler(authService *auth.Service, jwtManager *infraauth.JWTManager) *AuthHandler {
+func NewAuthHandler(authService *auth.Service, tokenService *auth.TokenService, throttleService *throttleusecase.Service, trustedProxies []netip.Prefix) *AuthHandler {
 	return &AuthHandler{
-		AuthService: authService,
-		JWTManager:  jwtManager,
+		AuthService:     authService,
+		TokenService:    tokenService,
+		ThrottleService: throttleService,
+		trustedProxies:  trustedProxies,
 	}
 }
 
@@ -36,6 +51,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.AuthService.Register(r.Context(), creds.Login, creds.Password)
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		if errors.Is(err, auth.ErrLoginTaken) {
 			http.Error(w, "login already used", http.StatusConflict)
 			return
@@ -44,9 +62,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, _ := h.JWTManager.Generate(int(user.ID))
-	w.Header().Set("Authorization", "Bearer "+token)
-	w.WriteHeader(http.StatusOK)
+	h.issueTokens(w, r, int(user.ID))
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -56,18 +72,145 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Попытка учитывается до проверки пароля; неудачной она остаётся, пока вход не удался
+	ip := clientIP(r, h.trustedProxies)
+	if err := h.ThrottleService.Attempt(r.Context(), creds.Login, ip); err != nil {
+		if retryAfter, ok := throttle.AsThrottled(err); ok {
+			writeTooManyRequests(w, retryAfter)
+			return
+		}
+		log.Printf("failed to check login throttling: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	user, err := h.AuthService.Authenticate(r.Context(), creds.Login, creds.Password)
 	if err != nil {
+		if errors.Is(err, domainuser.ErrAccountBlocked) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	token, _ := h.JWTManager.Generate(int(user.ID))
-	w.Header().Set("Authorization", "Bearer "+token)
+	if err := h.ThrottleService.Succeed(r.Context(), creds.Login, ip); err != nil {
+		log.Printf("failed to reset login throttling: %v", err)
+	}
+
+	h.issueTokens(w, r, int(user.ID))
+}
+
+// clientIP возвращает адрес клиента. X-Forwarded-For учитывается, только если соединение
+// пришло от доверенного прокси: иначе заголовок может подставить сам клиент, чтобы обойти
+// ограничение по IP. Прокси дописывают адреса справа, поэтому заголовок читается справа
+// налево до первого недоверенного адреса.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(addr, trustedProxies) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop
+		if !isTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+	return addr.Unmap().String()
+}
+
+func isTrustedProxy(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := h.TokenService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, token.ErrInvalidToken) || errors.Is(err, token.ErrTokenReused) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		log.Printf("failed to refresh token: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeTokens(w, pair)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := r.Context().Value(middleware.SessionIDKey).(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.TokenService.Logout(r.Context(), sessionID); err != nil {
+		log.Printf("failed to revoke session: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) issueTokens(w http.ResponseWriter, r *http.Request, userID int) {
+	issueTokens(w, r, h.TokenService, userID)
+}
+
+func issueTokens(w http.ResponseWriter, r *http.Request, tokenService *auth.TokenService, userID int) {
+	pair, err := tokenService.Issue(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to issue tokens for user %d: %v", userID, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeTokens(w, pair)
+}
+
+func writeTokens(w http.ResponseWriter, pair *auth.TokenPair) {
+	w.Header().Set("Authorization", "Bearer "+pair.AccessToken)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	json.NewEncoder(w).Encode(map[string]string{
-		"token": token,
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
 	})
 }
@@ -0,0 +1,54 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/handler"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
+	throttleusecase "github.com/GarikMirzoyan/gophermart/internal/usecase/throttle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	usermocks "github.com/GarikMirzoyan/gophermart/internal/domain/user/mocks"
+)
+
+func TestAuthHandler_LoginThrottlesClientIP(t *testing.T) {
+	users := usermocks.NewRepository(t)
+	users.On("GetByLogin", mock.Anything, mock.Anything).Return(nil, nil)
+
+	// Каждый адрес может ошибиться один раз, логины не ограничены
+	throttleService := throttleusecase.New(storage.NewThrottleMemory(), throttleusecase.Config{
+		LoginPolicy: throttle.Policy{Window: time.Hour, FreeAttempts: 100},
+		IPPolicy:    throttle.Policy{Window: time.Hour, FreeAttempts: 0, BaseDelay: time.Minute},
+	})
+	h := handler.NewAuthHandler(auth.New(users, nil, user.PasswordPolicy{}), nil, throttleService,
+		[]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+	login := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/login", strings.NewReader(`{"login":"alice","password":"wrong"}`))
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		h.Login(rec, req)
+		return rec.Code
+	}
+
+	// За доверенным прокси учитывается адрес клиента из заголовка
+	assert.Equal(t, http.StatusUnauthorized, login("10.0.0.2:4000", "203.0.113.7, 10.0.0.5"))
+	assert.Equal(t, http.StatusTooManyRequests, login("10.0.0.3:4000", "203.0.113.7"))
+	assert.Equal(t, http.StatusUnauthorized, login("10.0.0.2:4000", "198.51.100.1"))
+
+	// Без доверенного прокси заголовок не помогает сменить адрес
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.1:4000", "203.0.113.8"))
+	assert.Equal(t, http.StatusTooManyRequests, login("192.0.2.1:4000", "203.0.113.9"))
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	"github.com/GarikMirzoyan/gophermart/internal/usecase/balance"
 )
 
@@ -30,8 +34,8 @@ func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := struct {
-		Current   float64 `json:"current"`
-		Withdrawn float64 `json:"withdrawn"`
+		Current   money.Amount `json:"current"`
+		Withdrawn money.Amount `json:"withdrawn"`
 	}{
 		Current:   bal.Current,
 		Withdrawn: bal.Withdrawn,
@@ -40,3 +44,72 @@ func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+type historyItemResponse struct {
+	Type        ledger.Direction `json:"type"`
+	Kind        ledger.Kind      `json:"kind"`
+	Order       string           `json:"order,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Amount      money.Amount     `json:"amount"`
+	Balance     money.Amount     `json:"balance"`
+	ProcessedAt time.Time        `json:"processed_at"`
+}
+
+// GetHistory отдаёт выписку JSON-массивом. Как и у заказов и списаний, курсор следующей
+// страницы передаётся в заголовках X-Next-Cursor и Link (rel="next").
+func (h *BalanceHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.BalanceService.GetHistory(r.Context(), userID, filter)
+	if err != nil {
+		if errors.Is(err, ledger.ErrInvalidFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if len(page.Items) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response := make([]historyItemResponse, 0, len(page.Items))
+	for _, item := range page.Items {
+		amount := item.Amount
+		if amount.IsNegative() {
+			amount = -amount
+		}
+		response = append(response, historyItemResponse{
+			Type:        item.Direction(),
+			Kind:        item.Kind,
+			Order:       item.Reference,
+			Description: item.Description,
+			Amount:      amount,
+			Balance:     item.Balance,
+			ProcessedAt: item.CreatedAt,
+		})
+	}
+	if page.Next != nil {
+		setNextPage(w, r, page.Next.Encode())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func parseHistoryFilter(r *http.Request) (ledger.HistoryFilter, error) {
+	q := r.URL.Query()
+	params, err := parsePageParams(q)
+	return ledger.HistoryFilter{Params: params, Direction: ledger.Direction(q.Get("type"))}, err
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	domainorder "github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+)
+
+// EventSubscriber — источник событий заказов в реальном времени
+type EventSubscriber interface {
+	Subscribe(userID int) domainorder.Subscription
+}
+
+// EventsHandler отдаёт изменения статусов заказов пользователя потоком Server-Sent Events
+type EventsHandler struct {
+	OrderService *order.Service
+	events       EventSubscriber
+	sessions     middleware.SessionChecker
+	heartbeat    time.Duration
+}
+
+func NewEventsHandler(orderService *order.Service, events EventSubscriber, sessions middleware.SessionChecker, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{OrderService: orderService, events: events, sessions: sessions, heartbeat: heartbeat}
+}
+
+type orderStatusEvent struct {
+	Number string `json:"number"`
+	orderEventResponse
+	Accrual *money.Amount `json:"accrual,omitempty"`
+}
+
+// Stream держит соединение открытым и пишет событие "order" на каждый переход статуса.
+// Переход в PROCESSED с accrual означает, что баллы зачислены на баланс. С заголовком
+// Last-Event-ID сначала отдаются события, пропущенные после него, а если их больше
+// MaxReplayEvents — событие "reload" с пустым id, после чего поток закрывается. Комментарий-heartbeat
+// не даёт прокси закрыть простаивающее соединение; перед ним заново проверяется сессия,
+// и поток отозванной сессии закрывается.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastID int64
+	resume := r.Header.Get("Last-Event-ID")
+	if resume != "" {
+		id, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	// Подписка оформляется до чтения истории, чтобы не потерять события между ними
+	sub := h.events.Subscribe(userID)
+	defer sub.Close()
+
+	var late []*domainorder.Event
+	if resume != "" {
+		var err error
+		late, err = h.OrderService.LateEvents(r.Context(), userID, lastID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Отданное из истории может прийти и из подписки: повтор пропускается по id.
+	// Сравнивать с последним id нельзя — события фиксируются не в порядке id.
+	replayed := make(map[int64]bool)
+	for _, e := range late {
+		if err := writeOrderEvent(w, e); err != nil {
+			return
+		}
+		replayed[e.ID] = true
+	}
+
+	// История отдаётся по странице за раз. Если пропущено больше MaxReplayEvents,
+	// клиенту дешевле перечитать заказы целиком: он получает событие "reload"
+	for cursor := lastID; resume != ""; {
+		page, err := h.OrderService.EventsSince(r.Context(), userID, cursor)
+		if err != nil {
+			log.Printf("failed to replay order events for user %d: %v", userID, err)
+			return
+		}
+		for _, e := range page {
+			if err := writeOrderEvent(w, e); err != nil {
+				return
+			}
+			replayed[e.ID] = true
+		}
+		flusher.Flush()
+
+		if len(page) < domainorder.ReplayPageSize {
+			break
+		}
+		if len(replayed) >= domainorder.MaxReplayEvents {
+			if _, err := fmt.Fprint(w, "id: \nevent: reload\ndata: {}\n\n"); err == nil {
+				flusher.Flush()
+			}
+			return
+		}
+		cursor = page[len(page)-1].ID
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if replayed[e.ID] {
+				delete(replayed, e.ID)
+				continue
+			}
+			if err := writeOrderEvent(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			active, err := h.sessions.IsSessionActive(r.Context(), sessionID)
+			if err != nil {
+				log.Printf("failed to check session %s: %v", sessionID, err)
+				return
+			}
+			if !active {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeOrderEvent(w http.ResponseWriter, e *domainorder.Event) error {
+	data, err := json.Marshal(orderStatusEvent{
+		Number: e.OrderNumber,
+		orderEventResponse: orderEventResponse{
+			From:      e.FromStatus,
+			To:        e.ToStatus,
+			Source:    e.Source,
+			Reason:    e.Reason,
+			CreatedAt: e.CreatedAt,
+		},
+		Accrual: e.Accrual,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", e.ID, data)
+	return err
+}
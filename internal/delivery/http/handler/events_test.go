@@ -0,0 +1,119 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/handler"
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	domainorder "github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/events"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	ordermocks "github.com/GarikMirzoyan/gophermart/internal/domain/order/mocks"
+)
+
+type stubSessions struct {
+	active atomic.Bool
+}
+
+func (s *stubSessions) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s.active.Load(), nil
+}
+
+// startStream поднимает сервер с потоком пользователя 1 и подключается к нему
+func startStream(t *testing.T, repo domainorder.Repository, broker *events.Broker, sessions *stubSessions, heartbeat time.Duration, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+
+	h := handler.NewEventsHandler(order.New(repo, nil, domainorder.Backoff{}, "", nil), broker, sessions, heartbeat)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDKey, 1)
+		ctx = context.WithValue(ctx, middleware.SessionIDKey, "session-1")
+		h.Stream(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// readFrame читает одно сообщение потока до пустой строки
+func readFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	var frame strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return frame.String()
+		}
+		frame.WriteString(line)
+	}
+}
+
+func TestEventsHandler_Stream(t *testing.T) {
+	t.Run("resume replays missed events and skips them in live stream", func(t *testing.T) {
+		repo := ordermocks.NewRepository(t)
+		broker := events.NewBroker(events.DefaultBuffer)
+		sessions := &stubSessions{}
+		sessions.active.Store(true)
+
+		late := &domainorder.Event{ID: 9, OrderNumber: "12345678903", FromStatus: domainorder.StatusNew, ToStatus: domainorder.StatusProcessing, UserID: 1}
+		missed := &domainorder.Event{ID: 11, OrderNumber: "12345678903", FromStatus: domainorder.StatusProcessing, ToStatus: domainorder.StatusProcessed, UserID: 1}
+		repo.On("ListLateUserEvents", mock.Anything, 1, int64(10), domainorder.ReplayLookback, domainorder.MaxReplayEvents).
+			Return([]*domainorder.Event{late}, nil)
+		repo.On("ListUserEvents", mock.Anything, 1, int64(10), domainorder.ReplayPageSize).
+			Return([]*domainorder.Event{missed}, nil)
+
+		resp, body := startStream(t, repo, broker, sessions, time.Hour, "10")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+		assert.True(t, strings.HasPrefix(readFrame(t, body), "id: 9\nevent: order\n"))
+		assert.True(t, strings.HasPrefix(readFrame(t, body), "id: 11\nevent: order\n"))
+
+		broker.Publish(context.Background(), missed)
+		broker.Publish(context.Background(), &domainorder.Event{ID: 12, OrderNumber: "12345678903", UserID: 1})
+		assert.True(t, strings.HasPrefix(readFrame(t, body), "id: 12\n"))
+	})
+
+	t.Run("invalid Last-Event-ID", func(t *testing.T) {
+		resp, _ := startStream(t, ordermocks.NewRepository(t), events.NewBroker(events.DefaultBuffer), &stubSessions{}, time.Hour, "abc")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("heartbeat until session is revoked", func(t *testing.T) {
+		sessions := &stubSessions{}
+		sessions.active.Store(true)
+
+		resp, body := startStream(t, ordermocks.NewRepository(t), events.NewBroker(events.DefaultBuffer), sessions, 10*time.Millisecond, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, ": heartbeat\n", readFrame(t, body))
+
+		sessions.active.Store(false)
+		for {
+			if _, err := body.ReadString('\n'); err != nil {
+				break
+			}
+		}
+	})
+}
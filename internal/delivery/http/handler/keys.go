@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	infraauth "github.com/GarikMirzoyan/gophermart/internal/infrastructure/auth"
+)
+
+type KeysHandler struct {
+	JWTManager *infraauth.JWTManager
+}
+
+func NewKeysHandler(jwtManager *infraauth.JWTManager) *KeysHandler {
+	return &KeysHandler{JWTManager: jwtManager}
+}
+
+// JWKS отдаёт открытые ключи, которыми другие сервисы могут проверять наши токены
+func (h *KeysHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.JWTManager.JWKS())
+}
@@ -7,11 +7,18 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	domainorder "github.com/GarikMirzoyan/gophermart/internal/domain/order"
 	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+	"github.com/go-chi/chi/v5"
 )
 
+// maxBatchBodyBytes с запасом вмещает domainorder.MaxBatchSize номеров
+const maxBatchBodyBytes = 1 << 20
+
 type OrderHandler struct {
 	OrderService *order.Service
 }
@@ -54,6 +61,70 @@ func (h *OrderHandler) AddOrder(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// AddOrders принимает пачку номеров JSON-массивом (application/json) или по одному
+// в строке и отвечает результатом по каждому номеру в порядке запроса
+func (h *OrderHandler) AddOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBatchBodyBytes+1))
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxBatchBodyBytes {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	numbers, err := parseOrderNumbers(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.OrderService.AddOrders(r.Context(), userID, numbers)
+	if err != nil {
+		switch {
+		case errors.Is(err, domainorder.ErrEmptyBatch):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domainorder.ErrBatchTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, "server error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// parseOrderNumbers разбирает тело пачки: JSON-массив строк или номера по одному в строке.
+// Пустые строки пропускаются.
+func parseOrderNumbers(contentType string, body []byte) ([]string, error) {
+	var raw []string
+	if strings.HasPrefix(contentType, "application/json") {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		raw = strings.Split(string(body), "\n")
+	}
+
+	numbers := make([]string, 0, len(raw))
+	for _, number := range raw {
+		if number = strings.TrimSpace(number); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers, nil
+}
+
+// GetOrders отдаёт страницу заказов массивом, как и раньше. Курсор следующей страницы
+// передаётся в заголовках X-Next-Cursor и Link (rel="next").
 func (h *OrderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
@@ -61,17 +132,124 @@ func (h *OrderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orders, err := h.OrderService.GetOrdersByUser(r.Context(), userID)
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	page, err := h.OrderService.ListOrders(r.Context(), userID, filter)
 	if err != nil {
+		if errors.Is(err, domainorder.ErrInvalidFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, fmt.Sprintf("server error: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if len(orders) == 0 {
+	if len(page.Items) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	if page.Next != nil {
+		setNextPage(w, r, page.Next.Encode())
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+	json.NewEncoder(w).Encode(toOrderResponses(page.Items))
+}
+
+type orderResponse struct {
+	Number     string             `json:"number"`
+	Status     domainorder.Status `json:"status"`
+	Accrual    *money.Amount      `json:"accrual,omitempty"`
+	UploadedAt time.Time          `json:"uploaded_at"`
+}
+
+func toOrderResponse(o *domainorder.Order) orderResponse {
+	return orderResponse{
+		Number:     o.Number,
+		Status:     o.Status,
+		Accrual:    o.Accrual,
+		UploadedAt: o.UploadedAt,
+	}
+}
+
+func toOrderResponses(orders []*domainorder.Order) []orderResponse {
+	response := make([]orderResponse, 0, len(orders))
+	for _, o := range orders {
+		response = append(response, toOrderResponse(o))
+	}
+	return response
+}
+
+type orderEventResponse struct {
+	From      domainorder.Status      `json:"from"`
+	To        domainorder.Status      `json:"to"`
+	Source    domainorder.EventSource `json:"source"`
+	Reason    string                  `json:"reason,omitempty"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+type orderDetailsResponse struct {
+	orderResponse
+	History []orderEventResponse `json:"history"`
+}
+
+// GetOrder отдаёт один заказ пользователя с историей обработки.
+// На чужой заказ отвечает 404, как и на несуществующий.
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	details, err := h.OrderService.GetOrder(r.Context(), userID, chi.URLParam(r, "number"))
+	if err != nil {
+		if errors.Is(err, domainorder.ErrOrderNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("server error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := orderDetailsResponse{
+		orderResponse: toOrderResponse(details.Order),
+		History:       make([]orderEventResponse, 0, len(details.History)),
+	}
+	for _, e := range details.History {
+		response.History = append(response.History, orderEventResponse{
+			From:      e.FromStatus,
+			To:        e.ToStatus,
+			Source:    e.Source,
+			Reason:    e.Reason,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseOrderFilter разбирает параметры status (через запятую), from, to (RFC3339),
+// sort (asc или desc), limit и cursor
+func parseOrderFilter(r *http.Request) (domainorder.ListFilter, error) {
+	q := r.URL.Query()
+	params, err := parsePageParams(q)
+	if err != nil {
+		return domainorder.ListFilter{}, err
+	}
+	filter := domainorder.ListFilter{Params: params, Sort: domainorder.SortOrder(strings.ToLower(q.Get("sort")))}
+
+	for _, v := range q["status"] {
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, domainorder.Status(strings.ToUpper(status)))
+			}
+		}
+	}
+	return filter, nil
 }
@@ -0,0 +1,145 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/handler"
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	domainorder "github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	ordermocks "github.com/GarikMirzoyan/gophermart/internal/domain/order/mocks"
+)
+
+// serveOrders выполняет запрос обработчиком заказов от имени пользователя 1
+func serveOrders(h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h(rec, req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1)))
+	return rec
+}
+
+func TestOrderHandler_AddOrders(t *testing.T) {
+	numbers := []string{"12345678903", "79927398713"}
+
+	for name, tc := range map[string]struct {
+		contentType string
+		body        string
+	}{
+		"json array":      {"application/json; charset=utf-8", `["12345678903", "79927398713"]`},
+		"one per line":    {"text/plain", "12345678903\n\n79927398713\n"},
+		"no content type": {"", "12345678903\r\n79927398713"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := ordermocks.NewRepository(t)
+			repo.On("AddOrders", mock.Anything, 1, numbers, mock.AnythingOfType("time.Time")).
+				Return(&domainorder.BatchInsert{
+					Inserted: map[string]bool{"12345678903": true},
+					Owners:   map[string]int{"79927398713": 2},
+				}, nil)
+			h := handler.NewOrderHandler(order.New(repo, nil, domainorder.Backoff{}, "", nil))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/orders/batch", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := serveOrders(h.AddOrders, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var items []domainorder.BatchItem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&items))
+			assert.Equal(t, []domainorder.BatchItem{
+				{Number: "12345678903", Outcome: domainorder.OutcomeAccepted},
+				{Number: "79927398713", Outcome: domainorder.OutcomeConflict},
+			}, items)
+		})
+	}
+
+	t.Run("invalid json", func(t *testing.T) {
+		h := handler.NewOrderHandler(order.New(ordermocks.NewRepository(t), nil, domainorder.Backoff{}, "", nil))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/user/orders/batch", strings.NewReader(`{"number":"1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		assert.Equal(t, http.StatusBadRequest, serveOrders(h.AddOrders, req).Code)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		h := handler.NewOrderHandler(order.New(ordermocks.NewRepository(t), nil, domainorder.Backoff{}, "", nil))
+
+		body := strings.Repeat("12345678903\n", (1<<20)/12+1)
+		req := httptest.NewRequest(http.MethodPost, "/api/user/orders/batch", strings.NewReader(body))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, serveOrders(h.AddOrders, req).Code)
+	})
+
+	t.Run("too many numbers", func(t *testing.T) {
+		h := handler.NewOrderHandler(order.New(ordermocks.NewRepository(t), nil, domainorder.Backoff{}, "", nil))
+
+		body := strings.Repeat("12345678903\n", domainorder.MaxBatchSize+1)
+		req := httptest.NewRequest(http.MethodPost, "/api/user/orders/batch", strings.NewReader(body))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, serveOrders(h.AddOrders, req).Code)
+	})
+}
+
+func TestOrderHandler_GetOrders(t *testing.T) {
+	t.Run("invalid parameters", func(t *testing.T) {
+		for name, query := range map[string]string{
+			"from":   "from=yesterday",
+			"to":     "to=2025-07-14",
+			"limit":  "limit=-1",
+			"cursor": "cursor=not-a-cursor",
+			"status": "status=done",
+			"sort":   "sort=random",
+			"range":  "from=2025-07-14T00:00:00Z&to=2025-07-13T00:00:00Z",
+		} {
+			h := handler.NewOrderHandler(order.New(ordermocks.NewRepository(t), nil, domainorder.Backoff{}, "", nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/orders?"+query, nil)
+			assert.Equal(t, http.StatusBadRequest, serveOrders(h.GetOrders, req).Code, name)
+		}
+	})
+
+	t.Run("next page in headers", func(t *testing.T) {
+		now := time.Date(2025, 7, 14, 9, 0, 0, 0, time.UTC)
+		repo := ordermocks.NewRepository(t)
+		repo.On("ListOrdersByUser", mock.Anything, 1, mock.AnythingOfType("order.ListFilter")).
+			Return([]*domainorder.Order{
+				{ID: 2, Number: "12345678903", UserID: 1, Status: domainorder.StatusProcessed, UploadedAt: now},
+				{ID: 1, Number: "79927398713", UserID: 1, UploadedAt: now.Add(-time.Minute)},
+			}, nil)
+		h := handler.NewOrderHandler(order.New(repo, nil, domainorder.Backoff{}, "", nil))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/user/orders?limit=1&status=processed", nil)
+		rec := serveOrders(h.GetOrders, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		cursor := pagination.Cursor{At: now, ID: 2}.Encode()
+		assert.Equal(t, cursor, rec.Header().Get("X-Next-Cursor"))
+		assert.Equal(t, `</api/user/orders?cursor=`+cursor+`&limit=1&status=processed>; rel="next"`, rec.Header().Get("Link"))
+
+		var orders []map[string]any
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&orders))
+		require.Len(t, orders, 1)
+		assert.Equal(t, map[string]any{"number": "12345678903", "status": "PROCESSED", "uploaded_at": "2025-07-14T09:00:00Z"}, orders[0])
+	})
+
+	t.Run("last page has no cursor", func(t *testing.T) {
+		repo := ordermocks.NewRepository(t)
+		repo.On("ListOrdersByUser", mock.Anything, 1, mock.AnythingOfType("order.ListFilter")).
+			Return([]*domainorder.Order{{ID: 1, Number: "12345678903", UserID: 1}}, nil)
+		h := handler.NewOrderHandler(order.New(repo, nil, domainorder.Backoff{}, "", nil))
+
+		rec := serveOrders(h.GetOrders, httptest.NewRequest(http.MethodGet, "/api/user/orders", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-Next-Cursor"))
+		assert.Empty(t, rec.Header().Get("Link"))
+	})
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+)
+
+// parsePageParams разбирает общие параметры списков: from, to (RFC3339), limit и cursor
+func parsePageParams(q url.Values) (pagination.Params, error) {
+	var params pagination.Params
+
+	if v := q.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return params, errors.New("invalid from: expected RFC3339")
+		}
+		params.From = from
+	}
+	if v := q.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return params, errors.New("invalid to: expected RFC3339")
+		}
+		params.To = to
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return params, errors.New("invalid limit")
+		}
+		params.Limit = limit
+	}
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := pagination.DecodeCursor(v)
+		if err != nil {
+			return params, err
+		}
+		params.After = cursor
+	}
+
+	return params, nil
+}
+
+// setNextPage сообщает курсор следующей страницы в заголовках, не меняя тело ответа:
+// ссылка повторяет исходный запрос с подставленным параметром cursor
+func setNextPage(w http.ResponseWriter, r *http.Request, cursor string) {
+	next := *r.URL
+	q := next.Query()
+	q.Set("cursor", cursor)
+	next.RawQuery = q.Encode()
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/netip"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
+	throttleusecase "github.com/GarikMirzoyan/gophermart/internal/usecase/throttle"
+)
+
+type PasswordHandler struct {
+	PasswordService *auth.PasswordService
+	TokenService    *auth.TokenService
+	ThrottleService *throttleusecase.Service
+	trustedProxies  []netip.Prefix
+}
+
+func NewPasswordHandler(passwordService *auth.PasswordService, tokenService *auth.TokenService, throttleService *throttleusecase.Service, trustedProxies []netip.Prefix) *PasswordHandler {
+	return &PasswordHandler{
+		PasswordService: passwordService,
+		TokenService:    tokenService,
+		ThrottleService: throttleService,
+		trustedProxies:  trustedProxies,
+	}
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePassword меняет пароль. Все сессии пользователя отзываются,
+// а текущему клиенту выдаётся новая пара токенов.
+func (h *PasswordHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.PasswordService.ChangePassword(r.Context(), userID, clientIP(r, h.trustedProxies), req.OldPassword, req.NewPassword)
+	if writeValidationError(w, err) {
+		return
+	}
+	if retryAfter, ok := throttle.AsThrottled(err); ok {
+		writeTooManyRequests(w, retryAfter)
+		return
+	}
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		http.Error(w, "wrong password", http.StatusForbidden)
+		return
+	case err != nil:
+		log.Printf("failed to change password for user %d: %v", userID, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	issueTokens(w, r, h.TokenService, userID)
+}
+
+type resetRequest struct {
+	Login string `json:"login"`
+}
+
+// RequestReset всегда отвечает 202, чтобы по ответу нельзя было узнать, существует ли логин.
+// Частые запросы по одному логину или адресу получают 429 независимо от существования логина.
+// Если доставка уведомлений не настроена, сброс пароля отключён.
+func (h *PasswordHandler) RequestReset(w http.ResponseWriter, r *http.Request) {
+	if !h.PasswordService.ResetEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req resetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Login == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ThrottleService.AttemptReset(r.Context(), req.Login, clientIP(r, h.trustedProxies)); err != nil {
+		if retryAfter, ok := throttle.AsThrottled(err); ok {
+			writeTooManyRequests(w, retryAfter)
+			return
+		}
+		log.Printf("failed to check password reset throttling: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.PasswordService.RequestReset(r.Context(), req.Login); err != nil {
+		log.Printf("failed to request password reset: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (h *PasswordHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if !h.PasswordService.ResetEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.PasswordService.ResetPassword(r.Context(), req.Token, req.NewPassword)
+	if writeValidationError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, user.ErrInvalidResetToken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("failed to reset password: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
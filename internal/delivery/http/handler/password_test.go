@@ -0,0 +1,36 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/handler"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
+	"github.com/stretchr/testify/assert"
+
+	usermocks "github.com/GarikMirzoyan/gophermart/internal/domain/user/mocks"
+)
+
+// Без настроенной доставки уведомлений сброс пароля отключён: токен не выпускается
+// и не попадает в лог
+func TestPasswordHandler_ResetDisabledWithoutNotifier(t *testing.T) {
+	users := usermocks.NewRepository(t)
+	resets := usermocks.NewResetTokenRepository(t)
+	service := auth.NewPasswordService(users, resets, nil, nil, nil, user.DefaultPasswordPolicy, time.Hour)
+	h := handler.NewPasswordHandler(service, nil, nil, nil)
+
+	for path, call := range map[string]http.HandlerFunc{
+		"/api/user/password/reset/request": h.RequestReset,
+		"/api/user/password/reset":         h.ResetPassword,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"login": "alice", "token": "t", "new_password": "n3w-Passw0rd"}`))
+		rec := httptest.NewRecorder()
+		call(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+)
+
+type validationResponse struct {
+	Errors []user.FieldError `json:"errors"`
+}
+
+// writeValidationError отвечает 400 со списком ошибок по полям, если err вызвана
+// некорректным вводом. Возвращает false, если ответ не был записан.
+func writeValidationError(w http.ResponseWriter, err error) bool {
+	fields, ok := user.AsValidation(err)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(validationResponse{Errors: fields})
+	return true
+}
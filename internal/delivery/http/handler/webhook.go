@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	domainorder "github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+)
+
+const (
+	// WebhookSignatureHeader — HMAC-SHA256 тела запроса в виде "sha256=<hex>"
+	WebhookSignatureHeader = "X-Accrual-Signature"
+	maxWebhookBodyBytes    = 64 << 10
+)
+
+// AccrualWebhookHandler принимает уведомления системы начислений о возвращённых
+// и аннулированных заказах
+type AccrualWebhookHandler struct {
+	OrderService *order.Service
+	secret       []byte
+}
+
+// NewAccrualWebhookHandler создаёт обработчик webhook. С пустым секретом webhook отключён.
+func NewAccrualWebhookHandler(orderService *order.Service, secret string) *AccrualWebhookHandler {
+	return &AccrualWebhookHandler{OrderService: orderService, secret: []byte(secret)}
+}
+
+type orderReturnedEvent struct {
+	Order  string `json:"order"`
+	Reason string `json:"reason"`
+}
+
+func (h *AccrualWebhookHandler) OrderReturned(w http.ResponseWriter, r *http.Request) {
+	if len(h.secret) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if !h.validSignature(r.Header.Get(WebhookSignatureHeader), body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event orderReturnedEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.Order == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.OrderService.ReturnOrder(r.Context(), event.Order, event.Reason, domainorder.SourceAccrual, 0)
+	switch {
+	case errors.Is(err, domainorder.ErrOrderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err == nil, errors.Is(err, domainorder.ErrAlreadyReturned):
+		// Повторная доставка того же события — не ошибка
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, domainorder.ErrNotReturnable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("failed to return order %s: %v", event.Order, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *AccrualWebhookHandler) validSignature(header string, body []byte) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,56 @@
+package handler_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/handler"
+	domainorder "github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	ordermocks "github.com/GarikMirzoyan/gophermart/internal/domain/order/mocks"
+)
+
+func TestAccrualWebhookHandler_OrderReturned(t *testing.T) {
+	const secret = "webhook-secret"
+	body := `{"order": "12345678903", "reason": "fraud"}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	for name, tc := range map[string]struct {
+		err  error
+		code int
+	}{
+		"returned":          {nil, http.StatusNoContent},
+		"redelivered event": {domainorder.ErrAlreadyReturned, http.StatusNoContent},
+		"not processed yet": {domainorder.ErrNotReturnable, http.StatusConflict},
+		"unknown order":     {domainorder.ErrOrderNotFound, http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := ordermocks.NewRepository(t)
+			var result *domainorder.ReturnResult
+			if tc.err == nil {
+				result = &domainorder.ReturnResult{UserID: 1, FromStatus: domainorder.StatusProcessed}
+			}
+			repo.On("ReturnOrder", mock.Anything, mock.MatchedBy(func(ret *domainorder.Return) bool {
+				return ret.Number == "12345678903" && ret.Source == domainorder.SourceAccrual
+			})).Return(result, tc.err)
+			h := handler.NewAccrualWebhookHandler(order.New(repo, nil, domainorder.Backoff{}, "", nil), secret)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/accrual/webhook/orders/returned", strings.NewReader(body))
+			req.Header.Set(handler.WebhookSignatureHeader, signature)
+			rec := httptest.NewRecorder()
+			h.OrderReturned(rec, req)
+
+			assert.Equal(t, tc.code, rec.Code)
+		})
+	}
+}
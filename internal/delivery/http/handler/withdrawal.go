@@ -2,9 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	"github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
 	withdrawalService "github.com/GarikMirzoyan/gophermart/internal/usecase/withdrawal"
 )
@@ -18,8 +21,8 @@ func NewWithdrawalHandler(service *withdrawalService.Service) *WithdrawalHandler
 }
 
 type withdrawRequest struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order string       `json:"order"`
+	Sum   money.Amount `json:"sum"`
 }
 
 func (h *WithdrawalHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
@@ -39,10 +42,12 @@ func (h *WithdrawalHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	switch err {
 	case nil:
 		w.WriteHeader(http.StatusOK)
-	case withdrawal.ErrInvalidOrderNumber:
+	case withdrawal.ErrInvalidOrderNumber, withdrawal.ErrInvalidSum:
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 	case withdrawal.ErrInsufficientFunds:
 		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case withdrawal.ErrDuplicateOrder:
+		http.Error(w, err.Error(), http.StatusConflict)
 	default:
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
@@ -55,16 +60,62 @@ func (h *WithdrawalHandler) GetWithdrawals(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	withdrawals, err := h.WithdrawService.GetUserWithdrawals(r.Context(), userID)
+	filter, err := parseWithdrawalFilter(r)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.WithdrawService.ListWithdrawals(r.Context(), userID, filter)
+	if err != nil {
+		if errors.Is(err, withdrawal.ErrInvalidFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if len(withdrawals) == 0 {
+	if len(page.Items) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	if page.Next != nil {
+		setNextPage(w, r, page.Next.Encode())
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(withdrawals)
+	json.NewEncoder(w).Encode(toWithdrawalResponses(page.Items))
+}
+
+type withdrawalResponse struct {
+	Order       string            `json:"order"`
+	Sum         money.Amount      `json:"sum"`
+	Status      withdrawal.Status `json:"status"`
+	ProcessedAt time.Time         `json:"processed_at"`
+	ReversedAt  *time.Time        `json:"reversed_at,omitempty"`
+}
+
+func toWithdrawalResponse(w *withdrawal.Withdrawal) withdrawalResponse {
+	return withdrawalResponse{
+		Order:       w.Order,
+		Sum:         w.Sum,
+		Status:      w.Status,
+		ProcessedAt: w.ProcessedAt,
+		ReversedAt:  w.ReversedAt,
+	}
+}
+
+func toWithdrawalResponses(withdrawals []*withdrawal.Withdrawal) []withdrawalResponse {
+	response := make([]withdrawalResponse, 0, len(withdrawals))
+	for _, w := range withdrawals {
+		response = append(response, toWithdrawalResponse(w))
+	}
+	return response
+}
+
+// parseWithdrawalFilter разбирает параметры from, to (RFC3339), limit и cursor
+func parseWithdrawalFilter(r *http.Request) (withdrawal.ListFilter, error) {
+	params, err := parsePageParams(r.URL.Query())
+	return withdrawal.ListFilter{Params: params}, err
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"strings"
 
@@ -10,9 +11,18 @@ import (
 
 type contextKey string
 
-const UserIDKey = contextKey("userID")
+const (
+	UserIDKey    = contextKey("userID")
+	SessionIDKey = contextKey("sessionID")
+	RoleKey      = contextKey("role")
+)
+
+// SessionChecker проверяет, что сессия, от которой выпущен токен доступа, не отозвана
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
 
-func AuthMiddleware(jwtManager *authinfra.JWTManager) func(http.Handler) http.Handler {
+func AuthMiddleware(jwtManager *authinfra.JWTManager, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -21,14 +31,43 @@ func AuthMiddleware(jwtManager *authinfra.JWTManager) func(http.Handler) http.Ha
 				return
 			}
 			token := strings.TrimPrefix(authHeader, "Bearer ")
-			userID, err := jwtManager.Verify(token)
+			claims, err := jwtManager.Verify(token)
 			if err != nil {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			active, err := sessions.IsSessionActive(r.Context(), claims.SessionID)
+			if err != nil {
+				log.Printf("failed to check session %s: %v", claims.SessionID, err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+			ctx = context.WithValue(ctx, RoleKey, claims.Role)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// RequireRole пропускает только пользователей с одной из ролей. Ставится после AuthMiddleware.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value(RoleKey).(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	}
+}
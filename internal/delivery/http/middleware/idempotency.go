@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/idempotency"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// Idempotency сохраняет ответ на запрос с заголовком Idempotency-Key и отдаёт его
+// повторно на запросы с тем же ключом и телом. Ключ с другим телом — 422,
+// повтор во время выполнения первого запроса — 409. Ответы 5xx не сохраняются,
+// чтобы клиент мог повторить запрос; так же ключ освобождается, если обработчик
+// запаниковал или ответ не удалось сохранить. Если запрос выполнялся дольше lease и ключ занял
+// повтор, его резерв не трогается. Ставится после AuthMiddleware: ключи у каждого пользователя свои.
+func Idempotency(store idempotency.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotency.MaxKeyLength {
+				http.Error(w, idempotency.ErrInvalidKey.Error(), http.StatusBadRequest)
+				return
+			}
+
+			userID, ok := r.Context().Value(UserIDKey).(int)
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			// Читаем на байт больше лимита: обрезанное тело нельзя ни сравнивать, ни передавать обработчику
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBytes+1))
+			if err != nil {
+				http.Error(w, "invalid request", http.StatusBadRequest)
+				return
+			}
+			if len(body) > maxIdempotentRequestBytes {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := idempotency.Fingerprint(r.Method, r.URL.Path, body)
+
+			reservation := &idempotency.Record{UserID: userID, Key: key, Fingerprint: fingerprint}
+			existing, err := store.Reserve(r.Context(), reservation)
+			if err != nil {
+				log.Printf("failed to reserve idempotency key for user %d: %v", userID, err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if existing != nil {
+				replay(w, existing, fingerprint)
+				return
+			}
+
+			// Ответ уже отправлен клиенту, поэтому сохраняем его независимо от отмены запроса
+			ctx := context.WithoutCancel(r.Context())
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := store.Release(ctx, reservation); err != nil {
+					log.Printf("failed to release idempotency key for user %d: %v", userID, err)
+				}
+			}()
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			reservation.StatusCode = rec.status
+			reservation.ContentType = rec.Header().Get("Content-Type")
+			reservation.Body = rec.body.Bytes()
+			err = store.Complete(ctx, reservation)
+			if errors.Is(err, idempotency.ErrReservationLost) {
+				// Ключ уже занял повтор: освобождать нечего, сохранённым останется его ответ
+				log.Printf("idempotency key reservation of user %d expired before response was stored", userID)
+				completed = true
+				return
+			}
+			if err != nil {
+				log.Printf("failed to store idempotent response for user %d: %v", userID, err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, rec *idempotency.Record, fingerprint string) {
+	switch {
+	case rec.Fingerprint != fingerprint:
+		http.Error(w, idempotency.ErrKeyReused.Error(), http.StatusUnprocessableEntity)
+	case !rec.Completed():
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, idempotency.ErrInProgress.Error(), http.StatusConflict)
+	default:
+		if rec.ContentType != "" {
+			w.Header().Set("Content-Type", rec.ContentType)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(rec.StatusCode)
+		w.Write(rec.Body)
+	}
+}
+
+// recorder пропускает ответ клиенту и запоминает его для сохранения
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
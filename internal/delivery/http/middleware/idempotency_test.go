@@ -0,0 +1,194 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/idempotency"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]*idempotency.Record)}
+}
+
+func (s *memoryStore) Reserve(ctx context.Context, rec *idempotency.Record) (*idempotency.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.records[rec.Key]; ok {
+		copied := *stored
+		return &copied, nil
+	}
+	rec.CreatedAt = time.Now()
+	s.records[rec.Key] = &idempotency.Record{UserID: rec.UserID, Key: rec.Key, Fingerprint: rec.Fingerprint, CreatedAt: rec.CreatedAt}
+	return nil, nil
+}
+
+// reserved возвращает запись, только если она всё ещё принадлежит резерву rec
+func (s *memoryStore) reserved(rec *idempotency.Record) (*idempotency.Record, error) {
+	stored, ok := s.records[rec.Key]
+	if !ok || stored.Completed() || !stored.CreatedAt.Equal(rec.CreatedAt) {
+		return nil, idempotency.ErrReservationLost
+	}
+	return stored, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, rec *idempotency.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.reserved(rec)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	stored.StatusCode, stored.ContentType, stored.Body, stored.CompletedAt = rec.StatusCode, rec.ContentType, rec.Body, &now
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, rec *idempotency.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.reserved(rec); err != nil {
+		return err
+	}
+	delete(s.records, rec.Key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	send := func(h http.Handler, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 7))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("identical retry replays original response", func(t *testing.T) {
+		calls := 0
+		h := middleware.Idempotency(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("done"))
+		}))
+
+		first := send(h, "k1", `{"order":"2377225624","sum":10}`)
+		retry := send(h, "k1", `{"order":"2377225624","sum":10}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusOK, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	})
+
+	t.Run("key reused with different body", func(t *testing.T) {
+		h := middleware.Idempotency(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		send(h, "k1", `{"order":"2377225624","sum":10}`)
+		retry := send(h, "k1", `{"order":"2377225624","sum":20}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, retry.Code)
+	})
+
+	t.Run("retry while first request is in progress", func(t *testing.T) {
+		store := newMemoryStore()
+		store.Reserve(context.Background(), &idempotency.Record{
+			UserID:      7,
+			Key:         "k1",
+			Fingerprint: idempotency.Fingerprint(http.MethodPost, "/api/user/balance/withdraw", []byte(`{}`)),
+		})
+		h := middleware.Idempotency(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("handler must not run")
+		}))
+
+		assert.Equal(t, http.StatusConflict, send(h, "k1", `{}`).Code)
+	})
+
+	t.Run("server error releases key for retry", func(t *testing.T) {
+		calls := 0
+		h := middleware.Idempotency(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				http.Error(w, "db down", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		assert.Equal(t, http.StatusInternalServerError, send(h, "k1", `{}`).Code)
+		assert.Equal(t, http.StatusOK, send(h, "k1", `{}`).Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("panic releases key for retry", func(t *testing.T) {
+		calls := 0
+		h := middleware.Idempotency(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		assert.Panics(t, func() { send(h, "k1", `{}`) })
+		assert.Equal(t, http.StatusOK, send(h, "k1", `{}`).Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("expired reservation taken over by retry is left alone", func(t *testing.T) {
+		store := newMemoryStore()
+		retry := &idempotency.Record{UserID: 7, Key: "k1", Fingerprint: "retry"}
+		h := middleware.Idempotency(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Пока обработчик работал, lease истёк и ключ занял повтор
+			store.mu.Lock()
+			delete(store.records, "k1")
+			store.mu.Unlock()
+			store.Reserve(context.Background(), retry)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("first"))
+		}))
+
+		assert.Equal(t, http.StatusOK, send(h, "k1", `{}`).Code)
+		stored := store.records["k1"]
+		if assert.NotNil(t, stored) {
+			assert.Equal(t, "retry", stored.Fingerprint)
+			assert.False(t, stored.Completed())
+		}
+	})
+
+	t.Run("oversized body rejected without reserving key", func(t *testing.T) {
+		store := newMemoryStore()
+		h := middleware.Idempotency(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("handler must not run")
+		}))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, send(h, "k1", strings.Repeat("x", 1<<20+1)).Code)
+		assert.Empty(t, store.records)
+	})
+
+	t.Run("requests without key are not tracked", func(t *testing.T) {
+		calls := 0
+		h := middleware.Idempotency(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		}))
+
+		send(h, "", `{}`)
+		send(h, "", `{}`)
+		assert.Equal(t, 2, calls)
+	})
+}
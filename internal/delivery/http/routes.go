@@ -5,6 +5,8 @@ import (
 
 	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/handler"
 	"github.com/GarikMirzoyan/gophermart/internal/delivery/http/middleware"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/idempotency"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
 	infraauth "github.com/GarikMirzoyan/gophermart/internal/infrastructure/auth"
 	LoyaltyHandler "github.com/GarikMirzoyan/gophermart/internal/loyalty/handler"
 	"github.com/go-chi/chi/v5"
@@ -12,29 +14,66 @@ import (
 
 func NewRouter(
 	authHandler *handler.AuthHandler,
+	passwordHandler *handler.PasswordHandler,
 	orderHandler *handler.OrderHandler,
 	balanceHandler *handler.BalanceHandler,
 	withdrawalHandler *handler.WithdrawalHandler,
 	loyaltyHandler *LoyaltyHandler.LoyaltyHandler,
+	keysHandler *handler.KeysHandler,
+	adminHandler *handler.AdminHandler,
+	webhookHandler *handler.AccrualWebhookHandler,
+	eventsHandler *handler.EventsHandler,
 	jwtManager *infraauth.JWTManager,
+	sessions middleware.SessionChecker,
+	idempotencyKeys idempotency.Store,
 ) http.Handler {
 	r := chi.NewRouter()
 
+	r.Get("/.well-known/jwks.json", keysHandler.JWKS)
+
 	r.Post("/api/user/register", authHandler.Register)
 	r.Post("/api/user/login", authHandler.Login)
+	r.Post("/api/user/token/refresh", authHandler.Refresh)
+	r.Post("/api/user/password/reset/request", passwordHandler.RequestReset)
+	r.Post("/api/user/password/reset", passwordHandler.ResetPassword)
 
 	// r.Get("/api/orders/{number}", loyaltyHandler.GetOrderAccrual)
 
+	r.Post("/api/accrual/webhook/orders/returned", webhookHandler.OrderReturned)
+
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.AuthMiddleware(jwtManager))
+		r.Use(middleware.AuthMiddleware(jwtManager, sessions))
+
+		r.Post("/api/user/logout", authHandler.Logout)
+		r.Post("/api/user/password", passwordHandler.ChangePassword)
 
 		r.Post("/api/user/orders", orderHandler.AddOrder)
+		r.Post("/api/user/orders/batch", orderHandler.AddOrders)
+		r.Get("/api/user/events", eventsHandler.Stream)
 		r.Get("/api/user/orders", orderHandler.GetOrders)
+		r.Get("/api/user/orders/{number}", orderHandler.GetOrder)
 
 		r.Get("/api/user/balance", balanceHandler.GetBalance)
+		r.Get("/api/user/balance/history", balanceHandler.GetHistory)
 
-		r.Post("/api/user/balance/withdraw", withdrawalHandler.Withdraw)
+		r.With(middleware.Idempotency(idempotencyKeys)).Post("/api/user/balance/withdraw", withdrawalHandler.Withdraw)
 		r.Get("/api/user/withdrawals", withdrawalHandler.GetWithdrawals)
+
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(middleware.RequireRole(string(user.RoleAdmin)))
+
+			r.Get("/users", adminHandler.SearchUsers)
+			r.Get("/users/{id}", adminHandler.GetUser)
+			r.Get("/users/{id}/orders", adminHandler.GetUserOrders)
+			r.Get("/users/{id}/withdrawals", adminHandler.GetUserWithdrawals)
+			r.Get("/users/{id}/balance", adminHandler.GetUserBalance)
+			r.Post("/users/{id}/balance/adjustments", adminHandler.AdjustBalance)
+			r.Post("/users/{id}/block", adminHandler.BlockUser)
+			r.Post("/users/{id}/unblock", adminHandler.UnblockUser)
+			r.Post("/withdrawals/{order}/reverse", adminHandler.ReverseWithdrawal)
+			r.Post("/orders/{number}/return", adminHandler.ReturnOrder)
+			r.Get("/audit", adminHandler.GetAuditLog)
+		})
 	})
 
 	return r
@@ -0,0 +1,29 @@
+package audit
+
+import "time"
+
+type Action string
+
+const (
+	ActionSearchUsers     Action = "SEARCH_USERS"
+	ActionViewUser        Action = "VIEW_USER"
+	ActionViewOrders      Action = "VIEW_ORDERS"
+	ActionViewWithdrawals Action = "VIEW_WITHDRAWALS"
+	ActionViewBalance     Action = "VIEW_BALANCE"
+	ActionBlockUser       Action = "BLOCK_USER"
+	ActionUnblockUser     Action = "UNBLOCK_USER"
+	ActionAdjustBalance   Action = "ADJUST_BALANCE"
+	ActionReverseWithdraw Action = "REVERSE_WITHDRAWAL"
+	ActionReturnOrder     Action = "RETURN_ORDER"
+	ActionViewAuditLog    Action = "VIEW_AUDIT_LOG"
+)
+
+// Entry — запись журнала действий администраторов
+type Entry struct {
+	ID           int64
+	ActorID      int
+	Action       Action
+	TargetUserID int
+	Details      map[string]string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,9 @@
+package audit
+
+import "context"
+
+type Repository interface {
+	Record(ctx context.Context, e *Entry) error
+	// List возвращает последние записи; targetUserID = 0 — по всем пользователям
+	List(ctx context.Context, targetUserID int, limit int) ([]*Entry, error)
+}
@@ -0,0 +1,55 @@
+package balance
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
+
+// Adjustment — ручная корректировка баланса оператором поддержки.
+// Положительная сумма начисляет баллы, отрицательная — списывает.
+type Adjustment struct {
+	UserID     int
+	OperatorID int
+	Amount     money.Amount
+	Reason     string
+	// Force разрешает увести баланс в минус, например при возврате баллов после мошенничества
+	Force bool
+}
+
+func (a *Adjustment) Validate() error {
+	if a.Amount.IsZero() {
+		return ErrZeroAdjustment
+	}
+	if strings.TrimSpace(a.Reason) == "" {
+		return ErrReasonRequired
+	}
+	if a.OperatorID <= 0 {
+		return ErrOperatorRequired
+	}
+	return nil
+}
+
+// Transaction возвращает проводку корректировки для журнала
+func (a *Adjustment) Transaction() *ledger.Transaction {
+	t := ledger.NewAdjustment(a.UserID, a.Amount, strings.TrimSpace(a.Reason))
+	t.OperatorID = a.OperatorID
+	return t
+}
+
+// AuditEntry возвращает запись журнала аудита, которая пишется вместе с проводкой
+func (a *Adjustment) AuditEntry() *audit.Entry {
+	return &audit.Entry{
+		ActorID:      a.OperatorID,
+		Action:       audit.ActionAdjustBalance,
+		TargetUserID: a.UserID,
+		Details: map[string]string{
+			"amount": a.Amount.String(),
+			"reason": strings.TrimSpace(a.Reason),
+			"force":  strconv.FormatBool(a.Force),
+		},
+	}
+}
@@ -0,0 +1,25 @@
+package balance_test
+
+import (
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjustmentValidate(t *testing.T) {
+	valid := balance.Adjustment{UserID: 7, OperatorID: 1, Amount: money.FromFloat(-15), Reason: "fraud"}
+	assert.NoError(t, valid.Validate())
+
+	for name, tc := range map[string]struct {
+		adj balance.Adjustment
+		err error
+	}{
+		"zero amount":  {balance.Adjustment{UserID: 7, OperatorID: 1, Reason: "goodwill"}, balance.ErrZeroAdjustment},
+		"blank reason": {balance.Adjustment{UserID: 7, OperatorID: 1, Amount: money.FromFloat(10), Reason: "  "}, balance.ErrReasonRequired},
+		"no operator":  {balance.Adjustment{UserID: 7, Amount: money.FromFloat(10), Reason: "goodwill"}, balance.ErrOperatorRequired},
+	} {
+		assert.ErrorIs(t, tc.adj.Validate(), tc.err, name)
+	}
+}
@@ -1,12 +1,14 @@
 package balance
 
+import "github.com/GarikMirzoyan/gophermart/internal/domain/money"
+
 type Balance struct {
-	UserID    int     `json:"-"`
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	UserID    int          `json:"-"`
+	Current   money.Amount `json:"current"`
+	Withdrawn money.Amount `json:"withdrawn"`
 }
 
 type WithdrawRequest struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order string       `json:"order"`
+	Sum   money.Amount `json:"sum"`
 }
@@ -0,0 +1,11 @@
+package balance
+
+import "errors"
+
+var (
+	ErrBalanceMismatch  = errors.New("balance does not match ledger")
+	ErrZeroAdjustment   = errors.New("adjustment amount must not be zero")
+	ErrReasonRequired   = errors.New("adjustment reason is required")
+	ErrOperatorRequired = errors.New("adjustment operator is required")
+	ErrNegativeBalance  = errors.New("adjustment would make balance negative")
+)
@@ -4,5 +4,11 @@ import "context"
 
 type Repository interface {
 	GetByUserID(ctx context.Context, userID int) (*Balance, error)
-	Add(ctx context.Context, userID int, amount float64) error
+
+	// id пользователей, у которых есть сохранённый баланс или проводки в журнале, по возрастанию
+	ListUserIDs(ctx context.Context) ([]int, error)
+
+	// Проводит корректировку, меняет баланс и пишет запись журнала аудита в одной транзакции.
+	// Без Force возвращает ErrNegativeBalance, если остаток стал бы отрицательным.
+	Adjust(ctx context.Context, adj *Adjustment) (*Balance, error)
 }
@@ -0,0 +1,39 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// MaxKeyLength — предельная длина заголовка Idempotency-Key
+const MaxKeyLength = 255
+
+// Record — запрос, выполненный с ключом идемпотентности, и сохранённый ответ на него.
+// Пока запрос выполняется, StatusCode равен 0. CreatedAt — время резерва: по нему
+// запрос, занявший ключ, отличает свой резерв от перехваченного после истечения lease.
+type Record struct {
+	UserID      int
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+func (r *Record) Completed() bool {
+	return r.CompletedAt != nil
+}
+
+// Fingerprint — отпечаток запроса: повтор с тем же ключом должен совпадать с ним побайтно
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
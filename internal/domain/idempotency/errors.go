@@ -0,0 +1,13 @@
+package idempotency
+
+import "errors"
+
+var (
+	ErrInvalidKey = errors.New("invalid idempotency key")
+	// ErrKeyReused — ключ уже использован для запроса с другим телом
+	ErrKeyReused = errors.New("idempotency key reused with a different request")
+	// ErrInProgress — первый запрос с этим ключом ещё выполняется
+	ErrInProgress = errors.New("request with this idempotency key is in progress")
+	// ErrReservationLost — резерв ключа истёк и ключ занят заново, ответ сохранить нельзя
+	ErrReservationLost = errors.New("idempotency key reservation lost")
+)
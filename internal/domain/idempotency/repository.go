@@ -0,0 +1,17 @@
+package idempotency
+
+import "context"
+
+type Store interface {
+	// Резервирует ключ rec.Key за пользователем rec.UserID. Если ключ уже занят и не истёк,
+	// возвращает существующую запись, иначе — nil, а в rec.CreatedAt записывает время резерва.
+	Reserve(ctx context.Context, rec *Record) (*Record, error)
+
+	// Сохраняет ответ на запрос, чтобы отдавать его при повторах. Пишет только в резерв
+	// с тем же CreatedAt: если его уже перехватил другой запрос, возвращает ErrReservationLost.
+	Complete(ctx context.Context, rec *Record) error
+
+	// Освобождает ключ, если запрос не удался и его можно повторить. Как и Complete,
+	// трогает только свой резерв, иначе возвращает ErrReservationLost.
+	Release(ctx context.Context, rec *Record) error
+}
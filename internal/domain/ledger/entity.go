@@ -0,0 +1,142 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
+
+type Kind string
+
+const (
+	KindAccrual    Kind = "ACCRUAL"
+	KindWithdrawal Kind = "WITHDRAWAL"
+	KindAdjustment Kind = "ADJUSTMENT"
+	// KindWithdrawalReversal — возврат баллов по отменённому списанию
+	KindWithdrawalReversal Kind = "WITHDRAWAL_REVERSAL"
+	// KindClawback — списание начисления по возвращённому заказу
+	KindClawback Kind = "CLAWBACK"
+	// KindDebtRepayment — погашение долга по возвращённым заказам из нового начисления
+	KindDebtRepayment Kind = "DEBT_REPAYMENT"
+)
+
+// Account — счёт, по которому проходит проводка.
+// У каждого пользователя свой счёт, системные счета — источники и получатели баллов.
+type Account string
+
+const (
+	AccountAccrual    Account = "system:accrual"
+	AccountWithdrawal Account = "system:withdrawal"
+	AccountAdjustment Account = "system:adjustment"
+)
+
+func UserAccount(userID int) Account {
+	return Account(fmt.Sprintf("user:%d", userID))
+}
+
+// Entry — неизменяемая проводка по одному счёту.
+// Положительная сумма — поступление на счёт, отрицательная — списание.
+type Entry struct {
+	ID            int64
+	TransactionID int64
+	Account       Account
+	Amount        money.Amount
+	CreatedAt     time.Time
+}
+
+// Transaction — операция с баллами, состоящая из проводок с нулевой суммой.
+type Transaction struct {
+	ID          int64
+	Kind        Kind
+	UserID      int
+	Reference   string // номер заказа для начислений и списаний
+	Description string
+	OperatorID  int // оператор, проводивший ручную корректировку; 0 — система
+	Entries     []Entry
+	CreatedAt   time.Time
+}
+
+// Totals — остатки по счёту пользователя, рассчитанные по проводкам
+type Totals struct {
+	Current   money.Amount
+	Withdrawn money.Amount
+}
+
+func NewAccrual(userID int, orderNumber string, amount money.Amount) *Transaction {
+	return newTransfer(KindAccrual, userID, orderNumber, AccountAccrual, UserAccount(userID), amount)
+}
+
+func NewWithdrawal(userID int, orderNumber string, amount money.Amount) *Transaction {
+	return newTransfer(KindWithdrawal, userID, orderNumber, UserAccount(userID), AccountWithdrawal, amount)
+}
+
+// NewWithdrawalReversal возвращает пользователю баллы, списанные по заказу
+func NewWithdrawalReversal(userID int, orderNumber string, amount money.Amount, reason string) *Transaction {
+	t := newTransfer(KindWithdrawalReversal, userID, orderNumber, AccountWithdrawal, UserAccount(userID), amount)
+	t.Description = reason
+	return t
+}
+
+// NewClawback списывает баллы, начисленные по возвращённому заказу
+func NewClawback(userID int, orderNumber string, amount money.Amount, reason string) *Transaction {
+	t := newTransfer(KindClawback, userID, orderNumber, UserAccount(userID), AccountAccrual, amount)
+	t.Description = reason
+	return t
+}
+
+// NewDebtRepayment погашает долг из начисления по заказу orderNumber
+func NewDebtRepayment(userID int, orderNumber string, amount money.Amount) *Transaction {
+	return newTransfer(KindDebtRepayment, userID, orderNumber, UserAccount(userID), AccountAccrual, amount)
+}
+
+// NewAdjustment создаёт ручную корректировку. Отрицательная сумма списывает баллы.
+func NewAdjustment(userID int, amount money.Amount, description string) *Transaction {
+	t := newTransfer(KindAdjustment, userID, "", AccountAdjustment, UserAccount(userID), amount)
+	t.Description = description
+	return t
+}
+
+func newTransfer(kind Kind, userID int, reference string, from, to Account, amount money.Amount) *Transaction {
+	return &Transaction{
+		Kind:      kind,
+		UserID:    userID,
+		Reference: reference,
+		Entries: []Entry{
+			{Account: from, Amount: -amount},
+			{Account: to, Amount: amount},
+		},
+	}
+}
+
+// Validate проверяет, что операция сбалансирована: сумма проводок равна нулю
+func (t *Transaction) Validate() error {
+	if len(t.Entries) < 2 {
+		return ErrUnbalanced
+	}
+
+	var sum money.Amount
+	for _, e := range t.Entries {
+		if e.Amount.IsZero() {
+			return ErrZeroAmount
+		}
+		sum += e.Amount
+	}
+	if !sum.IsZero() {
+		return ErrUnbalanced
+	}
+	return nil
+}
+
+// UserAmount возвращает изменение баланса пользователя в этой операции
+func (t *Transaction) UserAmount() money.Amount {
+	account := UserAccount(t.UserID)
+
+	var sum money.Amount
+	for _, e := range t.Entries {
+		if e.Account == account {
+			sum += e.Amount
+		}
+	}
+	return sum
+}
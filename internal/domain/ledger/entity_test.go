@@ -0,0 +1,45 @@
+package ledger_test
+
+import (
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionValidate(t *testing.T) {
+	t.Run("accrual moves points from system to user", func(t *testing.T) {
+		tx := ledger.NewAccrual(7, "12345678903", money.FromFloat(42.5))
+		assert.NoError(t, tx.Validate())
+		assert.Equal(t, money.FromFloat(42.5), tx.UserAmount())
+	})
+
+	t.Run("withdrawal moves points from user to system", func(t *testing.T) {
+		tx := ledger.NewWithdrawal(7, "2377225624", money.FromFloat(10))
+		assert.NoError(t, tx.Validate())
+		assert.Equal(t, money.FromFloat(-10), tx.UserAmount())
+	})
+
+	t.Run("adjustment keeps operator and reason", func(t *testing.T) {
+		adj := &balance.Adjustment{UserID: 7, OperatorID: 1, Amount: money.FromFloat(-3), Reason: " fraud "}
+		tx := adj.Transaction()
+		assert.NoError(t, tx.Validate())
+		assert.Equal(t, ledger.KindAdjustment, tx.Kind)
+		assert.Equal(t, 1, tx.OperatorID)
+		assert.Equal(t, "fraud", tx.Description)
+		assert.Equal(t, money.FromFloat(-3), tx.UserAmount())
+	})
+
+	t.Run("unbalanced entries rejected", func(t *testing.T) {
+		tx := ledger.NewAdjustment(7, money.FromFloat(5), "goodwill")
+		tx.Entries[0].Amount = money.FromFloat(-4)
+		assert.ErrorIs(t, tx.Validate(), ledger.ErrUnbalanced)
+	})
+
+	t.Run("zero amount rejected", func(t *testing.T) {
+		tx := ledger.NewAdjustment(7, 0, "noop")
+		assert.ErrorIs(t, tx.Validate(), ledger.ErrZeroAmount)
+	})
+}
@@ -0,0 +1,10 @@
+package ledger
+
+import "errors"
+
+var (
+	ErrUnbalanced           = errors.New("ledger transaction is not balanced")
+	ErrZeroAmount           = errors.New("ledger entry amount must not be zero")
+	ErrDuplicateTransaction = errors.New("ledger transaction already posted")
+	ErrInvalidFilter        = errors.New("invalid filter")
+)
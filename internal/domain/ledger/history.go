@@ -0,0 +1,68 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+)
+
+const (
+	DefaultHistoryLimit = pagination.DefaultLimit
+	MaxHistoryLimit     = pagination.MaxLimit
+)
+
+// Direction — направление движения баллов по счёту пользователя
+type Direction string
+
+const (
+	DirectionCredit Direction = "credit"
+	DirectionDebit  Direction = "debit"
+)
+
+// HistoryItem — строка выписки по счёту пользователя
+type HistoryItem struct {
+	TransactionID int64
+	Kind          Kind
+	Reference     string
+	Description   string
+	Amount        money.Amount // со знаком: поступление > 0, списание < 0
+	Balance       money.Amount // остаток после операции
+	CreatedAt     time.Time
+}
+
+func (i *HistoryItem) Direction() Direction {
+	if i.Amount.IsNegative() {
+		return DirectionDebit
+	}
+	return DirectionCredit
+}
+
+// HistoryCursor — позиция в выписке: время проводки и её id
+type HistoryCursor = pagination.Cursor
+
+// HistoryFilter — параметры выборки выписки. Нулевые значения означают «без ограничения».
+type HistoryFilter struct {
+	pagination.Params
+	Direction Direction
+}
+
+// Validate приводит лимит к допустимому диапазону и проверяет остальные параметры
+func (f *HistoryFilter) Validate() error {
+	switch f.Direction {
+	case "", DirectionCredit, DirectionDebit:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidFilter, f.Direction)
+	}
+	if err := f.Params.Normalize(DefaultHistoryLimit, MaxHistoryLimit); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	return nil
+}
+
+// HistoryPage — страница выписки и курсор следующей страницы (nil, если это последняя)
+type HistoryPage struct {
+	Items []*HistoryItem
+	Next  *HistoryCursor
+}
@@ -0,0 +1,16 @@
+package ledger
+
+import "context"
+
+type Repository interface {
+	// Остатки по счёту пользователя, рассчитанные по проводкам
+	GetUserTotals(ctx context.Context, userID int) (*Totals, error)
+
+	// Все операции пользователя вместе с проводками, в порядке проведения
+	GetUserTransactions(ctx context.Context, userID int) ([]*Transaction, error)
+
+	// Выписка по счёту пользователя в хронологическом порядке с остатком после каждой операции.
+	// Остаток считается по всем операциям, фильтр ограничивает только выдачу.
+	// filter.Limit = 0 — без ограничения числа записей.
+	GetUserHistory(ctx context.Context, userID int, filter HistoryFilter) ([]*HistoryItem, error)
+}
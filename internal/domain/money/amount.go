@@ -0,0 +1,150 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// scale — количество минимальных единиц в одном балле (точность до сотых)
+const scale = 100
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// decimalPattern — обычная десятичная запись без экспоненты и дробей вида "1/3"
+var decimalPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// Amount — сумма баллов с фиксированной точкой, хранится в сотых долях.
+// В JSON кодируется числом ("current": 500.5), в БД — как NUMERIC.
+type Amount int64
+
+// FromFloat переводит число с плавающей точкой в Amount с округлением до сотых
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * scale))
+}
+
+// FromMinor создаёт Amount из количества сотых долей
+func FromMinor(v int64) Amount {
+	return Amount(v)
+}
+
+// Parse разбирает десятичную запись ("500.5", "-10") без потери точности.
+// Экспонента и дроби не принимаются: Parse разбирает тела запросов, а big.Rat
+// долго разбирает запись вроде "1e999999". Цифры после сотых округляются половиной от нуля.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if !decimalPattern.MatchString(s) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	r.Mul(r, big.NewRat(scale, 1))
+
+	num, den := r.Num(), r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// |rem| * 2 >= den — округляем от нуля
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(int64(num.Sign())))
+	}
+	if !quo.IsInt64() {
+		return 0, fmt.Errorf("%w: %q out of range", ErrInvalidAmount, s)
+	}
+	return Amount(quo.Int64()), nil
+}
+
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+func (a Amount) Float64() float64 {
+	return float64(a) / scale
+}
+
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a < 0
+}
+
+// String возвращает запись с двумя знаками после точки: "500.50"
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/scale, v%scale)
+}
+
+// MarshalJSON кодирует сумму кратчайшей десятичной записью: 500.5, 42, 0.01
+func (a Amount) MarshalJSON() ([]byte, error) {
+	s := a.String()
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return []byte(s), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	// Принимаем и число, и строку с числом
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value передаёт сумму в БД строкой, чтобы NUMERIC получил точное значение
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = Amount(v * scale)
+		return nil
+	case float64:
+		*a = FromFloat(v)
+		return nil
+	case nil:
+		return fmt.Errorf("%w: cannot scan NULL", ErrInvalidAmount)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+}
+
+func (a *Amount) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,81 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want money.Amount
+	}{
+		{"500.5", 50050},
+		{"0.1", 10},
+		{"42", 4200},
+		{"-10.25", -1025},
+		{"729.98", 72998},
+		{"0.005", 1},
+		{"-0.005", -1},
+		{"0.004", 0},
+	}
+	for _, tt := range tests {
+		got, err := money.Parse(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, in := range []string{"abc", "", "1/3", "1e2", "1e999999", "0x10", ".5", "5.", "+5", "1_000"} {
+		_, err := money.Parse(in)
+		assert.ErrorIs(t, err, money.ErrInvalidAmount, in)
+	}
+}
+
+func TestSumHasNoDrift(t *testing.T) {
+	var sum money.Amount
+	for i := 0; i < 10; i++ {
+		a, err := money.Parse("0.1")
+		require.NoError(t, err)
+		sum += a
+	}
+	assert.Equal(t, money.FromMinor(100), sum)
+	assert.Equal(t, "1.00", sum.String())
+}
+
+func TestJSON(t *testing.T) {
+	var resp struct {
+		Current   money.Amount  `json:"current"`
+		Withdrawn money.Amount  `json:"withdrawn"`
+		Accrual   *money.Amount `json:"accrual,omitempty"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"current": 500.5, "withdrawn": 42}`), &resp))
+	assert.Equal(t, money.FromMinor(50050), resp.Current)
+	assert.Nil(t, resp.Accrual)
+
+	out, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"current": 500.5, "withdrawn": 42}`, string(out))
+
+	out, err = json.Marshal(money.FromMinor(-1))
+	require.NoError(t, err)
+	assert.Equal(t, "-0.01", string(out))
+}
+
+func TestScan(t *testing.T) {
+	var a money.Amount
+	require.NoError(t, a.Scan([]byte("123.45")))
+	assert.Equal(t, money.FromMinor(12345), a)
+
+	require.NoError(t, a.Scan(float64(0.3)))
+	assert.Equal(t, money.FromMinor(30), a)
+
+	assert.Error(t, a.Scan(nil))
+
+	v, err := money.FromMinor(12345).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "123.45", v)
+}
@@ -0,0 +1,34 @@
+package order
+
+import "time"
+
+const (
+	DefaultBackoffBase = time.Second
+	DefaultBackoffMax  = 5 * time.Minute
+)
+
+// Backoff — экспоненциальная задержка между опросами системы начислений по одному заказу
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay возвращает задержку перед следующей попыткой: Base * 2^attempts, но не больше Max
+func (b Backoff) Delay(attempts int) time.Duration {
+	base, max := b.Base, b.Max
+	if base <= 0 {
+		base = DefaultBackoffBase
+	}
+	if max <= 0 {
+		max = DefaultBackoffMax
+	}
+
+	delay := base
+	for i := 0; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
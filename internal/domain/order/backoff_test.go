@@ -0,0 +1,21 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := order.Backoff{Base: time.Second, Max: 10 * time.Second}
+
+	assert.Equal(t, time.Second, b.Delay(0))
+	assert.Equal(t, 2*time.Second, b.Delay(1))
+	assert.Equal(t, 8*time.Second, b.Delay(3))
+	assert.Equal(t, 10*time.Second, b.Delay(4))
+	assert.Equal(t, 10*time.Second, b.Delay(1000))
+
+	assert.Equal(t, order.DefaultBackoffBase, order.Backoff{}.Delay(0))
+}
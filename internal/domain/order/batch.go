@@ -0,0 +1,27 @@
+package order
+
+// MaxBatchSize — сколько номеров принимается в одной пачке
+const MaxBatchSize = 1000
+
+// Outcome — результат загрузки одного номера из пачки
+type Outcome string
+
+const (
+	OutcomeAccepted  Outcome = "accepted"  // новый заказ принят в обработку
+	OutcomeDuplicate Outcome = "duplicate" // номер уже загружен этим пользователем
+	OutcomeConflict  Outcome = "conflict"  // номер загружен другим пользователем
+	OutcomeInvalid   Outcome = "invalid"   // номер не прошёл проверку формата
+)
+
+// BatchInsert — итог вставки пачки в хранилище: вставленные номера и владельцы
+// номеров, которые уже были загружены раньше
+type BatchInsert struct {
+	Inserted map[string]bool
+	Owners   map[string]int
+}
+
+// BatchItem — результат по одному номеру пачки
+type BatchItem struct {
+	Number  string  `json:"number"`
+	Outcome Outcome `json:"status"`
+}
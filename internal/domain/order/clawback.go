@@ -0,0 +1,68 @@
+package order
+
+import (
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
+
+// ClawbackPolicy определяет, что делать, если начисление по возвращённому заказу
+// уже потрачено и на балансе не хватает баллов
+type ClawbackPolicy string
+
+const (
+	// ClawbackNegativeBalance списывает начисление целиком, баланс может стать отрицательным
+	ClawbackNegativeBalance ClawbackPolicy = "negative"
+	// ClawbackDebt списывает сколько есть, остаток записывается в долг
+	// и погашается из следующих начислений
+	ClawbackDebt ClawbackPolicy = "debt"
+)
+
+func (p ClawbackPolicy) Valid() bool {
+	return p == ClawbackNegativeBalance || p == ClawbackDebt
+}
+
+// Split делит начисление amount на списание с баланса current и долг
+func (p ClawbackPolicy) Split(current, amount money.Amount) (debit, debt money.Amount) {
+	if p != ClawbackDebt || current >= amount {
+		return amount, 0
+	}
+	if current.IsNegative() {
+		current = 0
+	}
+	return current, amount - current
+}
+
+// Return — запрос на возврат заказа
+type Return struct {
+	Number  string
+	Reason  string
+	Source  EventSource
+	ActorID int
+	Policy  ClawbackPolicy
+}
+
+// ReturnResult — итог возврата: сколько баллов списано и сколько записано в долг
+type ReturnResult struct {
+	UserID     int
+	FromStatus Status
+	Debited    money.Amount
+	Debt       money.Amount
+	Event      *Event // записанный переход в RETURNED
+}
+
+// AuditEntry возвращает запись журнала аудита о возврате заказа администратором,
+// которая пишется в транзакции возврата вместе со списанием
+func (r *Return) AuditEntry(result *ReturnResult) *audit.Entry {
+	return &audit.Entry{
+		ActorID:      r.ActorID,
+		Action:       audit.ActionReturnOrder,
+		TargetUserID: result.UserID,
+		Details: map[string]string{
+			"order":       r.Number,
+			"reason":      r.Reason,
+			"from_status": string(result.FromStatus),
+			"debited":     result.Debited.String(),
+			"debt":        result.Debt.String(),
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package order_test
+
+import (
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClawbackPolicySplit(t *testing.T) {
+	accrual := money.FromFloat(100)
+
+	tests := []struct {
+		name      string
+		policy    order.ClawbackPolicy
+		current   money.Amount
+		wantDebit money.Amount
+		wantDebt  money.Amount
+	}{
+		{"enough balance", order.ClawbackDebt, money.FromFloat(150), accrual, 0},
+		{"negative policy debits everything", order.ClawbackNegativeBalance, money.FromFloat(30), accrual, 0},
+		{"debt policy debits what is left", order.ClawbackDebt, money.FromFloat(30), money.FromFloat(30), money.FromFloat(70)},
+		{"debt policy with negative balance", order.ClawbackDebt, money.FromFloat(-5), 0, accrual},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			debit, debt := tt.policy.Split(tt.current, accrual)
+			assert.Equal(t, tt.wantDebit, debit)
+			assert.Equal(t, tt.wantDebt, debt)
+		})
+	}
+}
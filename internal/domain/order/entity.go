@@ -1,6 +1,10 @@
 package order
 
-import "time"
+import (
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
 
 type Status string
 
@@ -9,12 +13,22 @@ const (
 	StatusProcessing Status = "PROCESSING"
 	StatusInvalid    Status = "INVALID"
 	StatusProcessed  Status = "PROCESSED"
+	// StatusReturned — заказ отменён или возвращён, начисленные баллы списаны
+	StatusReturned Status = "RETURNED"
 )
 
 type Order struct {
+	ID         int64
 	Number     string
 	Status     Status
-	Accrual    *float64
+	Accrual    *money.Amount
 	UploadedAt time.Time
 	UserID     int
+
+	// Сколько раз заказ уже опрашивался в системе начислений и когда опрашивать снова
+	Attempts      int
+	NextAttemptAt time.Time
+
+	// Экземпляр сервиса, который сейчас обрабатывает заказ
+	ClaimedBy string
 }
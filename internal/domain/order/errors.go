@@ -0,0 +1,14 @@
+package order
+
+import "errors"
+
+var (
+	ErrAlreadyProcessed = errors.New("order already processed")
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrAlreadyReturned  = errors.New("order already returned")
+	ErrLeaseLost        = errors.New("order lease lost")
+	ErrNotReturnable    = errors.New("only processed orders can be returned")
+	ErrInvalidFilter    = errors.New("invalid filter")
+	ErrEmptyBatch       = errors.New("no order numbers in batch")
+	ErrBatchTooLarge    = errors.New("too many order numbers in batch")
+)
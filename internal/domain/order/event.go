@@ -0,0 +1,66 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
+
+const (
+	// MaxReplayEvents — сколько пропущенных событий отдаётся клиенту при переподключении
+	MaxReplayEvents = 1000
+
+	// ReplayPageSize — сколько событий истории читается и отдаётся за один запрос
+	ReplayPageSize = 100
+
+	// ReplayLookback — насколько раньше события Last-Event-ID перечитывается история.
+	// id выдаются при вставке, а транзакции фиксируются в другом порядке: событие с меньшим
+	// id может появиться уже после того, как клиент получил больший. Окно покрывает
+	// такие транзакции, если они короче его.
+	ReplayLookback = time.Minute
+)
+
+// EventSource — кто изменил состояние заказа
+type EventSource string
+
+const (
+	SourceWorker  EventSource = "worker"
+	SourceAdmin   EventSource = "admin"
+	SourceAccrual EventSource = "accrual"
+)
+
+// Event — запись об изменении статуса заказа
+type Event struct {
+	ID          int64
+	OrderNumber string
+	FromStatus  Status
+	ToStatus    Status
+	Source      EventSource
+	ActorID     int // администратор, если изменение сделано вручную
+	Reason      string
+	CreatedAt   time.Time
+
+	// Владелец заказа и начисление (для перехода в PROCESSED) — для уведомлений пользователя
+	UserID  int
+	Accrual *money.Amount
+}
+
+// Publisher доставляет записанные события заказов подписчикам. Доставка без гарантий:
+// пропущенное событие клиент дочитывает из истории по Last-Event-ID.
+type Publisher interface {
+	Publish(ctx context.Context, e *Event)
+}
+
+// Details — заказ вместе с историей изменения его статуса, от старых записей к новым
+type Details struct {
+	Order   *Order
+	History []*Event
+}
+
+// Subscription — поток событий одного подписчика. Канал закрывается, если подписчик
+// не успевает читать или доставка остановлена; клиент переподключается с Last-Event-ID.
+type Subscription interface {
+	Events() <-chan *Event
+	Close()
+}
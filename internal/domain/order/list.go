@@ -0,0 +1,58 @@
+package order
+
+import (
+	"fmt"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+)
+
+const (
+	DefaultListLimit = pagination.DefaultLimit
+	MaxListLimit     = pagination.MaxLimit
+)
+
+// SortOrder — направление сортировки заказов по времени загрузки
+type SortOrder string
+
+const (
+	SortDesc SortOrder = "desc"
+	SortAsc  SortOrder = "asc"
+)
+
+// Cursor — позиция в списке заказов: время загрузки и id заказа
+type Cursor = pagination.Cursor
+
+// ListFilter — параметры выборки заказов пользователя. Нулевые значения означают «без ограничения».
+type ListFilter struct {
+	pagination.Params
+	Statuses []Status
+	Sort     SortOrder
+}
+
+// Validate приводит лимит и сортировку к допустимым значениям и проверяет остальные параметры
+func (f *ListFilter) Validate() error {
+	for _, s := range f.Statuses {
+		switch s {
+		case StatusNew, StatusProcessing, StatusInvalid, StatusProcessed, StatusReturned:
+		default:
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, s)
+		}
+	}
+	switch f.Sort {
+	case "":
+		f.Sort = SortDesc
+	case SortDesc, SortAsc:
+	default:
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, f.Sort)
+	}
+	if err := f.Params.Normalize(DefaultListLimit, MaxListLimit); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	return nil
+}
+
+// Page — страница заказов и курсор следующей страницы (nil, если это последняя)
+type Page struct {
+	Items []*Order
+	Next  *Cursor
+}
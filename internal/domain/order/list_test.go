@@ -0,0 +1,37 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFilterValidate(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		f := order.ListFilter{}
+		require.NoError(t, f.Validate())
+		assert.Equal(t, order.SortDesc, f.Sort)
+		assert.Equal(t, order.DefaultListLimit, f.Limit)
+	})
+
+	t.Run("limit capped", func(t *testing.T) {
+		f := order.ListFilter{Params: pagination.Params{Limit: order.MaxListLimit + 1}}
+		require.NoError(t, f.Validate())
+		assert.Equal(t, order.MaxListLimit, f.Limit)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		now := time.Now()
+		for name, f := range map[string]order.ListFilter{
+			"status": {Statuses: []order.Status{"DONE"}},
+			"sort":   {Sort: "random"},
+			"range":  {Params: pagination.Params{From: now, To: now.Add(-time.Hour)}},
+		} {
+			assert.ErrorIs(t, f.Validate(), order.ErrInvalidFilter, name)
+		}
+	})
+}
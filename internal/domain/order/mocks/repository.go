@@ -5,8 +5,11 @@ package mocks
 import (
 	context "context"
 
+	money "github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	order "github.com/GarikMirzoyan/gophermart/internal/domain/order"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Repository is an autogenerated mock type for the Repository type
@@ -32,6 +35,156 @@ func (_m *Repository) AddOrder(ctx context.Context, _a1 *order.Order) error {
 	return r0
 }
 
+// AddOrders provides a mock function with given fields: ctx, userID, numbers, uploadedAt
+func (_m *Repository) AddOrders(ctx context.Context, userID int, numbers []string, uploadedAt time.Time) (*order.BatchInsert, error) {
+	ret := _m.Called(ctx, userID, numbers, uploadedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddOrders")
+	}
+
+	var r0 *order.BatchInsert
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []string, time.Time) (*order.BatchInsert, error)); ok {
+		return rf(ctx, userID, numbers, uploadedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, []string, time.Time) *order.BatchInsert); ok {
+		r0 = rf(ctx, userID, numbers, uploadedAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*order.BatchInsert)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, []string, time.Time) error); ok {
+		r1 = rf(ctx, userID, numbers, uploadedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimOrdersForProcessing provides a mock function with given fields: ctx, owner, limit, lease
+func (_m *Repository) ClaimOrdersForProcessing(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error) {
+	ret := _m.Called(ctx, owner, limit, lease)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimOrdersForProcessing")
+	}
+
+	var r0 []*order.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, time.Duration) ([]*order.Order, error)); ok {
+		return rf(ctx, owner, limit, lease)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, time.Duration) []*order.Order); ok {
+		r0 = rf(ctx, owner, limit, lease)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*order.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, time.Duration) error); ok {
+		r1 = rf(ctx, owner, limit, lease)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreditAccrual provides a mock function with given fields: ctx, orderNumber, userID, owner, accrual
+func (_m *Repository) CreditAccrual(ctx context.Context, orderNumber string, userID int, owner string, accrual money.Amount) (*order.Event, error) {
+	ret := _m.Called(ctx, orderNumber, userID, owner, accrual)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreditAccrual")
+	}
+
+	var r0 *order.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string, money.Amount) (*order.Event, error)); ok {
+		return rf(ctx, orderNumber, userID, owner, accrual)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string, money.Amount) *order.Event); ok {
+		r0 = rf(ctx, orderNumber, userID, owner, accrual)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*order.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, string, money.Amount) error); ok {
+		r1 = rf(ctx, orderNumber, userID, owner, accrual)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrder provides a mock function with given fields: ctx, number
+func (_m *Repository) GetOrder(ctx context.Context, number string) (*order.Order, error) {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrder")
+	}
+
+	var r0 *order.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*order.Order, error)); ok {
+		return rf(ctx, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *order.Order); ok {
+		r0 = rf(ctx, number)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*order.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderEvents provides a mock function with given fields: ctx, number
+func (_m *Repository) GetOrderEvents(ctx context.Context, number string) ([]*order.Event, error) {
+	ret := _m.Called(ctx, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderEvents")
+	}
+
+	var r0 []*order.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*order.Event, error)); ok {
+		return rf(ctx, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*order.Event); ok {
+		r0 = rf(ctx, number)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*order.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOrderOwner provides a mock function with given fields: ctx, number
 func (_m *Repository) GetOrderOwner(ctx context.Context, number string) (int, error) {
 	ret := _m.Called(ctx, number)
@@ -90,29 +243,89 @@ func (_m *Repository) GetOrdersByUser(ctx context.Context, userID int) ([]*order
 	return r0, r1
 }
 
-// GetOrdersForProcessing provides a mock function with given fields: ctx
-func (_m *Repository) GetOrdersForProcessing(ctx context.Context) ([]*order.Order, error) {
-	ret := _m.Called(ctx)
+// ListOrdersByUser provides a mock function with given fields: ctx, userID, filter
+func (_m *Repository) ListOrdersByUser(ctx context.Context, userID int, filter order.ListFilter) ([]*order.Order, error) {
+	ret := _m.Called(ctx, userID, filter)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetOrdersForProcessing")
+		panic("no return value specified for ListOrdersByUser")
 	}
 
 	var r0 []*order.Order
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]*order.Order, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, int, order.ListFilter) ([]*order.Order, error)); ok {
+		return rf(ctx, userID, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []*order.Order); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, int, order.ListFilter) []*order.Order); ok {
+		r0 = rf(ctx, userID, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*order.Order)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, int, order.ListFilter) error); ok {
+		r1 = rf(ctx, userID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUserEvents provides a mock function with given fields: ctx, userID, afterID, limit
+func (_m *Repository) ListUserEvents(ctx context.Context, userID int, afterID int64, limit int) ([]*order.Event, error) {
+	ret := _m.Called(ctx, userID, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUserEvents")
+	}
+
+	var r0 []*order.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int) ([]*order.Event, error)); ok {
+		return rf(ctx, userID, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int) []*order.Event); ok {
+		r0 = rf(ctx, userID, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*order.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64, int) error); ok {
+		r1 = rf(ctx, userID, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLateUserEvents provides a mock function with given fields: ctx, userID, lastID, lookback, limit
+func (_m *Repository) ListLateUserEvents(ctx context.Context, userID int, lastID int64, lookback time.Duration, limit int) ([]*order.Event, error) {
+	ret := _m.Called(ctx, userID, lastID, lookback, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLateUserEvents")
+	}
+
+	var r0 []*order.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, time.Duration, int) ([]*order.Event, error)); ok {
+		return rf(ctx, userID, lastID, lookback, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, time.Duration, int) []*order.Event); ok {
+		r0 = rf(ctx, userID, lastID, lookback, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*order.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64, time.Duration, int) error); ok {
+		r1 = rf(ctx, userID, lastID, lookback, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -120,17 +333,17 @@ func (_m *Repository) GetOrdersForProcessing(ctx context.Context) ([]*order.Orde
 	return r0, r1
 }
 
-// UpdateAccrual provides a mock function with given fields: ctx, orderNumber, status, accrual
-func (_m *Repository) UpdateAccrual(ctx context.Context, orderNumber string, status string, accrual float64) error {
-	ret := _m.Called(ctx, orderNumber, status, accrual)
+// ExtendLease provides a mock function with given fields: ctx, orderNumber, owner, lease
+func (_m *Repository) ExtendLease(ctx context.Context, orderNumber string, owner string, lease time.Duration) error {
+	ret := _m.Called(ctx, orderNumber, owner, lease)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateAccrual")
+		panic("no return value specified for ExtendLease")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, float64) error); ok {
-		r0 = rf(ctx, orderNumber, status, accrual)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Duration) error); ok {
+		r0 = rf(ctx, orderNumber, owner, lease)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -138,17 +351,17 @@ func (_m *Repository) UpdateAccrual(ctx context.Context, orderNumber string, sta
 	return r0
 }
 
-// UpdateStatus provides a mock function with given fields: ctx, orderNumber, status
-func (_m *Repository) UpdateStatus(ctx context.Context, orderNumber string, status string) error {
-	ret := _m.Called(ctx, orderNumber, status)
+// ReleaseOrder provides a mock function with given fields: ctx, orderNumber, owner
+func (_m *Repository) ReleaseOrder(ctx context.Context, orderNumber string, owner string) error {
+	ret := _m.Called(ctx, orderNumber, owner)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateStatus")
+		panic("no return value specified for ReleaseOrder")
 	}
 
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, orderNumber, status)
+		r0 = rf(ctx, orderNumber, owner)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -156,6 +369,96 @@ func (_m *Repository) UpdateStatus(ctx context.Context, orderNumber string, stat
 	return r0
 }
 
+// ReturnOrder provides a mock function with given fields: ctx, ret
+func (_m *Repository) ReturnOrder(ctx context.Context, ret *order.Return) (*order.ReturnResult, error) {
+	ret_1 := _m.Called(ctx, ret)
+
+	if len(ret_1) == 0 {
+		panic("no return value specified for ReturnOrder")
+	}
+
+	var r0 *order.ReturnResult
+	var r1 error
+	if rf, ok := ret_1.Get(0).(func(context.Context, *order.Return) (*order.ReturnResult, error)); ok {
+		return rf(ctx, ret)
+	}
+	if rf, ok := ret_1.Get(0).(func(context.Context, *order.Return) *order.ReturnResult); ok {
+		r0 = rf(ctx, ret)
+	} else {
+		if ret_1.Get(0) != nil {
+			r0 = ret_1.Get(0).(*order.ReturnResult)
+		}
+	}
+
+	if rf, ok := ret_1.Get(1).(func(context.Context, *order.Return) error); ok {
+		r1 = rf(ctx, ret)
+	} else {
+		r1 = ret_1.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ScheduleRetry provides a mock function with given fields: ctx, orderNumber, owner, status, nextAttemptAt
+func (_m *Repository) ScheduleRetry(ctx context.Context, orderNumber string, owner string, status string, nextAttemptAt time.Time) (*order.Event, error) {
+	ret := _m.Called(ctx, orderNumber, owner, status, nextAttemptAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScheduleRetry")
+	}
+
+	var r0 *order.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, time.Time) (*order.Event, error)); ok {
+		return rf(ctx, orderNumber, owner, status, nextAttemptAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, time.Time) *order.Event); ok {
+		r0 = rf(ctx, orderNumber, owner, status, nextAttemptAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*order.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, time.Time) error); ok {
+		r1 = rf(ctx, orderNumber, owner, status, nextAttemptAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateStatus provides a mock function with given fields: ctx, orderNumber, owner, status
+func (_m *Repository) UpdateStatus(ctx context.Context, orderNumber string, owner string, status string) (*order.Event, error) {
+	ret := _m.Called(ctx, orderNumber, owner, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStatus")
+	}
+
+	var r0 *order.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*order.Event, error)); ok {
+		return rf(ctx, orderNumber, owner, status)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *order.Event); ok {
+		r0 = rf(ctx, orderNumber, owner, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*order.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, orderNumber, owner, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewRepository creates a new instance of Repository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewRepository(t interface {
@@ -1,22 +1,75 @@
 package order
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
 
 type Repository interface {
 	// Добавить заказ, вернуть ошибку в случае конфликта или некорректного номера
 	AddOrder(ctx context.Context, order *Order) error
 
+	// Добавить пачку новых заказов пользователя одной транзакцией. Уже существующие номера
+	// не меняются: вставленные номера попадают в Inserted, для остальных в Owners — владелец.
+	AddOrders(ctx context.Context, userID int, numbers []string, uploadedAt time.Time) (*BatchInsert, error)
+
 	// Получить список заказов пользователя, отсортированных по uploaded_at DESC
 	GetOrdersByUser(ctx context.Context, userID int) ([]*Order, error)
 
+	// Страница заказов пользователя по фильтру: не более filter.Limit заказов
+	// после курсора filter.After в порядке (uploaded_at, id) по filter.Sort;
+	// filter.Limit = 0 — все подходящие заказы
+	ListOrdersByUser(ctx context.Context, userID int, filter ListFilter) ([]*Order, error)
+
 	// Проверить существует ли номер заказа и кому он принадлежит
 	GetOrderOwner(ctx context.Context, number string) (int, error)
 
-	// Обновить статус и начисленные баллы по заказу
-	UpdateAccrual(ctx context.Context, orderNumber string, status string, accrual float64) error
+	// Получить заказ по номеру, ErrOrderNotFound если его нет
+	GetOrder(ctx context.Context, number string) (*Order, error)
+
+	// Получить историю изменения статуса заказа в порядке записи
+	GetOrderEvents(ctx context.Context, number string) ([]*Event, error)
+
+	// Перевести заказ в PROCESSED и начислить баллы на баланс пользователя одной транзакцией.
+	// Начисление проходит только из NEW/PROCESSING и только пока заказ захвачен owner;
+	// иначе (уже обработан, INVALID, RETURNED, аренду забрал другой экземпляр)
+	// возвращает ErrAlreadyProcessed и баланс не меняет.
+	// Смена статуса, как и в UpdateStatus и ScheduleRetry, записывается в историю заказа
+	// и возвращается; nil, если статус не изменился.
+	CreditAccrual(ctx context.Context, orderNumber string, userID int, owner string, accrual money.Amount) (*Event, error)
+
+	// Обновить только статус заказа. Запись проходит, только пока заказ захвачен owner:
+	// если аренда истекла и заказ забрал другой экземпляр, ничего не меняется и событие nil.
+	UpdateStatus(ctx context.Context, orderNumber string, owner string, status string) (*Event, error)
+
+	// Запланировать повторный опрос заказа: обновить статус, увеличить счётчик попыток.
+	// Как и UpdateStatus, пишет только от имени текущего владельца аренды.
+	ScheduleRetry(ctx context.Context, orderNumber string, owner string, status string, nextAttemptAt time.Time) (*Event, error)
+
+	// События по заказам пользователя с id больше afterID, по возрастанию id, не более limit
+	ListUserEvents(ctx context.Context, userID int, afterID int64, limit int) ([]*Event, error)
+
+	// События по заказам пользователя с id меньше lastID, созданные не раньше чем за lookback
+	// до события lastID, по возрастанию id, не более limit
+	ListLateUserEvents(ctx context.Context, userID int, lastID int64, lookback time.Duration, limit int) ([]*Event, error)
+
+	// Захватить в аренду на lease не более limit заказов в статусах NEW/PROCESSING,
+	// время опроса которых наступило и которые не захвачены другим экземпляром.
+	// Обновление статуса, начисление и ScheduleRetry снимают аренду.
+	ClaimOrdersForProcessing(ctx context.Context, owner string, limit int, lease time.Duration) ([]*Order, error)
+
+	// Продлить аренду заказа на lease от текущего момента. Если заказ уже не захвачен owner
+	// (аренду забрал другой экземпляр или заказ обработан), возвращает ErrLeaseLost.
+	ExtendLease(ctx context.Context, orderNumber string, owner string, lease time.Duration) error
 
-	// Обновить только статус заказа
-	UpdateStatus(ctx context.Context, orderNumber string, status string) error
+	// Снять аренду, не меняя состояние заказа
+	ReleaseOrder(ctx context.Context, orderNumber string, owner string) error
 
-	GetOrdersForProcessing(ctx context.Context) ([]*Order, error)
+	// Перевести заказ в RETURNED, списать начисленные по нему баллы по политике ret.Policy
+	// и записать изменение статуса одной транзакцией; возврат администратором (SourceAdmin)
+	// там же пишется в журнал аудита. Вернуть можно только PROCESSED заказ:
+	// для RETURNED — ErrAlreadyReturned, для остальных статусов — ErrNotReturnable.
+	ReturnOrder(ctx context.Context, ret *Return) (*ReturnResult, error)
 }
@@ -0,0 +1,82 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Общие для всех списков лимиты страницы: заказы, списания и выписка листаются одинаково
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidRange  = errors.New("from must be before to")
+)
+
+// Cursor — позиция в списке, упорядоченном по времени и id записи
+type Cursor struct {
+	At time.Time
+	ID int64
+}
+
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.At.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	recordID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{At: time.Unix(0, nanos).UTC(), ID: recordID}, nil
+}
+
+// Params — общие параметры постраничной выборки. Нулевые значения означают «без ограничения».
+type Params struct {
+	From  time.Time
+	To    time.Time
+	After *Cursor
+	Limit int
+}
+
+// IsZero сообщает, что клиент не передал ни одного параметра выборки: такой запрос
+// получает весь список без разбиения на страницы
+func (p Params) IsZero() bool {
+	return p.From.IsZero() && p.To.IsZero() && p.After == nil && p.Limit == 0
+}
+
+// Normalize проверяет интервал и приводит лимит к диапазону (0, max], подставляя def вместо нуля
+func (p *Params) Normalize(def, max int) error {
+	if !p.From.IsZero() && !p.To.IsZero() && !p.From.Before(p.To) {
+		return ErrInvalidRange
+	}
+	if p.Limit <= 0 {
+		p.Limit = def
+	}
+	if p.Limit > max {
+		p.Limit = max
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package pagination_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := pagination.Cursor{At: time.Date(2025, 7, 13, 9, 0, 0, 123, time.UTC), ID: 42}
+
+	decoded, err := pagination.DecodeCursor(c.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, c, *decoded)
+
+	for _, s := range []string{"not a cursor", "bm90LWEtY3Vyc29y"} {
+		_, err = pagination.DecodeCursor(s)
+		assert.ErrorIs(t, err, pagination.ErrInvalidCursor, s)
+	}
+}
+
+func TestParamsNormalize(t *testing.T) {
+	p := pagination.Params{}
+	require.NoError(t, p.Normalize(10, 100))
+	assert.Equal(t, 10, p.Limit)
+
+	p = pagination.Params{Limit: 500}
+	require.NoError(t, p.Normalize(10, 100))
+	assert.Equal(t, 100, p.Limit)
+
+	now := time.Now()
+	p = pagination.Params{From: now, To: now}
+	assert.ErrorIs(t, p.Normalize(10, 100), pagination.ErrInvalidRange)
+}
+
+func TestParamsIsZero(t *testing.T) {
+	assert.True(t, pagination.Params{}.IsZero())
+	assert.False(t, pagination.Params{Limit: 1}.IsZero())
+	assert.False(t, pagination.Params{After: &pagination.Cursor{ID: 1}}.IsZero())
+}
@@ -0,0 +1,50 @@
+package throttle
+
+import "time"
+
+// Attempts — счётчик неудачных попыток входа по одному ключу (логину или IP)
+// в пределах скользящего окна
+type Attempts struct {
+	Key           string
+	Failures      int
+	WindowStart   time.Time
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Reservation — результат учёта попытки входа до проверки пароля
+type Reservation struct {
+	Attempts Attempts
+	// RetryAfter больше нуля, если попытка отклонена; счётчик при этом не меняется
+	RetryAfter time.Duration
+	// Locked — попытка превысила порог, и ключ заблокирован
+	Locked bool
+}
+
+// LockoutEvent — запись о временной блокировке ключа
+type LockoutEvent struct {
+	Key         string
+	Failures    int
+	LockedUntil time.Time
+	CreatedAt   time.Time
+}
+
+func LoginKey(login string) string {
+	return "login:" + login
+}
+
+// ResetKey — счётчик запросов сброса пароля; отдельный от входа, чтобы запросы
+// сброса не блокировали вход и не сбрасывались им
+func ResetKey(login string) string {
+	return "reset:" + login
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// ResetIPKey — счётчик запросов сброса пароля с одного адреса; отдельный от IPKey
+// по той же причине, что и ResetKey
+func ResetIPKey(ip string) string {
+	return "reset-ip:" + ip
+}
@@ -0,0 +1,31 @@
+package throttle
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrThrottled = errors.New("too many failed login attempts")
+
+// ThrottledError — попытка отклонена до её проверки; повторить можно через RetryAfter
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%v: retry after %s", ErrThrottled, e.RetryAfter)
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrThrottled
+}
+
+// AsThrottled возвращает задержку, если err вызвана ограничением попыток входа
+func AsThrottled(err error) (time.Duration, bool) {
+	var te *ThrottledError
+	if errors.As(err, &te) {
+		return te.RetryAfter, true
+	}
+	return 0, false
+}
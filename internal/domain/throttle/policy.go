@@ -0,0 +1,119 @@
+package throttle
+
+import "time"
+
+// Policy задаёт, как неудачные попытки замедляют следующие: первые FreeAttempts
+// проходят без задержки, затем задержка удваивается от BaseDelay до MaxDelay,
+// а после LockoutThreshold неудач ключ блокируется на LockoutDuration.
+// Счётчик сбрасывается, если с начала окна прошло больше Window.
+type Policy struct {
+	Window           time.Duration
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+// DefaultLoginPolicy — ограничения на один логин
+var DefaultLoginPolicy = Policy{
+	Window:           15 * time.Minute,
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         30 * time.Second,
+	LockoutThreshold: 10,
+	LockoutDuration:  15 * time.Minute,
+}
+
+// DefaultIPPolicy — ограничения на один адрес; мягче, чем на логин,
+// чтобы не блокировать пользователей за общим NAT
+var DefaultIPPolicy = Policy{
+	Window:           15 * time.Minute,
+	FreeAttempts:     20,
+	BaseDelay:        time.Second,
+	MaxDelay:         30 * time.Second,
+	LockoutThreshold: 100,
+	LockoutDuration:  15 * time.Minute,
+}
+
+// DefaultResetPolicy — ограничения на запросы сброса пароля для одного логина:
+// каждый запрос отправляет письмо, поэтому частые повторы не нужны
+var DefaultResetPolicy = Policy{
+	Window:       time.Hour,
+	FreeAttempts: 3,
+	BaseDelay:    time.Minute,
+	MaxDelay:     15 * time.Minute,
+}
+
+// DefaultResetIPPolicy — ограничения на запросы сброса пароля с одного адреса;
+// мягче, чем на логин, чтобы не мешать пользователям за общим NAT
+var DefaultResetIPPolicy = Policy{
+	Window:       time.Hour,
+	FreeAttempts: 20,
+	BaseDelay:    time.Minute,
+	MaxDelay:     15 * time.Minute,
+}
+
+// Delay возвращает задержку после failures неудачных попыток подряд
+func (p Policy) Delay(failures int) time.Duration {
+	if failures <= p.FreeAttempts || p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// RetryAfter возвращает, сколько ещё ждать до следующей попытки; 0 — можно пробовать
+func (p Policy) RetryAfter(a *Attempts, now time.Time) time.Duration {
+	if a == nil {
+		return 0
+	}
+	if now.Before(a.LockedUntil) {
+		return a.LockedUntil.Sub(now)
+	}
+	if p.Expired(a, now) {
+		return 0
+	}
+	next := a.LastFailureAt.Add(p.Delay(a.Failures))
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// Expired сообщает, что окно счётчика закончилось и его нужно начинать заново
+func (p Policy) Expired(a *Attempts, now time.Time) bool {
+	return now.Sub(a.WindowStart) > p.Window
+}
+
+// Reserve учитывает очередную попытку по счётчику a. Попытка считается неудачной
+// заранее, до проверки пароля: успешный вход затем сбрасывает или уменьшает счётчик.
+// Запрещённая сейчас попытка не учитывается, чтобы не отодвигать обещанный RetryAfter.
+func (p Policy) Reserve(a Attempts, now time.Time) Reservation {
+	if wait := p.RetryAfter(&a, now); wait > 0 {
+		return Reservation{Attempts: a, RetryAfter: wait}
+	}
+	if p.Expired(&a, now) {
+		a.Failures = 0
+		a.WindowStart = now
+	}
+	a.Failures++
+	a.LastFailureAt = now
+
+	locked := p.ShouldLock(&a, now)
+	if locked {
+		a.LockedUntil = now.Add(p.LockoutDuration)
+	}
+	return Reservation{Attempts: a, Locked: locked}
+}
+
+// ShouldLock сообщает, что после очередной неудачи ключ нужно заблокировать
+func (p Policy) ShouldLock(a *Attempts, now time.Time) bool {
+	return p.LockoutThreshold > 0 && a.Failures >= p.LockoutThreshold && !now.Before(a.LockedUntil)
+}
@@ -0,0 +1,43 @@
+package throttle_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	p := throttle.Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Duration(0), p.Delay(0))
+	assert.Equal(t, time.Duration(0), p.Delay(3))
+	assert.Equal(t, time.Second, p.Delay(4))
+	assert.Equal(t, 2*time.Second, p.Delay(5))
+	assert.Equal(t, 8*time.Second, p.Delay(7))
+	assert.Equal(t, 10*time.Second, p.Delay(8))
+	assert.Equal(t, 10*time.Second, p.Delay(1000))
+}
+
+func TestPolicy_Reserve(t *testing.T) {
+	p := throttle.Policy{Window: time.Minute, FreeAttempts: 1, BaseDelay: time.Second, LockoutThreshold: 3, LockoutDuration: time.Hour}
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	r := p.Reserve(throttle.Attempts{WindowStart: now}, now)
+	assert.Zero(t, r.RetryAfter)
+	assert.Equal(t, 1, r.Attempts.Failures)
+
+	r = p.Reserve(r.Attempts, now)
+	assert.Equal(t, 2, r.Attempts.Failures)
+
+	// Отклонённая попытка не учитывается
+	rejected := p.Reserve(r.Attempts, now)
+	assert.Equal(t, time.Second, rejected.RetryAfter)
+	assert.Equal(t, r.Attempts, rejected.Attempts)
+
+	r = p.Reserve(r.Attempts, now.Add(time.Second))
+	assert.Equal(t, 3, r.Attempts.Failures)
+	assert.True(t, r.Locked)
+	assert.Equal(t, now.Add(time.Second+time.Hour), r.Attempts.LockedUntil)
+}
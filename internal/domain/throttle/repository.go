@@ -0,0 +1,21 @@
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+type Store interface {
+	// Get возвращает счётчик ключа или nil, если неудач не было
+	Get(ctx context.Context, key string) (*Attempts, error)
+	// Reserve применяет policy.Reserve к счётчику ключа атомарно: параллельные
+	// попытки по одному ключу учитываются по очереди и видят друг друга
+	Reserve(ctx context.Context, key string, policy Policy, now time.Time) (*Reservation, error)
+	// Refund возвращает одну учтённую попытку, если она оказалась успешной
+	Refund(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+	RecordLockout(ctx context.Context, e LockoutEvent) error
+	// Purge удаляет счётчики, окно которых началось раньше windowBefore и которые
+	// не заблокированы на момент now; возвращает число удалённых
+	Purge(ctx context.Context, windowBefore, now time.Time) (int64, error)
+}
@@ -0,0 +1,49 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// RefreshToken — запись о выданном refresh-токене. Сам токен не хранится,
+// только его SHA-256. Все токены, полученные друг из друга ротацией,
+// образуют семейство (сессию) с общим FamilyID.
+type RefreshToken struct {
+	ID        int64
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
+
+// Usable сообщает, можно ли обменять токен на новую пару
+func (t *RefreshToken) Usable(now time.Time) bool {
+	return t.RotatedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// Spent сообщает, что токен уже был обменян или отозван.
+// Повторное предъявление такого токена означает его утечку.
+func (t *RefreshToken) Spent() bool {
+	return t.RotatedAt != nil || t.RevokedAt != nil
+}
+
+// NewSecret генерирует случайное значение для refresh-токена или идентификатора семейства
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash возвращает хеш, под которым токен хранится в БД
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,9 @@
+package token
+
+import "errors"
+
+var (
+	ErrInvalidToken  = errors.New("invalid refresh token")
+	ErrTokenReused   = errors.New("refresh token reused")
+	ErrTokenNotFound = errors.New("refresh token not found")
+)
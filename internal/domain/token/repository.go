@@ -0,0 +1,14 @@
+package token
+
+import "context"
+
+type Repository interface {
+	Create(ctx context.Context, t *RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// Rotate помечает старый токен использованным и сохраняет новый в одной транзакции.
+	// Если старый токен уже использован или отозван, возвращает ErrTokenReused.
+	Rotate(ctx context.Context, oldID int64, next *RefreshToken) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID int) error
+	IsFamilyActive(ctx context.Context, familyID string) (bool, error)
+}
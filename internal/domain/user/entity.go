@@ -1,7 +1,37 @@
 package user
 
+import "time"
+
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
 type User struct {
-	ID       int64
-	Login    string
-	Password string
+	ID        int64
+	Login     string
+	Password  string
+	Role      Role
+	CreatedAt time.Time
+	// BlockedAt — когда аккаунт заблокирован администратором; nil, если не заблокирован
+	BlockedAt *time.Time
+}
+
+func (u *User) Blocked() bool {
+	return u.BlockedAt != nil
+}
+
+// ResetToken — одноразовый токен сброса пароля. Хранится только хеш токена.
+type ResetToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
 }
@@ -0,0 +1,10 @@
+package user
+
+import "errors"
+
+var (
+	ErrLoginTaken        = errors.New("login already taken")
+	ErrAccountBlocked    = errors.New("account is blocked")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
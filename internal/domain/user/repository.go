@@ -1,8 +1,37 @@
 package user
 
-import "context"
+import (
+	"context"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+)
 
 type Repository interface {
 	CreateUser(ctx context.Context, user *User) (*User, error)
 	GetByLogin(ctx context.Context, login string) (*User, error)
+	GetByID(ctx context.Context, id int64) (*User, error)
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
+	// ChangePassword меняет пароль и в той же транзакции отзывает все сессии пользователя,
+	// чтобы после смены пароля не осталось токенов, выданных по старому
+	ChangePassword(ctx context.Context, id int64, passwordHash string) error
+	// SearchUsers ищет пользователей по подстроке логина; пустой запрос возвращает последних зарегистрированных
+	SearchUsers(ctx context.Context, query string, limit int) ([]*User, error)
+	// SetBlocked блокирует или разблокирует пользователя и пишет entry в журнал аудита одной
+	// транзакцией. При блокировке там же отзываются все сессии пользователя.
+	SetBlocked(ctx context.Context, id int64, blocked bool, entry *audit.Entry) error
+	// SyncAdmins выдаёт роль администратора ровно пользователям ids, остальных администраторов
+	// понижает и возвращает их id. Если кого-то из ids нет, ничего не меняет и возвращает ErrUserNotFound.
+	SyncAdmins(ctx context.Context, ids []int64) (demoted []int64, err error)
+}
+
+type ResetTokenRepository interface {
+	// CreateResetToken сохраняет новый токен и гасит ранее выданные неиспользованные токены пользователя
+	CreateResetToken(ctx context.Context, t *ResetToken) error
+	// GetResetTokenUser возвращает владельца действующего токена, не погашая его.
+	// Если токен не найден, уже использован или истёк, возвращает ErrInvalidResetToken.
+	GetResetTokenUser(ctx context.Context, tokenHash string) (int64, error)
+	// ResetPassword в одной транзакции помечает токен использованным, меняет пароль
+	// и отзывает все сессии пользователя.
+	// Если токен не найден, уже использован или истёк, возвращает ErrInvalidResetToken.
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int64, error)
 }
@@ -0,0 +1,155 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrValidation = errors.New("validation failed")
+
+const (
+	LoginMinLength = 3
+	LoginMaxLength = 64
+	// PasswordMaxBytes — bcrypt учитывает только первые 72 байта пароля
+	PasswordMaxBytes = 72
+)
+
+// FieldError описывает одну ошибку в поле запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError собирает все ошибки запроса, чтобы клиент мог показать их разом
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return fmt.Sprintf("%v: %s", ErrValidation, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+func (e *ValidationError) add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// AsValidation возвращает ошибки полей, если err вызвана некорректным вводом
+func AsValidation(err error) ([]FieldError, bool) {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Fields, true
+	}
+	return nil, false
+}
+
+// ValidateLogin проверяет длину логина и отсутствие пробельных и управляющих символов
+func ValidateLogin(login string) error {
+	ve := &ValidationError{}
+	validateLogin(ve, login)
+	return ve.err()
+}
+
+func validateLogin(ve *ValidationError, login string) {
+	n := utf8.RuneCountInString(login)
+	switch {
+	case n == 0:
+		ve.add("login", "required", "login is required")
+		return
+	case n < LoginMinLength:
+		ve.add("login", "too_short", fmt.Sprintf("login must be at least %d characters", LoginMinLength))
+	case n > LoginMaxLength:
+		ve.add("login", "too_long", fmt.Sprintf("login must be at most %d characters", LoginMaxLength))
+	}
+	if !utf8.ValidString(login) || strings.IndexFunc(login, func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) >= 0 {
+		ve.add("login", "invalid_characters", "login must not contain spaces or control characters")
+	}
+}
+
+// PasswordPolicy — настраиваемые требования к сложности пароля
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8}
+
+// Validate проверяет пароль; login передаётся, чтобы запретить пароль, совпадающий с логином
+func (p PasswordPolicy) Validate(password, login string) error {
+	ve := &ValidationError{}
+	p.validate(ve, password, login)
+	return ve.err()
+}
+
+// ValidateRegistration проверяет логин и пароль нового пользователя
+func (p PasswordPolicy) ValidateRegistration(login, password string) error {
+	ve := &ValidationError{}
+	validateLogin(ve, login)
+	p.validate(ve, password, login)
+	return ve.err()
+}
+
+func (p PasswordPolicy) validate(ve *ValidationError, password, login string) {
+	if password == "" {
+		ve.add("password", "required", "password is required")
+		return
+	}
+	if utf8.RuneCountInString(password) < p.MinLength {
+		ve.add("password", "too_short", fmt.Sprintf("password must be at least %d characters", p.MinLength))
+	}
+	if len(password) > PasswordMaxBytes {
+		ve.add("password", "too_long", fmt.Sprintf("password must be at most %d bytes", PasswordMaxBytes))
+	}
+	if login != "" && strings.EqualFold(password, login) {
+		ve.add("password", "same_as_login", "password must differ from login")
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		ve.add("password", "missing_upper", "password must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		ve.add("password", "missing_lower", "password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		ve.add("password", "missing_digit", "password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		ve.add("password", "missing_symbol", "password must contain a symbol")
+	}
+}
@@ -0,0 +1,67 @@
+package user_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldCodes(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	fields, ok := user.AsValidation(err)
+	require.True(t, ok, "expected validation error, got %v", err)
+	codes := make([]string, 0, len(fields))
+	for _, f := range fields {
+		codes = append(codes, f.Field+":"+f.Code)
+	}
+	return codes
+}
+
+func TestValidateLogin(t *testing.T) {
+	tests := []struct {
+		login string
+		want  []string
+	}{
+		{"alice", nil},
+		{"алиса_01", nil},
+		{"", []string{"login:required"}},
+		{"ab", []string{"login:too_short"}},
+		{strings.Repeat("a", user.LoginMaxLength+1), []string{"login:too_long"}},
+		{"al ice", []string{"login:invalid_characters"}},
+		{"al\x00ice", []string{"login:invalid_characters"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, fieldCodes(t, user.ValidateLogin(tt.login)), "login %q", tt.login)
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	strict := user.PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		policy   user.PasswordPolicy
+		password string
+		login    string
+		want     []string
+	}{
+		{"default accepts long password", user.DefaultPasswordPolicy, "correcthorse", "alice", nil},
+		{"empty", user.DefaultPasswordPolicy, "", "alice", []string{"password:required"}},
+		{"too short", user.DefaultPasswordPolicy, "short", "alice", []string{"password:too_short"}},
+		{"too long for bcrypt", user.DefaultPasswordPolicy, strings.Repeat("x", 73), "alice", []string{"password:too_long"}},
+		{"same as login", user.DefaultPasswordPolicy, "AliceSmith", "alicesmith", []string{"password:same_as_login"}},
+		{"strict accepts mixed", strict, "Corr3ct-horse", "alice", nil},
+		{"strict reports every missing class", strict, "aaaaaaaa", "alice", []string{"password:missing_upper", "password:missing_digit", "password:missing_symbol"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, fieldCodes(t, tt.policy.Validate(tt.password, tt.login)))
+		})
+	}
+}
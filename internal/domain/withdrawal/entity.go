@@ -1,10 +1,43 @@
 package withdrawal
 
-import "time"
+import (
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
+
+type Status string
+
+const (
+	StatusCompleted Status = "COMPLETED"
+	// StatusReversed — заказ, оплаченный баллами, отменён, баллы возвращены на счёт
+	StatusReversed Status = "REVERSED"
+)
 
 type Withdrawal struct {
-	Order       string    `json:"order"`
-	Sum         float64   `json:"sum"`
-	UserID      int       `json:"-"`
-	ProcessedAt time.Time `json:"processed_at"`
+	ID             int64
+	Order          string
+	Sum            money.Amount
+	UserID         int
+	Status         Status
+	ProcessedAt    time.Time
+	ReversedAt     *time.Time
+	ReversalReason string
+}
+
+// ReversalAuditEntry возвращает запись журнала аудита об отмене списания администратором actorID,
+// которая пишется вместе с возвратом баллов
+func (w *Withdrawal) ReversalAuditEntry(actorID int) *audit.Entry {
+	return &audit.Entry{
+		ActorID:      actorID,
+		Action:       audit.ActionReverseWithdraw,
+		TargetUserID: w.UserID,
+		Details: map[string]string{
+			"order":  w.Order,
+			"reason": w.ReversalReason,
+			"sum":    w.Sum.String(),
+			"status": string(w.Status),
+		},
+	}
 }
@@ -5,5 +5,11 @@ import "errors"
 var (
 	ErrInsufficientFunds  = errors.New("insufficient funds")
 	ErrInvalidOrderNumber = errors.New("invalid order number")
+	ErrInvalidSum         = errors.New("withdrawal sum must be positive")
 	ErrWithdrawSaveFailed = errors.New("failed to process withdrawal")
+	ErrDuplicateOrder     = errors.New("withdrawal for this order already exists")
+	ErrWithdrawalNotFound = errors.New("withdrawal not found")
+	ErrAlreadyReversed    = errors.New("withdrawal already reversed")
+	ErrReasonRequired     = errors.New("reversal reason is required")
+	ErrInvalidFilter      = errors.New("invalid filter")
 )
@@ -0,0 +1,35 @@
+package withdrawal
+
+import (
+	"fmt"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+)
+
+const (
+	DefaultListLimit = pagination.DefaultLimit
+	MaxListLimit     = pagination.MaxLimit
+)
+
+// Cursor — позиция в истории списаний: время списания и id записи
+type Cursor = pagination.Cursor
+
+// ListFilter — параметры выборки списаний пользователя. Нулевые значения означают «без ограничения».
+// Списания всегда отдаются от новых к старым, как того требует спецификация.
+type ListFilter struct {
+	pagination.Params
+}
+
+// Validate приводит лимит к допустимому диапазону и проверяет остальные параметры
+func (f *ListFilter) Validate() error {
+	if err := f.Params.Normalize(DefaultListLimit, MaxListLimit); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	return nil
+}
+
+// Page — страница списаний и курсор следующей страницы (nil, если это последняя)
+type Page struct {
+	Items []*Withdrawal
+	Next  *Cursor
+}
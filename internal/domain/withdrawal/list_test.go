@@ -0,0 +1,25 @@
+package withdrawal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFilterValidate(t *testing.T) {
+	f := withdrawal.ListFilter{Params: pagination.Params{Limit: withdrawal.MaxListLimit * 2}}
+	require.NoError(t, f.Validate())
+	assert.Equal(t, withdrawal.MaxListLimit, f.Limit)
+
+	f = withdrawal.ListFilter{}
+	require.NoError(t, f.Validate())
+	assert.Equal(t, withdrawal.DefaultListLimit, f.Limit)
+
+	now := time.Now()
+	f = withdrawal.ListFilter{Params: pagination.Params{From: now, To: now}}
+	assert.ErrorIs(t, f.Validate(), withdrawal.ErrInvalidFilter)
+}
@@ -1,9 +1,22 @@
 package withdrawal
 
-import "context"
+import (
+	"context"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+)
 
 type Repository interface {
-	Withdraw(ctx context.Context, userID int, order string, sum float64) error
+	Withdraw(ctx context.Context, userID int, order string, sum money.Amount) error
 	GetUserWithdrawals(ctx context.Context, userID int) ([]*Withdrawal, error)
-	GetTotalWithdrawn(ctx context.Context, userID int) (float64, error)
+
+	// Возвращает не более filter.Limit списаний пользователя после курсора, от новых к старым
+	ListUserWithdrawals(ctx context.Context, userID int, filter ListFilter) ([]*Withdrawal, error)
+
+	GetByOrder(ctx context.Context, order string) (*Withdrawal, error)
+
+	// Помечает списание отменённым, возвращает баллы на счёт и пишет в журнал аудита
+	// отмену администратором actorID в одной транзакции.
+	// Повторная отмена возвращает ErrAlreadyReversed.
+	Reverse(ctx context.Context, order string, reason string, actorID int) (*Withdrawal, error)
 }
@@ -10,42 +10,69 @@ import (
 var ErrInvalidToken = errors.New("invalid token")
 
 type JWTManager struct {
-	secretKey     string
+	keys          *KeySet
 	tokenDuration time.Duration
 }
 
-func NewJWTManager(secretKey string, duration time.Duration) *JWTManager {
-	return &JWTManager{secretKey: secretKey, tokenDuration: duration}
+func NewJWTManager(keys *KeySet, duration time.Duration) *JWTManager {
+	return &JWTManager{keys: keys, tokenDuration: duration}
 }
 
 type Claims struct {
 	UserID int `json:"user_id"`
+	// SessionID — семейство refresh-токенов, от которого выпущен токен доступа.
+	// По нему проверяется, не была ли сессия отозвана.
+	SessionID string `json:"sid"`
+	Role      string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func (j *JWTManager) Generate(userID int) (string, error) {
+func (j *JWTManager) Generate(userID int, sessionID, role string) (string, error) {
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.tokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	key := j.keys.Current()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
 }
 
-func (j *JWTManager) Verify(tokenString string) (int, error) {
+// JWKS возвращает открытые ключи для проверки токенов другими сервисами
+func (j *JWTManager) JWKS() JWKS {
+	return j.keys.JWKS()
+}
+
+// TokenDuration возвращает время жизни токена доступа
+func (j *JWTManager) TokenDuration() time.Duration {
+	return j.tokenDuration
+}
+
+func (j *JWTManager) Verify(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.secretKey), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, err := j.keys.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		// Алгоритм задаётся ключом, а не заголовком токена
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.verifyKey, nil
+	}, jwt.WithValidMethods(j.keys.algorithms()))
 	if err != nil {
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return 0, ErrInvalidToken
+	if !ok || !token.Valid || claims.SessionID == "" {
+		return nil, ErrInvalidToken
 	}
-	return claims.UserID, nil
+	return claims, nil
 }
@@ -0,0 +1,176 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTManager_KeyID(t *testing.T) {
+	keys, err := auth.NewSecretKeySet("current-secret")
+	require.NoError(t, err)
+	manager := auth.NewJWTManager(keys, time.Minute)
+
+	signed, err := manager.Generate(7, "sid", "user")
+	require.NoError(t, err)
+
+	token, _, err := jwt.NewParser().ParseUnverified(signed, &auth.Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, keys.Current().ID, token.Header["kid"])
+
+	claims, err := manager.Verify(signed)
+	require.NoError(t, err)
+	assert.Equal(t, 7, claims.UserID)
+	assert.Equal(t, "sid", claims.SessionID)
+	assert.Equal(t, "user", claims.Role)
+}
+
+func TestJWTManager_Rotation(t *testing.T) {
+	oldKeys, err := auth.NewSecretKeySet("old-secret")
+	require.NoError(t, err)
+	oldToken, err := auth.NewJWTManager(oldKeys, time.Minute).Generate(7, "sid", "user")
+	require.NoError(t, err)
+
+	t.Run("previous key is accepted during rotation", func(t *testing.T) {
+		keys, err := auth.NewSecretKeySet("new-secret", "old-secret")
+		require.NoError(t, err)
+
+		_, err = auth.NewJWTManager(keys, time.Minute).Verify(oldToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("dropped key is rejected", func(t *testing.T) {
+		keys, err := auth.NewSecretKeySet("new-secret")
+		require.NoError(t, err)
+
+		_, err = auth.NewJWTManager(keys, time.Minute).Verify(oldToken)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("expired previous key is rejected", func(t *testing.T) {
+		current, err := auth.NewHMACKey("new", "new-secret")
+		require.NoError(t, err)
+		previous, err := auth.NewHMACKey(oldKeys.Current().ID, "old-secret")
+		require.NoError(t, err)
+		previous.ExpiresAt = time.Now().Add(-time.Hour)
+		keys, err := auth.NewKeySet(current, previous)
+		require.NoError(t, err)
+
+		_, err = auth.NewJWTManager(keys, time.Minute).Verify(oldToken)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+}
+
+func TestJWTManager_Asymmetric(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		key *rsa.PrivateKey
+		ed  ed25519.PrivateKey
+		alg string
+		kty string
+	}{
+		"RS256": {key: rsaKey, alg: "RS256", kty: "RSA"},
+		"EdDSA": {ed: edKey, alg: "EdDSA", kty: "OKP"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var key *auth.Key
+			if tc.key != nil {
+				key, err = auth.NewSigningKey("k1", tc.key)
+			} else {
+				key, err = auth.NewSigningKey("k1", tc.ed)
+			}
+			require.NoError(t, err)
+			keys, err := auth.NewKeySet(key)
+			require.NoError(t, err)
+			manager := auth.NewJWTManager(keys, time.Minute)
+
+			signed, err := manager.Generate(7, "sid", "user")
+			require.NoError(t, err)
+			_, err = manager.Verify(signed)
+			require.NoError(t, err)
+
+			jwks := manager.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "k1", jwks.Keys[0].KeyID)
+			assert.Equal(t, tc.alg, jwks.Keys[0].Algorithm)
+			assert.Equal(t, tc.kty, jwks.Keys[0].KeyType)
+		})
+	}
+}
+
+func TestJWTManager_RejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	current, err := auth.NewSigningKey("rsa", rsaKey)
+	require.NoError(t, err)
+	keys, err := auth.NewKeySet(current)
+	require.NoError(t, err)
+
+	// Токен, подписанный HS256 с открытым ключом в качестве секрета
+	pub, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{UserID: 1, SessionID: "sid"})
+	forged.Header["kid"] = "rsa"
+	signed, err := forged.SignedString(pub)
+	require.NoError(t, err)
+
+	_, err = auth.NewJWTManager(keys, time.Minute).Verify(signed)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestJWKS_ExcludesSecrets(t *testing.T) {
+	keys, err := auth.NewSecretKeySet("secret")
+	require.NoError(t, err)
+
+	assert.Empty(t, keys.JWKS().Keys)
+}
+
+func TestLoadKeySetFile(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ed.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.secret"), []byte("old-secret\n"), 0o600))
+
+	path := filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"current": "ed",
+		"keys": [
+			{"kid": "ed", "private_key_file": "ed.pem"},
+			{"kid": "old", "secret_file": "old.secret", "expires_at": "2999-01-01T00:00:00Z"}
+		]
+	}`), 0o600))
+
+	keys, err := auth.LoadKeySetFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ed", keys.Current().ID)
+	assert.Equal(t, "EdDSA", keys.Current().Method.Alg())
+
+	old, err := auth.NewHMACKey("old", "old-secret")
+	require.NoError(t, err)
+	oldKeys, err := auth.NewKeySet(old)
+	require.NoError(t, err)
+	oldToken, err := auth.NewJWTManager(oldKeys, time.Minute).Generate(7, "sid", "user")
+	require.NoError(t, err)
+
+	_, err = auth.NewJWTManager(keys, time.Minute).Verify(oldToken)
+	assert.NoError(t, err)
+}
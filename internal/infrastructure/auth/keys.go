@@ -0,0 +1,355 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidKey   = errors.New("invalid signing key")
+	ErrUnknownKey   = errors.New("unknown key id")
+	ErrNoSigningKey = errors.New("current key cannot sign tokens")
+)
+
+// Key — ключ подписи или проверки токенов с идентификатором kid.
+// Для HS256 signKey и verifyKey совпадают; для RS256/EdDSA ключ предыдущего
+// поколения может содержать только открытую часть и использоваться лишь для проверки.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	ExpiresAt time.Time
+
+	signKey   any
+	verifyKey any
+}
+
+func NewHMACKey(id, secret string) (*Key, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("%w: empty secret for key %q", ErrInvalidKey, id)
+	}
+	if id == "" {
+		id = fingerprint([]byte(secret))
+	}
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}, nil
+}
+
+// NewSigningKey создаёт асимметричный ключ по закрытой части (*rsa.PrivateKey или ed25519.PrivateKey)
+func NewSigningKey(id string, private crypto.Signer) (*Key, error) {
+	method, err := methodFor(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: id, Method: method, signKey: private, verifyKey: private.Public()}, nil
+}
+
+// NewVerificationKey создаёт ключ, которым можно только проверять подписи
+func NewVerificationKey(id string, public crypto.PublicKey) (*Key, error) {
+	method, err := methodFor(public)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: id, Method: method, verifyKey: public}, nil
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+func methodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidKey, public)
+	}
+}
+
+// KeySet — текущий ключ, которым подписываются новые токены, и предыдущие ключи,
+// которые ещё принимаются при проверке, пока не истечёт их ExpiresAt.
+type KeySet struct {
+	current *Key
+	keys    map[string]*Key
+	now     func() time.Time
+}
+
+func NewKeySet(current *Key, previous ...*Key) (*KeySet, error) {
+	if current == nil || !current.CanSign() {
+		return nil, ErrNoSigningKey
+	}
+	ks := &KeySet{current: current, keys: map[string]*Key{current.ID: current}, now: time.Now}
+	for _, k := range previous {
+		if _, ok := ks.keys[k.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate key id %q", ErrInvalidKey, k.ID)
+		}
+		ks.keys[k.ID] = k
+	}
+	return ks, nil
+}
+
+// NewSecretKeySet собирает набор HS256-ключей из секретов: первый подписывает
+// новые токены, остальные только принимаются при проверке
+func NewSecretKeySet(current string, previous ...string) (*KeySet, error) {
+	key, err := NewHMACKey("", current)
+	if err != nil {
+		return nil, err
+	}
+	var prev []*Key
+	for _, secret := range previous {
+		k, err := NewHMACKey("", secret)
+		if err != nil {
+			return nil, err
+		}
+		prev = append(prev, k)
+	}
+	return NewKeySet(key, prev...)
+}
+
+// NewEphemeralKeySet создаёт случайный HS256-ключ, живущий до перезапуска процесса
+func NewEphemeralKeySet() (*KeySet, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key, err := NewHMACKey("", hex.EncodeToString(secret))
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet(key)
+}
+
+func (ks *KeySet) Current() *Key {
+	return ks.current
+}
+
+// Lookup возвращает ключ для проверки токена с заголовком kid
+func (ks *KeySet) Lookup(kid string) (*Key, error) {
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !k.ExpiresAt.IsZero() && ks.now().After(k.ExpiresAt) {
+		return nil, ErrUnknownKey
+	}
+	return k, nil
+}
+
+func (ks *KeySet) algorithms() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, k := range ks.keys {
+		if alg := k.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// JWK — открытый ключ в формате RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые части асимметричных ключей, ещё принимаемых при проверке.
+// HS256-ключи никогда не публикуются.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		if _, err := ks.Lookup(k.ID); err != nil {
+			continue
+		}
+		enc := base64.RawURLEncoding
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType: "RSA", KeyID: k.ID, Algorithm: k.Method.Alg(), Use: "sig",
+				N: enc.EncodeToString(pub.N.Bytes()),
+				E: enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType: "OKP", KeyID: k.ID, Algorithm: k.Method.Alg(), Use: "sig",
+				Curve: "Ed25519", X: enc.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// keyFileEntry — описание одного ключа в файле ключей
+type keyFileEntry struct {
+	ID             string    `json:"kid"`
+	Secret         string    `json:"secret"`
+	SecretFile     string    `json:"secret_file"`
+	PrivateKeyFile string    `json:"private_key_file"`
+	PublicKeyFile  string    `json:"public_key_file"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type keyFile struct {
+	Current string         `json:"current"`
+	Keys    []keyFileEntry `json:"keys"`
+}
+
+// LoadKeySetFile читает набор ключей из JSON-файла. Пути к файлам ключей
+// указываются относительно каталога, в котором лежит сам файл.
+//
+//	{
+//	  "current": "2025-07",
+//	  "keys": [
+//	    {"kid": "2025-07", "private_key_file": "jwt-2025-07.pem"},
+//	    {"kid": "2025-01", "secret_file": "jwt-2025-01.secret", "expires_at": "2025-08-01T00:00:00Z"}
+//	  ]
+//	}
+func LoadKeySetFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	dir := filepath.Dir(path)
+	var current *Key
+	var previous []*Key
+	for _, e := range f.Keys {
+		if e.ID == "" {
+			return nil, fmt.Errorf("%w: key without kid", ErrInvalidKey)
+		}
+		k, err := e.load(dir)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", e.ID, err)
+		}
+		k.ExpiresAt = e.ExpiresAt
+		if e.ID == f.Current {
+			current = k
+		} else {
+			previous = append(previous, k)
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("%w: current key %q not found", ErrInvalidKey, f.Current)
+	}
+	if !current.ExpiresAt.IsZero() {
+		return nil, fmt.Errorf("%w: current key %q must not expire", ErrInvalidKey, f.Current)
+	}
+	return NewKeySet(current, previous...)
+}
+
+func (e keyFileEntry) load(dir string) (*Key, error) {
+	switch {
+	case e.Secret != "":
+		return NewHMACKey(e.ID, e.Secret)
+	case e.SecretFile != "":
+		data, err := os.ReadFile(resolve(dir, e.SecretFile))
+		if err != nil {
+			return nil, err
+		}
+		return NewHMACKey(e.ID, string(trimNewline(data)))
+	case e.PrivateKeyFile != "":
+		block, err := readPEM(resolve(dir, e.PrivateKeyFile))
+		if err != nil {
+			return nil, err
+		}
+		private, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		return NewSigningKey(e.ID, private)
+	case e.PublicKeyFile != "":
+		block, err := readPEM(resolve(dir, e.PublicKeyFile))
+		if err != nil {
+			return nil, err
+		}
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return NewVerificationKey(e.ID, public)
+	default:
+		return nil, fmt.Errorf("%w: no key material", ErrInvalidKey)
+	}
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidKey, key)
+	}
+	return signer, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s is not PEM", ErrInvalidKey, path)
+	}
+	return block, nil
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func trimNewline(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == '\n' || b[len(b)-1] == '\r') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// fingerprint даёт HS256-ключу из переменной окружения стабильный kid,
+// не раскрывающий сам секрет
+func fingerprint(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:4])
+}
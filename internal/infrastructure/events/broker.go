@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+)
+
+// DefaultBuffer — сколько событий может ждать медленного подписчика
+const DefaultBuffer = 64
+
+// Broker — шина событий заказов внутри процесса. Событие получают только подписки
+// владельца заказа. Публикация не блокируется: подписка, которая не успевает читать,
+// закрывается, и клиент дочитывает пропущенное из истории при переподключении.
+type Broker struct {
+	buffer int
+
+	mu     sync.Mutex
+	subs   map[int]map[*subscription]struct{}
+	closed bool
+}
+
+func NewBroker(buffer int) *Broker {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Broker{
+		buffer: buffer,
+		subs:   make(map[int]map[*subscription]struct{}),
+	}
+}
+
+// Subscribe подписывает на события заказов пользователя. После Close шины
+// возвращается уже закрытая подписка.
+func (b *Broker) Subscribe(userID int) order.Subscription {
+	s := &subscription{broker: b, userID: userID, ch: make(chan *order.Event, b.buffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(s.ch)
+		s.done = true
+		return s
+	}
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*subscription]struct{})
+	}
+	b.subs[userID][s] = struct{}{}
+	return s
+}
+
+func (b *Broker) Publish(_ context.Context, e *order.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subs[e.UserID] {
+		select {
+		case s.ch <- e:
+		default:
+			log.Printf("[EVENTS] subscriber of user %d is too slow, dropping subscription", e.UserID)
+			b.remove(s)
+		}
+	}
+}
+
+// Close закрывает все подписки, чтобы открытые потоки завершились при остановке сервера
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subs := range b.subs {
+		for s := range subs {
+			b.remove(s)
+		}
+	}
+}
+
+// remove вызывается под b.mu
+func (b *Broker) remove(s *subscription) {
+	if s.done {
+		return
+	}
+	s.done = true
+	close(s.ch)
+
+	delete(b.subs[s.userID], s)
+	if len(b.subs[s.userID]) == 0 {
+		delete(b.subs, s.userID)
+	}
+}
+
+type subscription struct {
+	broker *Broker
+	userID int
+	ch     chan *order.Event
+	done   bool // канал закрыт; защищено broker.mu
+}
+
+func (s *subscription) Events() <-chan *order.Event {
+	return s.ch
+}
+
+func (s *subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
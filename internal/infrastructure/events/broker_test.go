@@ -0,0 +1,54 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("events delivered only to owner", func(t *testing.T) {
+		broker := events.NewBroker(4)
+		alice := broker.Subscribe(1)
+		bob := broker.Subscribe(2)
+		defer alice.Close()
+		defer bob.Close()
+
+		broker.Publish(ctx, &order.Event{ID: 1, UserID: 1, OrderNumber: "12345678903"})
+
+		require.Len(t, alice.Events(), 1)
+		assert.Equal(t, int64(1), (<-alice.Events()).ID)
+		assert.Empty(t, bob.Events())
+	})
+
+	t.Run("slow subscriber dropped without blocking publisher", func(t *testing.T) {
+		broker := events.NewBroker(1)
+		sub := broker.Subscribe(1)
+
+		broker.Publish(ctx, &order.Event{ID: 1, UserID: 1})
+		broker.Publish(ctx, &order.Event{ID: 2, UserID: 1})
+
+		<-sub.Events()
+		_, ok := <-sub.Events()
+		assert.False(t, ok, "overflowed subscription must be closed")
+		sub.Close()
+	})
+
+	t.Run("close ends all subscriptions", func(t *testing.T) {
+		broker := events.NewBroker(1)
+		sub := broker.Subscribe(1)
+
+		broker.Close()
+		_, ok := <-sub.Events()
+		assert.False(t, ok)
+
+		_, ok = <-broker.Subscribe(1).Events()
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/lib/pq"
+)
+
+// NotifyChannel — канал Postgres, через который реплики обмениваются событиями заказов
+const NotifyChannel = "order_events"
+
+type notification struct {
+	Origin string       `json:"origin"`
+	Event  *order.Event `json:"event"`
+}
+
+// PGRelay доставляет события подписчикам своей реплики и через NOTIFY — остальным.
+// Свои уведомления, вернувшиеся через LISTEN, пропускаются по origin.
+type PGRelay struct {
+	db     *sql.DB
+	broker *Broker
+	origin string
+}
+
+func NewPGRelay(db *sql.DB, broker *Broker) (*PGRelay, error) {
+	origin := make([]byte, 8)
+	if _, err := rand.Read(origin); err != nil {
+		return nil, err
+	}
+	return &PGRelay{db: db, broker: broker, origin: hex.EncodeToString(origin)}, nil
+}
+
+func (r *PGRelay) Publish(ctx context.Context, e *order.Event) {
+	r.broker.Publish(ctx, e)
+
+	payload, err := json.Marshal(notification{Origin: r.origin, Event: e})
+	if err != nil {
+		log.Printf("[EVENTS] failed to encode event %d: %v", e.ID, err)
+		return
+	}
+	if _, err := r.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, NotifyChannel, string(payload)); err != nil {
+		log.Printf("[EVENTS] failed to notify replicas about event %d: %v", e.ID, err)
+	}
+}
+
+// Listen получает события других реплик до отмены ctx. Отдельное соединение по dsn
+// переподключается само; события, пришедшие за время разрыва, теряются для открытых
+// потоков и дочитываются клиентами из истории при переподключении.
+func (r *PGRelay) Listen(ctx context.Context, dsn string) error {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("[EVENTS] listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(NotifyChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", NotifyChannel, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// nil приходит после переподключения
+			if n != nil {
+				r.receive(ctx, n.Extra)
+			}
+		}
+	}
+}
+
+func (r *PGRelay) receive(ctx context.Context, payload string) {
+	var msg notification
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil || msg.Event == nil {
+		log.Printf("[EVENTS] malformed notification: %q", payload)
+		return
+	}
+	if msg.Origin == r.origin {
+		return
+	}
+	r.broker.Publish(ctx, msg.Event)
+}
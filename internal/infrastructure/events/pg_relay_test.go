@@ -0,0 +1,38 @@
+package events_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGRelayPublish(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	broker := events.NewBroker(1)
+	relay, err := events.NewPGRelay(db, broker)
+	require.NoError(t, err)
+
+	sub := broker.Subscribe(1)
+	defer sub.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+		WithArgs(events.NotifyChannel, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	relay.Publish(ctx, &order.Event{ID: 5, UserID: 1, OrderNumber: "12345678903", ToStatus: order.StatusProcessing})
+
+	require.Len(t, sub.Events(), 1)
+	assert.Equal(t, int64(5), (<-sub.Events()).ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+)
+
+// LogNotifier пишет уведомления в лог. Только для локальной разработки:
+// токен сброса пароля попадает в лог в открытом виде.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) SendPasswordReset(_ context.Context, u *user.User, resetToken string, expiresAt time.Time) error {
+	log.Printf("[NOTIFY] password reset for %s: token=%s expires_at=%s", u.Login, resetToken, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// FileNotifier дописывает уведомления в файл по одному JSON-объекту в строке
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+type message struct {
+	Kind      string    `json:"kind"`
+	Login     string    `json:"login"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+func (n *FileNotifier) SendPasswordReset(_ context.Context, u *user.User, resetToken string, expiresAt time.Time) error {
+	return n.write(message{
+		Kind:      "password_reset",
+		Login:     u.Login,
+		Token:     resetToken,
+		ExpiresAt: expiresAt,
+		SentAt:    time.Now(),
+	})
+}
+
+func (n *FileNotifier) write(m message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(m)
+}
@@ -0,0 +1,41 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+type bcryptScheme struct {
+	cost int
+}
+
+func (s *bcryptScheme) Matches(encoded string) bool {
+	return hasPrefix(encoded, "$2a$", "$2b$", "$2y$")
+}
+
+func (s *bcryptScheme) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (s *bcryptScheme) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+	return true, nil
+}
+
+func (s *bcryptScheme) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != s.cost
+}
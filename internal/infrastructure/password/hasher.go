@@ -0,0 +1,87 @@
+package password
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+)
+
+var (
+	ErrUnknownScheme = errors.New("unknown password hash scheme")
+	ErrMalformedHash = errors.New("malformed password hash")
+)
+
+// scheme — один алгоритм хеширования. Закодированный хеш начинается с префикса
+// алгоритма и содержит все параметры, поэтому его можно проверить без настроек.
+type scheme interface {
+	Matches(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	// Outdated сообщает, что хеш создан с параметрами, отличными от текущих
+	Outdated(encoded string) bool
+}
+
+// Hasher хеширует пароли выбранным алгоритмом и проверяет хеши любого из
+// поддерживаемых алгоритмов, чтобы пароли можно было переводить на новый постепенно.
+// Одновременно выполняется не больше concurrency вычислений: каждое argon2id-вычисление
+// занимает Memory КиБ, и без ограничения поток входов может исчерпать память.
+type Hasher struct {
+	current scheme
+	schemes []scheme
+	slots   chan struct{}
+}
+
+// NewArgon2id создаёт хешер, выпускающий argon2id-хеши с заданными параметрами.
+// concurrency <= 0 означает число процессоров.
+func NewArgon2id(params Argon2idParams, concurrency int) *Hasher {
+	current := &argon2idScheme{params: params.withDefaults()}
+	return newHasher(current, []scheme{current, &bcryptScheme{cost: DefaultBcryptCost}}, concurrency)
+}
+
+// NewBcrypt создаёт хешер, выпускающий bcrypt-хеши с заданной стоимостью.
+// concurrency <= 0 означает число процессоров.
+func NewBcrypt(cost int, concurrency int) *Hasher {
+	current := &bcryptScheme{cost: cost}
+	return newHasher(current, []scheme{current, &argon2idScheme{params: DefaultArgon2idParams}}, concurrency)
+}
+
+func newHasher(current scheme, schemes []scheme, concurrency int) *Hasher {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	return &Hasher{current: current, schemes: schemes, slots: make(chan struct{}, concurrency)}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	h.slots <- struct{}{}
+	defer func() { <-h.slots }()
+
+	return h.current.Hash(password)
+}
+
+// Verify проверяет пароль. needsRehash означает, что пароль верный, но хеш
+// создан другим алгоритмом или устаревшими параметрами и его стоит пересчитать.
+func (h *Hasher) Verify(encoded, password string) (ok bool, needsRehash bool, err error) {
+	for _, s := range h.schemes {
+		if !s.Matches(encoded) {
+			continue
+		}
+		h.slots <- struct{}{}
+		ok, err := s.Verify(encoded, password)
+		<-h.slots
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, s != h.current || s.Outdated(encoded), nil
+	}
+	return false, false, ErrUnknownScheme
+}
+
+func hasPrefix(encoded string, prefixes ...string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(encoded, p) {
+			return true
+		}
+	}
+	return false
+}
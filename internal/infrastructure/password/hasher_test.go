@@ -0,0 +1,93 @@
+package password_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Небольшие параметры, чтобы тесты работали быстро
+var fastArgon2 = password.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2id_RoundTrip(t *testing.T) {
+	hasher := password.NewArgon2id(fastArgon2, 0)
+
+	encoded, err := hasher.Hash("correct-horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$"), encoded)
+
+	ok, needsRehash, err := hasher.Verify(encoded, "correct-horse")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, _, err = hasher.Verify(encoded, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	other, err := hasher.Hash("correct-horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, other, "salt must be random")
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	bcryptHash, err := password.NewBcrypt(bcrypt.MinCost, 0).Hash("correct-horse")
+	require.NoError(t, err)
+	oldArgon, err := password.NewArgon2id(fastArgon2, 0).Hash("correct-horse")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		hasher  *password.Hasher
+		encoded string
+		rehash  bool
+	}{
+		{"bcrypt hash under argon2id", password.NewArgon2id(fastArgon2, 0), bcryptHash, true},
+		{"argon2id with old params", password.NewArgon2id(password.Argon2idParams{Memory: 128, Iterations: 1, Parallelism: 1}, 0), oldArgon, true},
+		{"argon2id hash under bcrypt", password.NewBcrypt(bcrypt.MinCost, 0), oldArgon, true},
+		{"bcrypt with old cost", password.NewBcrypt(bcrypt.MinCost+1, 0), bcryptHash, true},
+		{"bcrypt with current cost", password.NewBcrypt(bcrypt.MinCost, 0), bcryptHash, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := tt.hasher.Verify(tt.encoded, "correct-horse")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.rehash, needsRehash)
+		})
+	}
+}
+
+func TestHasher_MalformedHash(t *testing.T) {
+	hasher := password.NewArgon2id(fastArgon2, 0)
+
+	_, _, err := hasher.Verify("plaintext", "plaintext")
+	assert.ErrorIs(t, err, password.ErrUnknownScheme)
+
+	_, _, err = hasher.Verify("$argon2id$v=19$m=64,t=1$c2FsdA$aGFzaA", "x")
+	assert.ErrorIs(t, err, password.ErrMalformedHash)
+}
+
+func TestHasher_ConcurrencyLimit(t *testing.T) {
+	hasher := password.NewArgon2id(fastArgon2, 1)
+	encoded, err := hasher.Hash("correct-horse")
+	require.NoError(t, err)
+
+	// Вызовы сверх лимита ждут своей очереди, а не падают
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _, err := hasher.Verify(encoded, "correct-horse")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}()
+	}
+	wg.Wait()
+}
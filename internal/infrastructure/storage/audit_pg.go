@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+)
+
+type AuditPG struct {
+	db *sql.DB
+}
+
+func NewAuditPG(db *sql.DB) *AuditPG {
+	return &AuditPG{db: db}
+}
+
+func (r *AuditPG) Record(ctx context.Context, e *audit.Entry) error {
+	return recordAudit(ctx, r.db, e)
+}
+
+// recordAudit пишет запись журнала аудита. Принимает querier, чтобы запись попадала
+// в транзакцию самого действия и не оставалась в журнале, если действие откатилось.
+func recordAudit(ctx context.Context, q querier, e *audit.Entry) error {
+	details, err := json.Marshal(e.Details)
+	if err != nil {
+		return err
+	}
+	if e.Details == nil {
+		details = []byte("{}")
+	}
+
+	var target sql.NullInt64
+	if e.TargetUserID != 0 {
+		target = sql.NullInt64{Int64: int64(e.TargetUserID), Valid: true}
+	}
+
+	return q.QueryRowContext(ctx, `
+		INSERT INTO admin_audit_log (actor_id, action, target_user_id, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, e.ActorID, string(e.Action), target, details).Scan(&e.ID, &e.CreatedAt)
+}
+
+func (r *AuditPG) List(ctx context.Context, targetUserID int, limit int) ([]*audit.Entry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, actor_id, action, target_user_id, details, created_at
+		FROM admin_audit_log
+		WHERE $1 = 0 OR target_user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, targetUserID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*audit.Entry
+	for rows.Next() {
+		var e audit.Entry
+		var target sql.NullInt64
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &target, &details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.TargetUserID = int(target.Int64)
+		if err := json.Unmarshal(details, &e.Details); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 )
 
 type BalancePG struct {
@@ -17,7 +18,7 @@ func NewBalancePG(db *sql.DB) *BalancePG {
 }
 
 func (r *BalancePG) GetByUserID(ctx context.Context, userID int) (*balance.Balance, error) {
-	var current, withdrawn float64
+	var current, withdrawn money.Amount
 	err := r.db.QueryRowContext(ctx,
 		`SELECT current_balance, total_withdrawn FROM user_balances WHERE user_id = $1`, userID).
 		Scan(&current, &withdrawn)
@@ -35,8 +36,83 @@ func (r *BalancePG) GetByUserID(ctx context.Context, userID int) (*balance.Balan
 	}, nil
 }
 
-func (r *BalancePG) Add(ctx context.Context, userID int, amount float64) error {
-	_, err := r.db.ExecContext(ctx, `
+func (r *BalancePG) ListUserIDs(ctx context.Context) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id FROM user_balances
+		UNION
+		SELECT user_id FROM ledger_transactions
+		ORDER BY user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *BalancePG) Adjust(ctx context.Context, adj *balance.Adjustment) (*balance.Balance, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Строка баланса может ещё не существовать: создаём её, чтобы было что блокировать
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_balances (user_id, current_balance, total_withdrawn)
+		VALUES ($1, 0, 0)
+		ON CONFLICT (user_id) DO NOTHING
+	`, adj.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init balance: %w", err)
+	}
+
+	var current, withdrawn money.Amount
+	err = tx.QueryRowContext(ctx, `
+		SELECT current_balance, total_withdrawn FROM user_balances WHERE user_id = $1 FOR UPDATE
+	`, adj.UserID).Scan(&current, &withdrawn)
+	if err != nil {
+		return nil, err
+	}
+
+	current += adj.Amount
+	if current.IsNegative() && !adj.Force {
+		return nil, balance.ErrNegativeBalance
+	}
+
+	if err := postTransaction(ctx, tx, adj.Transaction()); err != nil {
+		return nil, err
+	}
+	if err := addBalance(ctx, tx, adj.UserID, adj.Amount); err != nil {
+		return nil, err
+	}
+	// Запись аудита в той же транзакции: отклонённая корректировка в журнал не попадёт
+	if err := recordAudit(ctx, tx, adj.AuditEntry()); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &balance.Balance{UserID: adj.UserID, Current: current, Withdrawn: withdrawn}, nil
+}
+
+// addBalance начисляет баллы пользователю. Принимает querier, чтобы начисление
+// можно было выполнить в транзакции другого репозитория.
+func addBalance(ctx context.Context, q querier, userID int, amount money.Amount) error {
+	_, err := q.ExecContext(ctx, `
 		INSERT INTO user_balances (user_id, current_balance, total_withdrawn)
 		VALUES ($1, $2, 0)
 		ON CONFLICT (user_id) DO UPDATE
@@ -0,0 +1,108 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	lockBalanceQuery = regexp.QuoteMeta(`SELECT current_balance, total_withdrawn FROM user_balances`)
+	auditQuery       = regexp.QuoteMeta(`INSERT INTO admin_audit_log`)
+)
+
+func TestAdjust(t *testing.T) {
+	ctx := context.Background()
+	fraud := &balance.Adjustment{UserID: 1, OperatorID: 9, Amount: money.FromFloat(-15), Reason: "fraud"}
+
+	expectLock := func(mock sqlmock.Sqlmock, current money.Amount) {
+		mock.ExpectBegin()
+		mock.ExpectExec(addBalanceQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(lockBalanceQuery).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"current_balance", "total_withdrawn"}).AddRow(current, money.FromFloat(3)))
+	}
+
+	t.Run("debit below zero rejected without force and not audited", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		// Ни проводки, ни записи аудита: любой лишний запрос провалит ожидания
+		expectLock(mock, money.FromFloat(10))
+		mock.ExpectRollback()
+
+		_, err = storage.NewBalancePG(db).Adjust(ctx, fraud)
+		assert.ErrorIs(t, err, balance.ErrNegativeBalance)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("forced debit posts ledger entry and updates balance", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		forced := *fraud
+		forced.Force = true
+
+		expectLock(mock, money.FromFloat(10))
+		mock.ExpectQuery(ledgerTransactionQuery).
+			WithArgs("ADJUSTMENT", 1, nil, "fraud", int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectQuery(ledgerEntryQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(ledgerEntryQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectExec(addBalanceQuery).WithArgs(1, money.FromFloat(-15)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(auditQuery).
+			WithArgs(9, "ADJUST_BALANCE", sqlmock.AnyArg(), []byte(`{"amount":"-15.00","force":"true","reason":"fraud"}`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectCommit()
+
+		bal, err := storage.NewBalancePG(db).Adjust(ctx, &forced)
+		require.NoError(t, err)
+		assert.Equal(t, money.FromFloat(-5), bal.Current)
+		assert.Equal(t, money.FromFloat(3), bal.Withdrawn)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("audit failure rolls back adjustment", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		credit := &balance.Adjustment{UserID: 1, OperatorID: 9, Amount: money.FromFloat(5), Reason: "goodwill"}
+		expectLock(mock, money.FromFloat(10))
+		mock.ExpectQuery(ledgerTransactionQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectQuery(ledgerEntryQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(ledgerEntryQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectExec(addBalanceQuery).WithArgs(1, money.FromFloat(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(auditQuery).WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err = storage.NewBalancePG(db).Adjust(ctx, credit)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListUserIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM user_balances\s+UNION\s+SELECT user_id FROM ledger_transactions`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1).AddRow(3))
+
+	ids, err := storage.NewBalancePG(db).ListUserIDs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
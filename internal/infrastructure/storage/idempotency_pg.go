@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/idempotency"
+)
+
+type IdempotencyPG struct {
+	db    *sql.DB
+	ttl   time.Duration
+	lease time.Duration
+}
+
+// NewIdempotencyPG создаёт хранилище ключей идемпотентности. Ключ старше ttl
+// считается свободным и может быть использован заново, а незавершённый — уже через lease.
+func NewIdempotencyPG(db *sql.DB, ttl, lease time.Duration) *IdempotencyPG {
+	return &IdempotencyPG{db: db, ttl: ttl, lease: lease}
+}
+
+func (r *IdempotencyPG) Reserve(ctx context.Context, reservation *idempotency.Record) (*idempotency.Record, error) {
+	userID, key, fingerprint := reservation.UserID, reservation.Key, reservation.Fingerprint
+
+	// Истёкшая запись и запись, брошенная незавершённой, перезаписываются, живая остаётся как есть
+	var reservedAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, created_at = NOW(),
+			status_code = NULL, content_type = NULL, response_body = NULL, completed_at = NULL
+		WHERE idempotency_keys.created_at < NOW() - make_interval(secs => $4)
+			OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $5))
+		RETURNING created_at
+	`, userID, key, fingerprint, r.ttl.Seconds(), r.lease.Seconds()).Scan(&reservedAt)
+	if err == nil {
+		reservation.CreatedAt = reservedAt
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	rec := idempotency.Record{UserID: userID, Key: key}
+	var status sql.NullInt64
+	var contentType sql.NullString
+	var completedAt sql.NullTime
+	err = r.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, content_type, response_body, created_at, completed_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`, userID, key).Scan(&rec.Fingerprint, &status, &contentType, &rec.Body, &rec.CreatedAt, &completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Запись удалили между запросами: пробуем занять ключ ещё раз
+		return r.Reserve(ctx, reservation)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec.StatusCode = int(status.Int64)
+	rec.ContentType = contentType.String
+	if completedAt.Valid {
+		rec.CompletedAt = &completedAt.Time
+	}
+	return &rec, nil
+}
+
+// Complete и Release находят резерв по created_at: если запрос выполнялся дольше lease
+// и ключ занял повтор, запись уже чужая и не меняется
+func (r *IdempotencyPG) Complete(ctx context.Context, rec *idempotency.Record) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $4, content_type = $5, response_body = $6, completed_at = NOW()
+		WHERE user_id = $1 AND key = $2 AND created_at = $3 AND completed_at IS NULL
+	`, rec.UserID, rec.Key, rec.CreatedAt, rec.StatusCode, rec.ContentType, rec.Body)
+	return reservationResult(res, err)
+}
+
+func (r *IdempotencyPG) Release(ctx context.Context, rec *idempotency.Record) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND created_at = $3 AND completed_at IS NULL
+	`, rec.UserID, rec.Key, rec.CreatedAt)
+	return reservationResult(res, err)
+}
+
+func reservationResult(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return idempotency.ErrReservationLost
+	}
+	return nil
+}
+
+// Purge удаляет записи старше ttl и возвращает их число
+func (r *IdempotencyPG) Purge(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE created_at < NOW() - make_interval(secs => $1)
+	`, r.ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,92 @@
+package storage_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/idempotency"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reservationPredicate = regexp.QuoteMeta(`WHERE user_id = $1 AND key = $2 AND created_at = $3 AND completed_at IS NULL`)
+
+func TestIdempotencyReserve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	reservedAt := time.Date(2025, 7, 11, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`RETURNING created_at`)).
+		WithArgs(7, "k1", "fp", float64(86400), float64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(reservedAt))
+
+	rec := &idempotency.Record{UserID: 7, Key: "k1", Fingerprint: "fp"}
+	existing, err := storage.NewIdempotencyPG(db, 24*time.Hour, time.Minute).Reserve(context.Background(), rec)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+	assert.Equal(t, reservedAt, rec.CreatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyCompleteAndReleaseFencedByReservation(t *testing.T) {
+	ctx := context.Background()
+	rec := &idempotency.Record{
+		UserID:      7,
+		Key:         "k1",
+		StatusCode:  200,
+		ContentType: "application/json",
+		Body:        []byte(`{}`),
+		CreatedAt:   time.Date(2025, 7, 11, 9, 0, 0, 0, time.UTC),
+	}
+
+	cases := []struct {
+		name     string
+		affected int64
+		wantErr  error
+	}{
+		{name: "own reservation", affected: 1},
+		{name: "reservation taken over", affected: 0, wantErr: idempotency.ErrReservationLost},
+	}
+	for _, tc := range cases {
+		t.Run("complete "+tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectExec(`UPDATE idempotency_keys .*`+reservationPredicate).
+				WithArgs(7, "k1", rec.CreatedAt, 200, "application/json", []byte(`{}`)).
+				WillReturnResult(sqlmock.NewResult(0, tc.affected))
+
+			err = storage.NewIdempotencyPG(db, time.Hour, time.Minute).Complete(ctx, rec)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+
+		t.Run("release "+tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectExec(`DELETE FROM idempotency_keys\s+`+reservationPredicate).
+				WithArgs(7, "k1", rec.CreatedAt).
+				WillReturnResult(sqlmock.NewResult(0, tc.affected))
+
+			err = storage.NewIdempotencyPG(db, time.Hour, time.Minute).Release(ctx, rec)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
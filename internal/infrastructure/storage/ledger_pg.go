@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
+	"github.com/lib/pq"
+)
+
+const uniqueViolation = "23505"
+
+type LedgerPG struct {
+	db *sql.DB
+}
+
+func NewLedgerPG(db *sql.DB) *LedgerPG {
+	return &LedgerPG{db: db}
+}
+
+func (r *LedgerPG) GetUserTotals(ctx context.Context, userID int) (*ledger.Totals, error) {
+	var totals ledger.Totals
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(e.amount), 0),
+			COALESCE(-SUM(e.amount) FILTER (WHERE t.kind IN ($2, $3)), 0)
+		FROM ledger_entries e
+		JOIN ledger_transactions t ON t.id = e.transaction_id
+		WHERE e.account = $1
+	`, string(ledger.UserAccount(userID)), string(ledger.KindWithdrawal), string(ledger.KindWithdrawalReversal)).
+		Scan(&totals.Current, &totals.Withdrawn)
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+func (r *LedgerPG) GetUserTransactions(ctx context.Context, userID int) ([]*ledger.Transaction, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.kind, t.user_id, COALESCE(t.reference, ''), t.description,
+			COALESCE(t.operator_id, 0), t.created_at, e.id, e.account, e.amount, e.created_at
+		FROM ledger_transactions t
+		JOIN ledger_entries e ON e.transaction_id = t.id
+		WHERE t.user_id = $1
+		ORDER BY t.created_at, t.id, e.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*ledger.Transaction
+	var current *ledger.Transaction
+	for rows.Next() {
+		var t ledger.Transaction
+		var e ledger.Entry
+		var kind, account string
+		err := rows.Scan(&t.ID, &kind, &t.UserID, &t.Reference, &t.Description, &t.OperatorID, &t.CreatedAt,
+			&e.ID, &account, &e.Amount, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.ID != t.ID {
+			t.Kind = ledger.Kind(kind)
+			current = &t
+			result = append(result, current)
+		}
+		e.TransactionID = current.ID
+		e.Account = ledger.Account(account)
+		current.Entries = append(current.Entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *LedgerPG) GetUserHistory(ctx context.Context, userID int, filter ledger.HistoryFilter) ([]*ledger.HistoryItem, error) {
+	var from, to, afterAt sql.NullTime
+	var afterID int64
+	if !filter.From.IsZero() {
+		from = sql.NullTime{Time: filter.From, Valid: true}
+	}
+	if !filter.To.IsZero() {
+		to = sql.NullTime{Time: filter.To, Valid: true}
+	}
+	if filter.After != nil {
+		afterAt = sql.NullTime{Time: filter.After.At, Valid: true}
+		afterID = filter.After.ID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH history AS (
+			SELECT t.id, t.kind, COALESCE(t.reference, '') AS reference, t.description, t.created_at,
+				SUM(e.amount) AS amount,
+				SUM(SUM(e.amount)) OVER (ORDER BY t.created_at, t.id) AS balance
+			FROM ledger_transactions t
+			JOIN ledger_entries e ON e.transaction_id = t.id
+			WHERE e.account = $1
+			GROUP BY t.id
+		)
+		SELECT id, kind, reference, description, amount, balance, created_at
+		FROM history
+		WHERE ($2::TIMESTAMPTZ IS NULL OR created_at >= $2)
+			AND ($3::TIMESTAMPTZ IS NULL OR created_at < $3)
+			AND ($4 = '' OR ($4 = 'credit' AND amount > 0) OR ($4 = 'debit' AND amount < 0))
+			AND ($5::TIMESTAMPTZ IS NULL OR (created_at, id) > ($5, $6))
+		ORDER BY created_at, id
+		LIMIT $7
+	`, string(ledger.UserAccount(userID)), from, to, string(filter.Direction), afterAt, afterID, pageLimit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*ledger.HistoryItem
+	for rows.Next() {
+		var item ledger.HistoryItem
+		var kind string
+		err := rows.Scan(&item.TransactionID, &kind, &item.Reference, &item.Description,
+			&item.Amount, &item.Balance, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.Kind = ledger.Kind(kind)
+		result = append(result, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// postTransaction записывает операцию и её проводки. Вызывается внутри транзакции
+// того репозитория, который меняет баланс, чтобы журнал и остатки не расходились.
+func postTransaction(ctx context.Context, q querier, t *ledger.Transaction) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	var reference sql.NullString
+	if t.Reference != "" {
+		reference = sql.NullString{String: t.Reference, Valid: true}
+	}
+	var operatorID sql.NullInt64
+	if t.OperatorID != 0 {
+		operatorID = sql.NullInt64{Int64: int64(t.OperatorID), Valid: true}
+	}
+
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO ledger_transactions (kind, user_id, reference, description, operator_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, string(t.Kind), t.UserID, reference, t.Description, operatorID).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ledger.ErrDuplicateTransaction
+		}
+		return fmt.Errorf("failed to post ledger transaction: %w", err)
+	}
+
+	for i := range t.Entries {
+		e := &t.Entries[i]
+		e.TransactionID = t.ID
+		err := q.QueryRowContext(ctx, `
+			INSERT INTO ledger_entries (transaction_id, account, amount, created_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, t.ID, string(e.Account), e.Amount, t.CreatedAt).Scan(&e.ID)
+		if err != nil {
+			return fmt.Errorf("failed to post ledger entry: %w", err)
+		}
+		e.CreatedAt = t.CreatedAt
+	}
+
+	return nil
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/lib/pq"
 )
 
 var ErrNoRows = errors.New("no rows in result set")
@@ -27,6 +32,73 @@ func (r *OrderPG) AddOrder(ctx context.Context, o *order.Order) error {
 	return err
 }
 
+// Добавить пачку заказов. ON CONFLICT DO NOTHING пропускает номера, которые уже есть
+// или параллельно вставляются другим запросом; их владельцы читаются после вставки.
+func (r *OrderPG) AddOrders(ctx context.Context, userID int, numbers []string, uploadedAt time.Time) (*order.BatchInsert, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO orders (number, status, uploaded_at, user_id)
+		SELECT n, $2, $3, $4 FROM unnest($1::TEXT[]) AS n
+		ON CONFLICT (number) DO NOTHING
+		RETURNING number
+	`, pq.Array(numbers), string(order.StatusNew), uploadedAt, userID)
+	if err != nil {
+		return nil, err
+	}
+	inserted := make(map[string]bool, len(numbers))
+	for rows.Next() {
+		var number string
+		if err := rows.Scan(&number); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inserted[number] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var existing []string
+	for _, number := range numbers {
+		if !inserted[number] {
+			existing = append(existing, number)
+		}
+	}
+	owners := make(map[string]int, len(existing))
+	if len(existing) > 0 {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT number, user_id FROM orders WHERE number = ANY($1)
+		`, pq.Array(existing))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var number string
+			var ownerID int
+			if err := rows.Scan(&number, &ownerID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			owners[number] = ownerID
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &order.BatchInsert{Inserted: inserted, Owners: owners}, nil
+}
+
 // Получить список заказов пользователя, сортировка по времени DESC
 func (r *OrderPG) GetOrdersByUser(ctx context.Context, userID int) ([]*order.Order, error) {
 	rows, err := r.DB.QueryContext(ctx, `
@@ -43,18 +115,73 @@ func (r *OrderPG) GetOrdersByUser(ctx context.Context, userID int) ([]*order.Ord
 	var orders []*order.Order
 	for rows.Next() {
 		var o order.Order
-		var accrual sql.NullFloat64
 		var status string
 
-		err := rows.Scan(&o.Number, &status, &accrual, &o.UploadedAt)
+		err := rows.Scan(&o.Number, &status, &o.Accrual, &o.UploadedAt)
 		if err != nil {
 			return nil, err
 		}
 		o.Status = order.Status(status)
-		if accrual.Valid {
-			v := accrual.Float64
-			o.Accrual = &v
+		o.UserID = userID
+		orders = append(orders, &o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Страница заказов пользователя. Сортировка и сравнение с курсором идут по паре
+// (uploaded_at, id), поэтому заказы с одинаковым временем загрузки не теряются между страницами.
+func (r *OrderPG) ListOrdersByUser(ctx context.Context, userID int, filter order.ListFilter) ([]*order.Order, error) {
+	var statuses []string
+	for _, s := range filter.Statuses {
+		statuses = append(statuses, string(s))
+	}
+	var from, to, afterAt sql.NullTime
+	var afterID int64
+	if !filter.From.IsZero() {
+		from = sql.NullTime{Time: filter.From, Valid: true}
+	}
+	if !filter.To.IsZero() {
+		to = sql.NullTime{Time: filter.To, Valid: true}
+	}
+	if filter.After != nil {
+		afterAt = sql.NullTime{Time: filter.After.At, Valid: true}
+		afterID = filter.After.ID
+	}
+
+	// Направление подставляется только из двух фиксированных вариантов
+	cmp, dir := "<", "DESC"
+	if filter.Sort == order.SortAsc {
+		cmp, dir = ">", "ASC"
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, number, status, accrual, uploaded_at
+		FROM orders
+		WHERE user_id = $1
+			AND ($2::TEXT[] IS NULL OR status = ANY($2))
+			AND ($3::TIMESTAMPTZ IS NULL OR uploaded_at >= $3)
+			AND ($4::TIMESTAMPTZ IS NULL OR uploaded_at < $4)
+			AND ($5::TIMESTAMPTZ IS NULL OR (uploaded_at, id) `+cmp+` ($5, $6))
+		ORDER BY uploaded_at `+dir+`, id `+dir+`
+		LIMIT $7
+	`, userID, pq.Array(statuses), from, to, afterAt, afterID, pageLimit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*order.Order
+	for rows.Next() {
+		var o order.Order
+		var status string
+		if err := rows.Scan(&o.ID, &o.Number, &status, &o.Accrual, &o.UploadedAt); err != nil {
+			return nil, err
 		}
+		o.Status = order.Status(status)
 		o.UserID = userID
 		orders = append(orders, &o)
 	}
@@ -80,30 +207,460 @@ func (r *OrderPG) GetOrderOwner(ctx context.Context, number string) (int, error)
 	return userID, nil
 }
 
-func (r *OrderPG) UpdateAccrual(ctx context.Context, orderNumber string, status string, accrual float64) error {
-	_, err := r.DB.ExecContext(ctx, `
-		UPDATE orders
-		SET status = $1, accrual = $2
-		WHERE number = $3
-	`, status, accrual, orderNumber)
-	return err
+// Получить заказ по номеру
+func (r *OrderPG) GetOrder(ctx context.Context, number string) (*order.Order, error) {
+	var o order.Order
+	var status string
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT id, number, user_id, status, accrual, uploaded_at
+		FROM orders
+		WHERE number = $1
+	`, number).Scan(&o.ID, &o.Number, &o.UserID, &status, &o.Accrual, &o.UploadedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, order.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.Status = order.Status(status)
+	return &o, nil
 }
 
-func (r *OrderPG) UpdateStatus(ctx context.Context, orderNumber string, status string) error {
-	_, err := r.DB.ExecContext(ctx, `
+// Получить историю статусов заказа
+func (r *OrderPG) GetOrderEvents(ctx context.Context, number string) ([]*order.Event, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, order_number, from_status, to_status, source, COALESCE(actor_id, 0), reason, created_at
+		FROM order_events
+		WHERE order_number = $1
+		ORDER BY created_at, id
+	`, number)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*order.Event
+	for rows.Next() {
+		var e order.Event
+		var from, to, source string
+		if err := rows.Scan(&e.ID, &e.OrderNumber, &from, &to, &source, &e.ActorID, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.FromStatus = order.Status(from)
+		e.ToStatus = order.Status(to)
+		e.Source = order.EventSource(source)
+		events = append(events, &e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// События по заказам пользователя после afterID. Порядок id совпадает с порядком записи,
+// поэтому клиент продолжает поток с последнего полученного события.
+func (r *OrderPG) ListUserEvents(ctx context.Context, userID int, afterID int64, limit int) ([]*order.Event, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT e.id, e.order_number, e.from_status, e.to_status, e.source, COALESCE(e.actor_id, 0), e.reason, e.created_at, o.accrual
+		FROM order_events e
+		JOIN orders o ON o.number = e.order_number
+		WHERE o.user_id = $1 AND e.id > $2
+		ORDER BY e.id
+		LIMIT $3
+	`, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanUserEvents(rows, userID)
+}
+
+func (r *OrderPG) ListLateUserEvents(ctx context.Context, userID int, lastID int64, lookback time.Duration, limit int) ([]*order.Event, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT e.id, e.order_number, e.from_status, e.to_status, e.source, COALESCE(e.actor_id, 0), e.reason, e.created_at, o.accrual
+		FROM order_events e
+		JOIN orders o ON o.number = e.order_number
+		WHERE o.user_id = $1 AND e.id < $2
+			AND e.created_at >= (SELECT created_at FROM order_events WHERE id = $2) - $3 * INTERVAL '1 millisecond'
+		ORDER BY e.id
+		LIMIT $4
+	`, userID, lastID, lookback.Milliseconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanUserEvents(rows, userID)
+}
+
+func scanUserEvents(rows *sql.Rows, userID int) ([]*order.Event, error) {
+	defer rows.Close()
+
+	var events []*order.Event
+	for rows.Next() {
+		var e order.Event
+		var from, to, source string
+		var accrual *money.Amount
+		if err := rows.Scan(&e.ID, &e.OrderNumber, &from, &to, &source, &e.ActorID, &e.Reason, &e.CreatedAt, &accrual); err != nil {
+			return nil, err
+		}
+		e.FromStatus = order.Status(from)
+		e.ToStatus = order.Status(to)
+		e.Source = order.EventSource(source)
+		e.UserID = userID
+		if e.ToStatus == order.StatusProcessed {
+			e.Accrual = accrual
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Перевести заказ в PROCESSED и начислить баллы в одной транзакции.
+// Начисление проходит только из NEW/PROCESSING и только от имени владельца аренды, поэтому
+// повторный запуск воркера (или экземпляр с истёкшей арендой) не начислит баллы дважды,
+// а INVALID и RETURNED не зачисляются вовсе.
+func (r *OrderPG) CreditAccrual(ctx context.Context, orderNumber string, userID int, owner string, accrual money.Amount) (*order.Event, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Предыдущий статус читается под блокировкой строки, чтобы записать переход в историю
+	var from string
+	err = tx.QueryRowContext(ctx, `
+		WITH prev AS (
+			SELECT id, status FROM orders WHERE number = $3 AND user_id = $4 AND claimed_by = $7 FOR UPDATE
+		)
+		UPDATE orders o
+		SET status = $1, accrual = $2, claimed_by = NULL, lease_until = NULL
+		FROM prev
+		WHERE o.id = prev.id AND prev.status IN ($5, $6)
+		RETURNING prev.status
+	`, string(order.StatusProcessed), accrual, orderNumber, userID, string(order.StatusNew), string(order.StatusProcessing), owner).Scan(&from)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, order.ErrAlreadyProcessed
+	}
+	if err != nil {
+		return nil, err
+	}
+	event, err := recordWorkerTransition(ctx, tx, orderNumber, userID, order.Status(from), order.StatusProcessed)
+	if err != nil {
+		return nil, err
+	}
+	event.Accrual = &accrual
+
+	// Заказ с нулевым начислением просто закрывается, проводка не нужна
+	if accrual != 0 {
+		err = postTransaction(ctx, tx, ledger.NewAccrual(userID, orderNumber, accrual))
+		if errors.Is(err, ledger.ErrDuplicateTransaction) {
+			return nil, order.ErrAlreadyProcessed
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := addBalance(ctx, tx, userID, accrual); err != nil {
+			return nil, err
+		}
+		if err := repayDebts(ctx, tx, userID, orderNumber, accrual); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// repayDebts гасит долги по возвращённым заказам из нового начисления, начиная со старых
+func repayDebts(ctx context.Context, tx *sql.Tx, userID int, orderNumber string, accrual money.Amount) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, outstanding FROM user_debts
+		WHERE user_id = $1 AND outstanding > 0
+		ORDER BY created_at, id
+		FOR UPDATE
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load debts: %w", err)
+	}
+
+	type debt struct {
+		id          int64
+		outstanding money.Amount
+	}
+	var debts []debt
+	for rows.Next() {
+		var d debt
+		if err := rows.Scan(&d.id, &d.outstanding); err != nil {
+			rows.Close()
+			return err
+		}
+		debts = append(debts, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var repaid money.Amount
+	for _, d := range debts {
+		payment := min(d.outstanding, accrual-repaid)
+		if !payment.IsPositive() {
+			break
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE user_debts SET outstanding = outstanding - $1 WHERE id = $2
+		`, payment, d.id)
+		if err != nil {
+			return fmt.Errorf("failed to repay debt: %w", err)
+		}
+		repaid += payment
+	}
+	if repaid.IsZero() {
+		return nil
+	}
+
+	if err := postTransaction(ctx, tx, ledger.NewDebtRepayment(userID, orderNumber, repaid)); err != nil {
+		return err
+	}
+	return addBalance(ctx, tx, userID, -repaid)
+}
+
+// ReturnOrder переводит заказ в RETURNED и списывает начисление. Вернуть можно только
+// PROCESSED: заказ в очереди начислений или INVALID возвращать нечего. Строки заказа и баланса
+// блокируются, поэтому конкурентное начисление или повторный возврат дождутся завершения.
+func (r *OrderPG) ReturnOrder(ctx context.Context, ret *order.Return) (*order.ReturnResult, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var accrual *money.Amount
+	result := &order.ReturnResult{}
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, status, accrual FROM orders WHERE number = $1 FOR UPDATE
+	`, ret.Number).Scan(&result.UserID, &status, &accrual)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, order.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.FromStatus = order.Status(status)
+	if result.FromStatus == order.StatusReturned {
+		return nil, order.ErrAlreadyReturned
+	}
+	if result.FromStatus != order.StatusProcessed {
+		return nil, order.ErrNotReturnable
+	}
+
+	// Заказ мог быть закрыт с нулевым начислением — тогда списывать нечего
+	if accrual != nil && accrual.IsPositive() {
+		if err := r.clawback(ctx, tx, ret, result, *accrual); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
 		UPDATE orders
-		SET status = $1
+		SET status = $1, claimed_by = NULL, lease_until = NULL
 		WHERE number = $2
-	`, status, orderNumber)
-	return err
+	`, string(order.StatusReturned), ret.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Event = &order.Event{
+		OrderNumber: ret.Number,
+		FromStatus:  result.FromStatus,
+		ToStatus:    order.StatusReturned,
+		Source:      ret.Source,
+		ActorID:     ret.ActorID,
+		Reason:      ret.Reason,
+		UserID:      result.UserID,
+	}
+	if err := recordOrderEvent(ctx, tx, result.Event); err != nil {
+		return nil, err
+	}
+	if ret.Source == order.SourceAdmin {
+		if err := recordAudit(ctx, tx, ret.AuditEntry(result)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *OrderPG) clawback(ctx context.Context, tx *sql.Tx, ret *order.Return, result *order.ReturnResult, accrual money.Amount) error {
+	var current money.Amount
+	err := tx.QueryRowContext(ctx, `
+		SELECT current_balance FROM user_balances WHERE user_id = $1 FOR UPDATE
+	`, result.UserID).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	result.Debited, result.Debt = ret.Policy.Split(current, accrual)
+
+	if result.Debited.IsPositive() {
+		if err := postTransaction(ctx, tx, ledger.NewClawback(result.UserID, ret.Number, result.Debited, ret.Reason)); err != nil {
+			return err
+		}
+		if err := addBalance(ctx, tx, result.UserID, -result.Debited); err != nil {
+			return err
+		}
+	}
+
+	if result.Debt.IsPositive() {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO user_debts (user_id, order_number, amount, outstanding)
+			VALUES ($1, $2, $3, $3)
+		`, result.UserID, ret.Number, result.Debt)
+		if err != nil {
+			return fmt.Errorf("failed to record debt: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordOrderEvent записывает изменение статуса заказа в транзакции, которая его меняет
+func recordOrderEvent(ctx context.Context, q querier, e *order.Event) error {
+	var actorID sql.NullInt64
+	if e.ActorID != 0 {
+		actorID = sql.NullInt64{Int64: int64(e.ActorID), Valid: true}
+	}
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO order_events (order_number, from_status, to_status, source, actor_id, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, e.OrderNumber, string(e.FromStatus), string(e.ToStatus), string(e.Source), actorID, e.Reason).
+		Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record order event: %w", err)
+	}
+	return nil
+}
+
+// recordWorkerTransition записывает в историю смену статуса воркером и возвращает событие;
+// повторный опрос без смены статуса в историю не попадает, тогда событие nil
+func recordWorkerTransition(ctx context.Context, q querier, orderNumber string, userID int, from, to order.Status) (*order.Event, error) {
+	if from == to {
+		return nil, nil
+	}
+	e := &order.Event{
+		OrderNumber: orderNumber,
+		FromStatus:  from,
+		ToStatus:    to,
+		Source:      order.SourceWorker,
+		UserID:      userID,
+	}
+	if err := recordOrderEvent(ctx, q, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (r *OrderPG) UpdateStatus(ctx context.Context, orderNumber string, owner string, status string) (*order.Event, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var from string
+	var userID int
+	err = tx.QueryRowContext(ctx, `
+		WITH prev AS (
+			SELECT id, status FROM orders WHERE number = $2 AND claimed_by = $4 FOR UPDATE
+		)
+		UPDATE orders o
+		SET status = $1, claimed_by = NULL, lease_until = NULL
+		FROM prev
+		WHERE o.id = prev.id AND prev.status <> $3
+		RETURNING prev.status, o.user_id
+	`, status, orderNumber, string(order.StatusReturned), owner).Scan(&from, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Заказ уже возвращён или захвачен другим экземпляром
+	}
+	if err != nil {
+		return nil, err
+	}
+	event, err := recordWorkerTransition(ctx, tx, orderNumber, userID, order.Status(from), order.Status(status))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (r *OrderPG) ScheduleRetry(ctx context.Context, orderNumber string, owner string, status string, nextAttemptAt time.Time) (*order.Event, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var from string
+	var userID int
+	err = tx.QueryRowContext(ctx, `
+		WITH prev AS (
+			SELECT id, status FROM orders WHERE number = $3 AND claimed_by = $5 FOR UPDATE
+		)
+		UPDATE orders o
+		SET status = $1, attempts = o.attempts + 1, next_attempt_at = $2, claimed_by = NULL, lease_until = NULL
+		FROM prev
+		WHERE o.id = prev.id AND prev.status <> $4
+		RETURNING prev.status, o.user_id
+	`, status, nextAttemptAt, orderNumber, string(order.StatusReturned), owner).Scan(&from, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Заказ уже возвращён или захвачен другим экземпляром
+	}
+	if err != nil {
+		return nil, err
+	}
+	event, err := recordWorkerTransition(ctx, tx, orderNumber, userID, order.Status(from), order.Status(status))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return event, nil
 }
 
-func (r *OrderPG) GetOrdersForProcessing(ctx context.Context) ([]*order.Order, error) {
+// Захватить заказы, которые пора опросить, в порядке наступления времени опроса.
+// Строки, которые в этот момент захватывает другой экземпляр, пропускаются (SKIP LOCKED),
+// а захваченные получают аренду до lease_until и не выдаются другим экземплярам, пока она действует.
+// Если экземпляр упал, заказ вернётся в очередь после истечения аренды.
+func (r *OrderPG) ClaimOrdersForProcessing(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error) {
 	rows, err := r.DB.QueryContext(ctx, `
-		SELECT number, user_id
-		FROM orders
-		WHERE status IN ('NEW', 'PROCESSING')
-	`)
+		UPDATE orders
+		SET claimed_by = $1, lease_until = NOW() + $3 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id
+			FROM orders
+			WHERE status IN ('NEW', 'PROCESSING')
+				AND next_attempt_at <= NOW()
+				AND (lease_until IS NULL OR lease_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING number, user_id, status, attempts, next_attempt_at, claimed_by
+	`, owner, limit, lease.Milliseconds())
 	if err != nil {
 		return nil, err
 	}
@@ -112,9 +669,11 @@ func (r *OrderPG) GetOrdersForProcessing(ctx context.Context) ([]*order.Order, e
 	var orders []*order.Order
 	for rows.Next() {
 		var o order.Order
-		if err := rows.Scan(&o.Number, &o.UserID); err != nil {
+		var status string
+		if err := rows.Scan(&o.Number, &o.UserID, &status, &o.Attempts, &o.NextAttemptAt, &o.ClaimedBy); err != nil {
 			return nil, err
 		}
+		o.Status = order.Status(status)
 		orders = append(orders, &o)
 	}
 
@@ -123,3 +682,35 @@ func (r *OrderPG) GetOrdersForProcessing(ctx context.Context) ([]*order.Order, e
 	}
 	return orders, nil
 }
+
+// Продлить аренду перед опросом: заказ мог дождаться воркера, когда аренда уже истекла.
+// Пока строка захвачена другим экземпляром в ClaimOrdersForProcessing, UPDATE ждёт и затем
+// перепроверяет claimed_by, поэтому продлить перехваченную аренду нельзя.
+func (r *OrderPG) ExtendLease(ctx context.Context, orderNumber string, owner string, lease time.Duration) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE orders
+		SET lease_until = NOW() + $3 * INTERVAL '1 millisecond'
+		WHERE number = $1 AND claimed_by = $2 AND status IN ('NEW', 'PROCESSING')
+	`, orderNumber, owner, lease.Milliseconds())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return order.ErrLeaseLost
+	}
+	return nil
+}
+
+// Снять аренду с заказа, не меняя его состояние (например, при паузе после 429)
+func (r *OrderPG) ReleaseOrder(ctx context.Context, orderNumber string, owner string) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE orders
+		SET claimed_by = NULL, lease_until = NULL
+		WHERE number = $1 AND claimed_by = $2
+	`, orderNumber, owner)
+	return err
+}
@@ -0,0 +1,659 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	updateProcessedQuery   = regexp.QuoteMeta(`UPDATE orders`)
+	addBalanceQuery        = regexp.QuoteMeta(`INSERT INTO user_balances`)
+	ledgerTransactionQuery = regexp.QuoteMeta(`INSERT INTO ledger_transactions`)
+	ledgerEntryQuery       = regexp.QuoteMeta(`INSERT INTO ledger_entries`)
+	outstandingDebtsQuery  = regexp.QuoteMeta(`SELECT id, outstanding FROM user_debts`)
+	orderEventQuery        = regexp.QuoteMeta(`INSERT INTO order_events`)
+)
+
+// expectCreditStatus ожидает перевод заказа из PROCESSING в PROCESSED с записью в историю
+func expectCreditStatus(mock sqlmock.Sqlmock, accrual money.Amount) {
+	mock.ExpectQuery(updateProcessedQuery).
+		WithArgs("PROCESSED", accrual, "12345678903", 1, "NEW", "PROCESSING", "instance-a").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("PROCESSING"))
+	mock.ExpectQuery(orderEventQuery).
+		WithArgs("12345678903", "PROCESSING", "PROCESSED", "worker", nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+}
+
+func expectLedgerPost(mock sqlmock.Sqlmock, kind string, userID int, reference string, amount money.Amount) {
+	now := time.Now()
+	mock.ExpectQuery(ledgerTransactionQuery).
+		WithArgs(kind, userID, reference, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+	mock.ExpectQuery(ledgerEntryQuery).
+		WithArgs(1, sqlmock.AnyArg(), -amount, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(ledgerEntryQuery).
+		WithArgs(1, sqlmock.AnyArg(), amount, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+}
+
+func TestCreditAccrual(t *testing.T) {
+	ctx := context.Background()
+	accrual := money.FromFloat(42.5)
+
+	t.Run("order and balance updated in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectCreditStatus(mock, accrual)
+		expectLedgerPost(mock, "ACCRUAL", 1, "12345678903", accrual)
+		mock.ExpectExec(addBalanceQuery).
+			WithArgs(1, accrual).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(outstandingDebtsQuery).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "outstanding"}))
+		mock.ExpectCommit()
+
+		event, err := storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		require.NoError(t, err)
+		assert.Equal(t, order.StatusProcessed, event.ToStatus)
+		assert.Equal(t, 1, event.UserID)
+		assert.Equal(t, &accrual, event.Accrual)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("outstanding debt repaid from new accrual", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectCreditStatus(mock, accrual)
+		expectLedgerPost(mock, "ACCRUAL", 1, "12345678903", accrual)
+		mock.ExpectExec(addBalanceQuery).
+			WithArgs(1, accrual).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(outstandingDebtsQuery).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "outstanding"}).
+				AddRow(3, money.FromFloat(30)).
+				AddRow(4, money.FromFloat(20)))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE user_debts`)).
+			WithArgs(money.FromFloat(30), 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE user_debts`)).
+			WithArgs(money.FromFloat(12.5), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectLedgerPost(mock, "DEBT_REPAYMENT", 1, "12345678903", accrual)
+		mock.ExpectExec(addBalanceQuery).
+			WithArgs(1, -accrual).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err = storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("balance failure rolls back order status", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectCreditStatus(mock, accrual)
+		expectLedgerPost(mock, "ACCRUAL", 1, "12345678903", accrual)
+		mock.ExpectExec(addBalanceQuery).
+			WithArgs(1, accrual).
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ledger failure rolls back order status", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectCreditStatus(mock, accrual)
+		mock.ExpectQuery(ledgerTransactionQuery).
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("order status failure does not touch balance", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(updateProcessedQuery).
+			WithArgs("PROCESSED", accrual, "12345678903", 1, "NEW", "PROCESSING", "instance-a").
+			WillReturnError(errors.New("deadlock detected"))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("re-run on processed order does not credit twice", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(updateProcessedQuery).
+			WithArgs("PROCESSED", accrual, "12345678903", 1, "NEW", "PROCESSING", "instance-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status"}))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		assert.ErrorIs(t, err, order.ErrAlreadyProcessed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("only new or processing order is credited", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`prev.status IN ($5, $6)`)).
+			WithArgs("PROCESSED", accrual, "12345678903", 1, "NEW", "PROCESSING", "instance-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status"}))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		assert.ErrorIs(t, err, order.ErrAlreadyProcessed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("order claimed by another instance is not credited", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`WHERE number = $3 AND user_id = $4 AND claimed_by = $7`)).
+			WithArgs("PROCESSED", accrual, "12345678903", 1, "NEW", "PROCESSING", "instance-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status"}))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).CreditAccrual(ctx, "12345678903", 1, "instance-a", accrual)
+		assert.ErrorIs(t, err, order.ErrAlreadyProcessed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReturnOrder(t *testing.T) {
+	ctx := context.Background()
+	accrual := money.FromFloat(100)
+	lockOrderQuery := regexp.QuoteMeta(`SELECT user_id, status, accrual FROM orders`)
+	lockBalanceQuery := regexp.QuoteMeta(`SELECT current_balance FROM user_balances`)
+
+	t.Run("spent accrual recorded as debt", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockOrderQuery).WithArgs("12345678903").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "accrual"}).AddRow(1, "PROCESSED", accrual))
+		mock.ExpectQuery(lockBalanceQuery).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"current_balance"}).AddRow(money.FromFloat(40)))
+		expectLedgerPost(mock, "CLAWBACK", 1, "12345678903", money.FromFloat(40))
+		mock.ExpectExec(addBalanceQuery).WithArgs(1, money.FromFloat(-40)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_debts`)).
+			WithArgs(1, "12345678903", money.FromFloat(60)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateProcessedQuery).WithArgs("RETURNED", "12345678903").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(orderEventQuery).
+			WithArgs("12345678903", "PROCESSED", "RETURNED", "accrual", nil, "fraud").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectCommit()
+
+		result, err := storage.NewOrderPG(db).ReturnOrder(ctx, &order.Return{
+			Number: "12345678903",
+			Reason: "fraud",
+			Source: order.SourceAccrual,
+			Policy: order.ClawbackDebt,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, money.FromFloat(40), result.Debited)
+		assert.Equal(t, money.FromFloat(60), result.Debt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("audit failure rolls back return by admin", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockOrderQuery).WithArgs("12345678903").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "accrual"}).AddRow(1, "PROCESSED", nil))
+		mock.ExpectExec(updateProcessedQuery).WithArgs("RETURNED", "12345678903").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(orderEventQuery).
+			WithArgs("12345678903", "PROCESSED", "RETURNED", "admin", 9, "cancelled by store").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectQuery(auditQuery).
+			WithArgs(9, "RETURN_ORDER", sqlmock.AnyArg(), []byte(`{"debited":"0.00","debt":"0.00","from_status":"PROCESSED","order":"12345678903","reason":"cancelled by store"}`)).
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).ReturnOrder(ctx, &order.Return{
+			Number:  "12345678903",
+			Reason:  "cancelled by store",
+			Source:  order.SourceAdmin,
+			ActorID: 9,
+			Policy:  order.ClawbackDebt,
+		})
+		assert.Error(t, err, "return must roll back when its audit entry is not written")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("second return rejected", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockOrderQuery).WithArgs("12345678903").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "accrual"}).AddRow(1, "RETURNED", accrual))
+		mock.ExpectRollback()
+
+		_, err = storage.NewOrderPG(db).ReturnOrder(ctx, &order.Return{Number: "12345678903", Policy: order.ClawbackDebt})
+		assert.ErrorIs(t, err, order.ErrAlreadyReturned)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	for _, status := range []string{"NEW", "PROCESSING", "INVALID"} {
+		t.Run(status+" order is not returnable", func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(lockOrderQuery).WithArgs("12345678903").
+				WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "accrual"}).AddRow(1, status, nil))
+			mock.ExpectRollback()
+
+			_, err = storage.NewOrderPG(db).ReturnOrder(ctx, &order.Return{Number: "12345678903", Policy: order.ClawbackDebt})
+			assert.ErrorIs(t, err, order.ErrNotReturnable)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAddOrders(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`ON CONFLICT (number) DO NOTHING`)).
+		WithArgs(sqlmock.AnyArg(), "NEW", now, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"number"}).AddRow("12345678903"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT number, user_id FROM orders WHERE number = ANY($1)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"number", "user_id"}).AddRow("79927398713", 2))
+	mock.ExpectCommit()
+
+	result, err := storage.NewOrderPG(db).AddOrders(ctx, 1, []string{"12345678903", "79927398713"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"12345678903": true}, result.Inserted)
+	assert.Equal(t, map[string]int{"79927398713": 2}, result.Owners)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListOrdersByUser(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("ascending page after cursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		cursor := &order.Cursor{At: now, ID: 5}
+		mock.ExpectQuery(regexp.QuoteMeta(`(uploaded_at, id) > ($5, $6)) ORDER BY uploaded_at ASC, id ASC`)).
+			WithArgs(1, sqlmock.AnyArg(), nil, nil, now, int64(5), 11).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "number", "status", "accrual", "uploaded_at"}).
+				AddRow(6, "12345678903", "PROCESSED", money.FromFloat(10), now.Add(time.Second)))
+
+		orders, err := storage.NewOrderPG(db).ListOrdersByUser(ctx, 1, order.ListFilter{
+			Params:   pagination.Params{After: cursor, Limit: 11},
+			Statuses: []order.Status{order.StatusProcessed},
+			Sort:     order.SortAsc,
+		})
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, int64(6), orders[0].ID)
+		assert.Equal(t, order.StatusProcessed, orders[0].Status)
+		assert.Equal(t, 1, orders[0].UserID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("descending by default", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY uploaded_at DESC, id DESC`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "number", "status", "accrual", "uploaded_at"}))
+
+		orders, err := storage.NewOrderPG(db).ListOrdersByUser(ctx, 1, order.ListFilter{Params: pagination.Params{Limit: 10}, Sort: order.SortDesc})
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("zero limit lists all orders", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta(`LIMIT $7`)).
+			WithArgs(1, sqlmock.AnyArg(), nil, nil, nil, int64(0), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "number", "status", "accrual", "uploaded_at"}))
+
+		_, err = storage.NewOrderPG(db).ListOrdersByUser(ctx, 1, order.ListFilter{Sort: order.SortDesc})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestScheduleRetry(t *testing.T) {
+	ctx := context.Background()
+	next := time.Now().Add(time.Minute)
+
+	t.Run("status change recorded in history", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(updateProcessedQuery).
+			WithArgs("PROCESSING", next, "12345678903", "RETURNED", "instance-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status", "user_id"}).AddRow("NEW", 1))
+		mock.ExpectQuery(orderEventQuery).
+			WithArgs("12345678903", "NEW", "PROCESSING", "worker", nil, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectCommit()
+
+		event, err := storage.NewOrderPG(db).ScheduleRetry(ctx, "12345678903", "instance-a", "PROCESSING", next)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), event.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("repeated poll without status change is not recorded", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(updateProcessedQuery).
+			WithArgs("PROCESSING", next, "12345678903", "RETURNED", "instance-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status", "user_id"}).AddRow("PROCESSING", 1))
+		mock.ExpectCommit()
+
+		event, err := storage.NewOrderPG(db).ScheduleRetry(ctx, "12345678903", "instance-a", "PROCESSING", next)
+		require.NoError(t, err)
+		assert.Nil(t, event)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("order claimed by another instance is not updated", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`WHERE number = $3 AND claimed_by = $5`)).
+			WithArgs("PROCESSING", next, "12345678903", "RETURNED", "instance-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status", "user_id"}))
+		mock.ExpectRollback()
+
+		event, err := storage.NewOrderPG(db).ScheduleRetry(ctx, "12345678903", "instance-a", "PROCESSING", next)
+		require.NoError(t, err)
+		assert.Nil(t, event)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetOrderEvents(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM order_events`)).
+		WithArgs("12345678903").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_number", "from_status", "to_status", "source", "actor_id", "reason", "created_at"}).
+			AddRow(1, "12345678903", "NEW", "PROCESSING", "worker", 0, "", now).
+			AddRow(2, "12345678903", "PROCESSING", "PROCESSED", "worker", 0, "", now.Add(time.Second)))
+
+	events, err := storage.NewOrderPG(db).GetOrderEvents(ctx, "12345678903")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, order.StatusProcessed, events[1].ToStatus)
+	assert.Equal(t, order.SourceWorker, events[1].Source)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListUserEvents(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	accrual := money.FromFloat(42.5)
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN orders o ON o.number = e.order_number`)).
+		WithArgs(1, int64(10), order.MaxReplayEvents).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_number", "from_status", "to_status", "source", "actor_id", "reason", "created_at", "accrual"}).
+			AddRow(11, "12345678903", "NEW", "PROCESSING", "worker", 0, "", now, accrual).
+			AddRow(12, "12345678903", "PROCESSING", "PROCESSED", "worker", 0, "", now, accrual))
+
+	events, err := storage.NewOrderPG(db).ListUserEvents(ctx, 1, 10, order.MaxReplayEvents)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Nil(t, events[0].Accrual)
+	assert.Equal(t, &accrual, events[1].Accrual)
+	assert.Equal(t, 1, events[1].UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListLateUserEvents(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`e.id < $2`)).
+		WithArgs(1, int64(10), order.ReplayLookback.Milliseconds(), order.MaxReplayEvents).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_number", "from_status", "to_status", "source", "actor_id", "reason", "created_at", "accrual"}).
+			AddRow(9, "12345678903", "NEW", "PROCESSING", "worker", 0, "", time.Now(), nil))
+
+	events, err := storage.NewOrderPG(db).ListLateUserEvents(ctx, 1, 10, order.ReplayLookback, order.MaxReplayEvents)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(9), events[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// sqlInOrder собирает шаблон запроса из фрагментов SQL, идущих в нём друг за другом
+func sqlInOrder(fragments ...string) string {
+	quoted := make([]string, len(fragments))
+	for i, fragment := range fragments {
+		quoted[i] = regexp.QuoteMeta(fragment)
+	}
+	return "(?s)" + strings.Join(quoted, ".*")
+}
+
+// Экземпляры делят очередь только через условия запросов, поэтому они и проверяются:
+// захват пропускает строки, занятые другим экземпляром, и строки с действующей арендой,
+// но забирает заказы с истёкшей арендой упавшего экземпляра
+func TestClaimOrdersForProcessing(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	next := time.Now()
+	mock.ExpectQuery(sqlInOrder(
+		`SET claimed_by = $1, lease_until = NOW() + $3 * INTERVAL '1 millisecond'`,
+		`WHERE status IN ('NEW', 'PROCESSING')`,
+		`AND next_attempt_at <= NOW()`,
+		`AND (lease_until IS NULL OR lease_until < NOW())`,
+		`LIMIT $2`,
+		`FOR UPDATE SKIP LOCKED`,
+		`RETURNING number, user_id, status, attempts, next_attempt_at, claimed_by`,
+	)).
+		WithArgs("replica-a", 10, int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"number", "user_id", "status", "attempts", "next_attempt_at", "claimed_by"}).
+			AddRow("12345678903", 1, "NEW", 0, next, "replica-a").
+			AddRow("79927398713", 2, "PROCESSING", 3, next, "replica-a"))
+
+	orders, err := storage.NewOrderPG(db).ClaimOrdersForProcessing(ctx, "replica-a", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, order.StatusProcessing, orders[1].Status)
+	assert.Equal(t, 3, orders[1].Attempts)
+	assert.Equal(t, "replica-a", orders[0].ClaimedBy)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Все записи воркера проходят только от имени владельца аренды и снимают её
+func TestWorkerWritesFencedByLeaseOwner(t *testing.T) {
+	ctx := context.Background()
+	next := time.Now().Add(time.Minute)
+	accrual := money.FromFloat(10)
+
+	for name, tc := range map[string]struct {
+		fragments []string
+		args      []driver.Value
+		call      func(r *storage.OrderPG) error
+		wantErr   error
+	}{
+		"credit accrual": {
+			fragments: []string{`WHERE number = $3 AND user_id = $4 AND claimed_by = $7 FOR UPDATE`, `claimed_by = NULL, lease_until = NULL`},
+			args:      []driver.Value{"PROCESSED", accrual, "12345678903", 1, "NEW", "PROCESSING", "replica-a"},
+			call: func(r *storage.OrderPG) error {
+				_, err := r.CreditAccrual(ctx, "12345678903", 1, "replica-a", accrual)
+				return err
+			},
+			wantErr: order.ErrAlreadyProcessed,
+		},
+		"update status": {
+			fragments: []string{`WHERE number = $2 AND claimed_by = $4 FOR UPDATE`, `claimed_by = NULL, lease_until = NULL`},
+			args:      []driver.Value{"INVALID", "12345678903", "RETURNED", "replica-a"},
+			call: func(r *storage.OrderPG) error {
+				_, err := r.UpdateStatus(ctx, "12345678903", "replica-a", "INVALID")
+				return err
+			},
+		},
+		"schedule retry": {
+			fragments: []string{`WHERE number = $3 AND claimed_by = $5 FOR UPDATE`, `claimed_by = NULL, lease_until = NULL`},
+			args:      []driver.Value{"PROCESSING", next, "12345678903", "RETURNED", "replica-a"},
+			call: func(r *storage.OrderPG) error {
+				_, err := r.ScheduleRetry(ctx, "12345678903", "replica-a", "PROCESSING", next)
+				return err
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			// Заказ захвачен другим экземпляром: строка не выбирается, ничего не пишется
+			mock.ExpectBegin()
+			mock.ExpectQuery(sqlInOrder(tc.fragments...)).
+				WithArgs(tc.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"status", "user_id"}))
+			mock.ExpectRollback()
+
+			assert.ErrorIs(t, tc.call(storage.NewOrderPG(db)), tc.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("release order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(sqlInOrder(`SET claimed_by = NULL, lease_until = NULL`, `WHERE number = $1 AND claimed_by = $2`)).
+			WithArgs("12345678903", "replica-a").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, storage.NewOrderPG(db).ReleaseOrder(ctx, "12345678903", "replica-a"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestExtendLease(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	query := regexp.QuoteMeta(`WHERE number = $1 AND claimed_by = $2 AND status IN ('NEW', 'PROCESSING')`)
+	repo := storage.NewOrderPG(db)
+
+	t.Run("lease extended by owner", func(t *testing.T) {
+		mock.ExpectExec(query).
+			WithArgs("12345678903", "replica-a", int64(60000)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.ExtendLease(ctx, "12345678903", "replica-a", time.Minute))
+	})
+
+	t.Run("order claimed by another instance", func(t *testing.T) {
+		mock.ExpectExec(query).
+			WithArgs("12345678903", "replica-a", int64(60000)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.ExtendLease(ctx, "12345678903", "replica-a", time.Minute), order.ErrLeaseLost)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
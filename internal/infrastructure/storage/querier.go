@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+)
+
+// querier — общий интерфейс *sql.DB и *sql.Tx, чтобы одни и те же запросы
+// можно было выполнять как отдельно, так и внутри транзакции.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// pageLimit превращает лимит страницы в аргумент LIMIT: 0 означает весь список,
+// а LIMIT NULL в PostgreSQL ничего не ограничивает
+func pageLimit(limit int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(limit), Valid: limit > 0}
+}
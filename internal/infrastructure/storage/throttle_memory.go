@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+)
+
+// ThrottleMemory хранит счётчики попыток в памяти процесса. Подходит для одного
+// экземпляра сервиса; при нескольких репликах лимиты будут считаться отдельно.
+type ThrottleMemory struct {
+	mu       sync.Mutex
+	attempts map[string]throttle.Attempts
+	lockouts []throttle.LockoutEvent
+}
+
+func NewThrottleMemory() *ThrottleMemory {
+	return &ThrottleMemory{attempts: make(map[string]throttle.Attempts)}
+}
+
+func (m *ThrottleMemory) Get(_ context.Context, key string) (*throttle.Attempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+func (m *ThrottleMemory) Reserve(_ context.Context, key string, policy throttle.Policy, now time.Time) (*throttle.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.attempts[key]
+	if !ok {
+		a = throttle.Attempts{Key: key, WindowStart: now, LastFailureAt: now}
+	}
+	r := policy.Reserve(a, now)
+	if r.RetryAfter == 0 {
+		m.attempts[key] = r.Attempts
+	}
+	return &r, nil
+}
+
+func (m *ThrottleMemory) Refund(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a, ok := m.attempts[key]; ok && a.Failures > 0 {
+		a.Failures--
+		m.attempts[key] = a
+	}
+	return nil
+}
+
+func (m *ThrottleMemory) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+func (m *ThrottleMemory) RecordLockout(_ context.Context, e throttle.LockoutEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lockouts = append(m.lockouts, e)
+	return nil
+}
+
+func (m *ThrottleMemory) Purge(_ context.Context, windowBefore, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for key, a := range m.attempts {
+		if a.WindowStart.Before(windowBefore) && !now.Before(a.LockedUntil) {
+			delete(m.attempts, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Lockouts возвращает записанные блокировки
+func (m *ThrottleMemory) Lockouts() []throttle.LockoutEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]throttle.LockoutEvent(nil), m.lockouts...)
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+)
+
+type ThrottlePG struct {
+	db *sql.DB
+}
+
+func NewThrottlePG(db *sql.DB) *ThrottlePG {
+	return &ThrottlePG{db: db}
+}
+
+func (r *ThrottlePG) Get(ctx context.Context, key string) (*throttle.Attempts, error) {
+	a := throttle.Attempts{Key: key}
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT failures, window_start, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = $1
+	`, key).Scan(&a.Failures, &a.WindowStart, &a.LastFailureAt, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.LockedUntil = lockedUntil.Time
+	return &a, nil
+}
+
+func (r *ThrottlePG) Reserve(ctx context.Context, key string, policy throttle.Policy, now time.Time) (*throttle.Reservation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Строка создаётся заранее, чтобы параллельные попытки по новому ключу тоже
+	// ждали друг друга на её блокировке
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO login_attempts (key, failures, window_start, last_failure_at)
+		VALUES ($1, 0, $2, $2)
+		ON CONFLICT (key) DO NOTHING
+	`, key, now)
+	if err != nil {
+		return nil, err
+	}
+
+	a := throttle.Attempts{Key: key}
+	var lockedUntil sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT failures, window_start, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = $1
+		FOR UPDATE
+	`, key).Scan(&a.Failures, &a.WindowStart, &a.LastFailureAt, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	a.LockedUntil = lockedUntil.Time
+
+	res := policy.Reserve(a, now)
+	if res.RetryAfter > 0 {
+		return &res, tx.Commit()
+	}
+
+	var until sql.NullTime
+	if !res.Attempts.LockedUntil.IsZero() {
+		until = sql.NullTime{Time: res.Attempts.LockedUntil, Valid: true}
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE login_attempts
+		SET failures = $2, window_start = $3, last_failure_at = $4, locked_until = $5
+		WHERE key = $1
+	`, key, res.Attempts.Failures, res.Attempts.WindowStart, res.Attempts.LastFailureAt, until)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *ThrottlePG) Refund(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE login_attempts SET failures = GREATEST(failures - 1, 0) WHERE key = $1
+	`, key)
+	return err
+}
+
+func (r *ThrottlePG) Reset(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM login_attempts WHERE key = $1
+	`, key)
+	return err
+}
+
+func (r *ThrottlePG) RecordLockout(ctx context.Context, e throttle.LockoutEvent) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO login_lockouts (key, failures, locked_until, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, e.Key, e.Failures, e.LockedUntil, e.CreatedAt)
+	return err
+}
+
+func (r *ThrottlePG) Purge(ctx context.Context, windowBefore, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM login_attempts
+		WHERE window_start < $1 AND (locked_until IS NULL OR locked_until <= $2)
+	`, windowBefore, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
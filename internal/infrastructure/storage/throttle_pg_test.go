@@ -0,0 +1,65 @@
+package storage_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ensureAttemptsQuery = regexp.QuoteMeta(`ON CONFLICT (key) DO NOTHING`)
+	lockAttemptsQuery   = regexp.QuoteMeta(`FOR UPDATE`)
+	updateAttemptsQuery = regexp.QuoteMeta(`UPDATE login_attempts`)
+	attemptsRowColumns  = []string{"failures", "window_start", "last_failure_at", "locked_until"}
+	reservePolicy       = throttle.Policy{Window: time.Hour, FreeAttempts: 1, BaseDelay: time.Minute}
+)
+
+func TestThrottleReserve(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("counts attempt under row lock", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(ensureAttemptsQuery).WithArgs("login:alice", now).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(lockAttemptsQuery).WithArgs("login:alice").
+			WillReturnRows(sqlmock.NewRows(attemptsRowColumns).AddRow(0, now, now, nil))
+		mock.ExpectExec(updateAttemptsQuery).
+			WithArgs("login:alice", 1, now, now, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		res, err := storage.NewThrottlePG(db).Reserve(ctx, "login:alice", reservePolicy, now)
+		require.NoError(t, err)
+		assert.Zero(t, res.RetryAfter)
+		assert.Equal(t, 1, res.Attempts.Failures)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejected attempt leaves counter unchanged", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(ensureAttemptsQuery).WithArgs("login:alice", now).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(lockAttemptsQuery).WithArgs("login:alice").
+			WillReturnRows(sqlmock.NewRows(attemptsRowColumns).AddRow(2, now, now, nil))
+		mock.ExpectCommit()
+
+		res, err := storage.NewThrottlePG(db).Reserve(ctx, "login:alice", reservePolicy, now)
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, res.RetryAfter)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
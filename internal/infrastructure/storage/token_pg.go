@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/token"
+)
+
+type TokenPG struct {
+	db *sql.DB
+}
+
+func NewTokenPG(db *sql.DB) *TokenPG {
+	return &TokenPG{db: db}
+}
+
+func (r *TokenPG) Create(ctx context.Context, t *token.RefreshToken) error {
+	return insertRefreshToken(ctx, r.db, t)
+}
+
+func (r *TokenPG) GetByHash(ctx context.Context, hash string) (*token.RefreshToken, error) {
+	var t token.RefreshToken
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, family_id, token_hash, expires_at, created_at, rotated_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`, hash).Scan(&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt, &t.RotatedAt, &t.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, token.ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TokenPG) Rotate(ctx context.Context, oldID int64, next *token.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Условие по rotated_at/revoked_at защищает от гонки двух одновременных обменов
+	// одного и того же токена: выиграет только один
+	res, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET rotated_at = NOW()
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`, oldID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return token.ErrTokenReused
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TokenPG) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	return err
+}
+
+func (r *TokenPG) RevokeUser(ctx context.Context, userID int) error {
+	return revokeUserTokens(ctx, r.db, userID)
+}
+
+// revokeUserTokens отзывает все сессии пользователя; q — соединение или транзакция,
+// в которой меняется пароль
+func revokeUserTokens(ctx context.Context, q querier, userID int) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
+
+func (r *TokenPG) IsFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM refresh_tokens
+			WHERE family_id = $1 AND revoked_at IS NULL
+		)
+	`, familyID).Scan(&active)
+	return active, err
+}
+
+func insertRefreshToken(ctx context.Context, q querier, t *token.RefreshToken) error {
+	return q.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
 	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/lib/pq"
 )
 
 type UserPG struct {
@@ -20,9 +24,13 @@ func (r *UserPG) CreateUser(ctx context.Context, u *user.User) (*user.User, erro
 	err := r.DB.QueryRowContext(ctx, `
 		INSERT INTO users (login, password)
 		VALUES ($1, $2)
-		RETURNING id
-	`, u.Login, u.Password).Scan(&u.ID)
+		RETURNING id, role, created_at
+	`, u.Login, u.Password).Scan(&u.ID, &u.Role, &u.CreatedAt)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, user.ErrLoginTaken
+		}
 		return nil, err
 	}
 	return u, nil
@@ -30,16 +38,268 @@ func (r *UserPG) CreateUser(ctx context.Context, u *user.User) (*user.User, erro
 
 func (r *UserPG) GetByLogin(ctx context.Context, login string) (*user.User, error) {
 	row := r.DB.QueryRowContext(ctx, `
-		SELECT id, login, password FROM users WHERE login = $1
+		SELECT `+userColumns+` FROM users WHERE login = $1
 	`, login)
 
-	var u user.User
-	if err := row.Scan(&u.ID, &u.Login, &u.Password); err != nil {
+	u, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
 
+	return u, nil
+}
+
+func (r *UserPG) GetByID(ctx context.Context, id int64) (*user.User, error) {
+	row := r.DB.QueryRowContext(ctx, `
+		SELECT `+userColumns+` FROM users WHERE id = $1
+	`, id)
+
+	u, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, user.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (r *UserPG) SearchUsers(ctx context.Context, query string, limit int) ([]*user.User, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT `+userColumns+` FROM users
+		WHERE $1 = '' OR login ILIKE '%' || $1 || '%'
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, escapeLike(query), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*user.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// SetBlocked меняет блокировку, отзывает сессии заблокированного пользователя и пишет запись
+// аудита одной транзакцией: заблокированный пользователь не сохранит действующие токены,
+// а изменение без записи в журнале не останется
+func (r *UserPG) SetBlocked(ctx context.Context, id int64, blocked bool, entry *audit.Entry) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET blocked_at = CASE WHEN $2 THEN COALESCE(blocked_at, NOW()) ELSE NULL END
+		WHERE id = $1
+	`, id, blocked)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return user.ErrUserNotFound
+	}
+	if blocked {
+		if err := revokeUserTokens(ctx, tx, int(id)); err != nil {
+			return err
+		}
+	}
+	if err := recordAudit(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserPG) SyncAdmins(ctx context.Context, ids []int64) ([]int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users SET role = $2 WHERE id = ANY($1)
+	`, pq.Array(ids), string(user.RoleAdmin))
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n != int64(len(ids)) {
+		return nil, fmt.Errorf("%w: %d of %d admin ids exist", user.ErrUserNotFound, n, len(ids))
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE users SET role = $2
+		WHERE role = $3 AND NOT (id = ANY($1))
+		RETURNING id
+	`, pq.Array(ids), string(user.RoleUser), string(user.RoleAdmin))
+	if err != nil {
+		return nil, err
+	}
+	var demoted []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		demoted = append(demoted, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return demoted, nil
+}
+
+const userColumns = "id, login, password, role, created_at, blocked_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*user.User, error) {
+	var u user.User
+	if err := row.Scan(&u.ID, &u.Login, &u.Password, &u.Role, &u.CreatedAt, &u.BlockedAt); err != nil {
+		return nil, err
+	}
 	return &u, nil
 }
+
+// escapeLike экранирует спецсимволы шаблона LIKE во введённой строке
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *UserPG) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	return updatePassword(ctx, r.DB, id, passwordHash)
+}
+
+func (r *UserPG) ChangePassword(ctx context.Context, id int64, passwordHash string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updatePassword(ctx, tx, id, passwordHash); err != nil {
+		return err
+	}
+	if err := revokeUserTokens(ctx, tx, int(id)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserPG) CreateResetToken(ctx context.Context, t *user.ResetToken) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, t.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, t.UserID, t.TokenHash, t.ExpiresAt).Scan(&t.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserPG) GetResetTokenUser(ctx context.Context, tokenHash string) (int64, error) {
+	var userID int64
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, user.ErrInvalidResetToken
+	}
+	return userID, err
+}
+
+func (r *UserPG) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Токен гасится тем же запросом, которым проверяется, поэтому его нельзя использовать дважды
+	var userID int64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, user.ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := updatePassword(ctx, tx, userID, passwordHash); err != nil {
+		return 0, err
+	}
+	if err := revokeUserTokens(ctx, tx, int(userID)); err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+func updatePassword(ctx context.Context, q querier, id int64, passwordHash string) error {
+	res, err := q.ExecContext(ctx, `
+		UPDATE users SET password = $2 WHERE id = $1
+	`, id, passwordHash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return user.ErrUserNotFound
+	}
+	return nil
+}
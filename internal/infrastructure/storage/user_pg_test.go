@@ -0,0 +1,135 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	grantAdminQuery  = regexp.QuoteMeta(`UPDATE users SET role = $2 WHERE id = ANY($1)`)
+	demoteAdminQuery = regexp.QuoteMeta(`WHERE role = $3 AND NOT (id = ANY($1))`)
+)
+
+func TestSyncAdmins(t *testing.T) {
+	ctx := context.Background()
+	ids := []int64{1, 2}
+
+	t.Run("grants listed ids and demotes the rest", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(grantAdminQuery).
+			WithArgs(pq.Array(ids), "admin").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(demoteAdminQuery).
+			WithArgs(pq.Array(ids), "user", "admin").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		demoted, err := storage.NewUserPG(db).SyncAdmins(ctx, ids)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{7}, demoted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown id rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(grantAdminQuery).
+			WithArgs(pq.Array(ids), "admin").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		_, err = storage.NewUserPG(db).SyncAdmins(ctx, ids)
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET password = $2 WHERE id = $1`)).
+		WithArgs(int64(7), "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens`)).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, storage.NewUserPG(db).ChangePassword(ctx, 7, "hash"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetBlocked(t *testing.T) {
+	ctx := context.Background()
+	blockQuery := regexp.QuoteMeta(`SET blocked_at = CASE WHEN $2`)
+	entry := &audit.Entry{ActorID: 1, Action: audit.ActionBlockUser, TargetUserID: 7, Details: map[string]string{"reason": "fraud"}}
+
+	t.Run("block revokes sessions and records audit entry in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(blockQuery).WithArgs(int64(7), true).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens`)).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO admin_audit_log`)).
+			WithArgs(1, "BLOCK_USER", sqlmock.AnyArg(), []byte(`{"reason":"fraud"}`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectCommit()
+
+		require.NoError(t, storage.NewUserPG(db).SetBlocked(ctx, 7, true, entry))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("audit failure keeps user unblocked", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(blockQuery).WithArgs(int64(7), true).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens`)).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO admin_audit_log`)).WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		assert.Error(t, storage.NewUserPG(db).SetBlocked(ctx, 7, true, entry))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(blockQuery).WithArgs(int64(7), false).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = storage.NewUserPG(db).SetBlocked(ctx, 7, false, entry)
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
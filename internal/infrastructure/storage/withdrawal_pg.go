@@ -6,7 +6,10 @@ import (
 	"errors"
 	"time"
 
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	"github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
+	"github.com/lib/pq"
 )
 
 type WithdrawalPG struct {
@@ -17,7 +20,7 @@ func NewWithdrawalPG(db *sql.DB) *WithdrawalPG {
 	return &WithdrawalPG{db: db}
 }
 
-func (r *WithdrawalPG) Withdraw(ctx context.Context, userID int, order string, sum float64) error {
+func (r *WithdrawalPG) Withdraw(ctx context.Context, userID int, order string, sum money.Amount) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -25,7 +28,7 @@ func (r *WithdrawalPG) Withdraw(ctx context.Context, userID int, order string, s
 	defer tx.Rollback()
 
 	// Проверка баланса
-	var current float64
+	var current money.Amount
 	err = tx.QueryRowContext(ctx, `
 		SELECT current_balance FROM user_balances WHERE user_id = $1 FOR UPDATE
 	`, userID).Scan(&current)
@@ -48,6 +51,18 @@ func (r *WithdrawalPG) Withdraw(ctx context.Context, userID int, order string, s
 		VALUES ($1, $2, $3, $4)
 	`, userID, order, sum, time.Now())
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return withdrawal.ErrDuplicateOrder
+		}
+		return err
+	}
+
+	// Проводка в журнале
+	if err := postTransaction(ctx, tx, ledger.NewWithdrawal(userID, order, sum)); err != nil {
+		if errors.Is(err, ledger.ErrDuplicateTransaction) {
+			return withdrawal.ErrDuplicateOrder
+		}
 		return err
 	}
 
@@ -64,9 +79,25 @@ func (r *WithdrawalPG) Withdraw(ctx context.Context, userID int, order string, s
 	return tx.Commit()
 }
 
+const withdrawalColumns = `id, user_id, order_number, sum, status, processed_at, reversed_at, COALESCE(reversal_reason, '')`
+
+func scanWithdrawal(row rowScanner) (*withdrawal.Withdrawal, error) {
+	var w withdrawal.Withdrawal
+	var status string
+	var reversedAt sql.NullTime
+	if err := row.Scan(&w.ID, &w.UserID, &w.Order, &w.Sum, &status, &w.ProcessedAt, &reversedAt, &w.ReversalReason); err != nil {
+		return nil, err
+	}
+	w.Status = withdrawal.Status(status)
+	if reversedAt.Valid {
+		w.ReversedAt = &reversedAt.Time
+	}
+	return &w, nil
+}
+
 func (r *WithdrawalPG) GetUserWithdrawals(ctx context.Context, userID int) ([]*withdrawal.Withdrawal, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT order_number, sum, processed_at
+		SELECT `+withdrawalColumns+`
 		FROM withdrawals
 		WHERE user_id = $1
 		ORDER BY processed_at DESC
@@ -78,11 +109,11 @@ func (r *WithdrawalPG) GetUserWithdrawals(ctx context.Context, userID int) ([]*w
 
 	var result []*withdrawal.Withdrawal
 	for rows.Next() {
-		var w withdrawal.Withdrawal
-		if err := rows.Scan(&w.Order, &w.Sum, &w.ProcessedAt); err != nil {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
 			return nil, err
 		}
-		result = append(result, &w)
+		result = append(result, w)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -92,10 +123,108 @@ func (r *WithdrawalPG) GetUserWithdrawals(ctx context.Context, userID int) ([]*w
 	return result, nil
 }
 
-func (r *WithdrawalPG) GetTotalWithdrawn(ctx context.Context, userID int) (float64, error) {
-	var total float64
-	err := r.db.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(sum), 0) FROM withdrawals WHERE user_id = $1
-	`, userID).Scan(&total)
-	return total, err
+// ListUserWithdrawals читает страницу по индексу (user_id, processed_at);
+// id только разрешает совпадения времени внутри одной страницы
+func (r *WithdrawalPG) ListUserWithdrawals(ctx context.Context, userID int, filter withdrawal.ListFilter) ([]*withdrawal.Withdrawal, error) {
+	var from, to, afterAt sql.NullTime
+	var afterID int64
+	if !filter.From.IsZero() {
+		from = sql.NullTime{Time: filter.From, Valid: true}
+	}
+	if !filter.To.IsZero() {
+		to = sql.NullTime{Time: filter.To, Valid: true}
+	}
+	if filter.After != nil {
+		afterAt = sql.NullTime{Time: filter.After.At, Valid: true}
+		afterID = filter.After.ID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+withdrawalColumns+`
+		FROM withdrawals
+		WHERE user_id = $1
+			AND ($2::TIMESTAMPTZ IS NULL OR processed_at >= $2)
+			AND ($3::TIMESTAMPTZ IS NULL OR processed_at < $3)
+			AND ($4::TIMESTAMPTZ IS NULL OR (processed_at, id) < ($4, $5))
+		ORDER BY processed_at DESC, id DESC
+		LIMIT $6
+	`, userID, from, to, afterAt, afterID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*withdrawal.Withdrawal
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *WithdrawalPG) GetByOrder(ctx context.Context, order string) (*withdrawal.Withdrawal, error) {
+	w, err := scanWithdrawal(r.db.QueryRowContext(ctx, `
+		SELECT `+withdrawalColumns+` FROM withdrawals WHERE order_number = $1
+	`, order))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withdrawal.ErrWithdrawalNotFound
+	}
+	return w, err
+}
+
+func (r *WithdrawalPG) Reverse(ctx context.Context, order string, reason string, actorID int) (*withdrawal.Withdrawal, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Условие на статус делает отмену однократной даже при параллельных запросах
+	w, err := scanWithdrawal(tx.QueryRowContext(ctx, `
+		UPDATE withdrawals
+		SET status = $2, reversed_at = NOW(), reversal_reason = $3
+		WHERE order_number = $1 AND status = $4
+		RETURNING `+withdrawalColumns+`
+	`, order, string(withdrawal.StatusReversed), reason, string(withdrawal.StatusCompleted)))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := r.GetByOrder(ctx, order); err != nil {
+			return nil, err
+		}
+		return nil, withdrawal.ErrAlreadyReversed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Проводка в журнале
+	if err := postTransaction(ctx, tx, ledger.NewWithdrawalReversal(w.UserID, order, w.Sum, reason)); err != nil {
+		return nil, err
+	}
+
+	// Возврат баланса
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_balances
+		SET current_balance = current_balance + $1, total_withdrawn = total_withdrawn - $1
+		WHERE user_id = $2
+	`, w.Sum, w.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, w.ReversalAuditEntry(actorID)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return w, nil
 }
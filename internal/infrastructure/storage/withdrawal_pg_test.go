@@ -0,0 +1,123 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	reverseWithdrawalQuery = regexp.QuoteMeta(`UPDATE withdrawals`)
+	selectWithdrawalQuery  = regexp.QuoteMeta(`FROM withdrawals WHERE order_number`)
+	restoreBalanceQuery    = regexp.QuoteMeta(`UPDATE user_balances`)
+	withdrawalRowColumns   = []string{"id", "user_id", "order_number", "sum", "status", "processed_at", "reversed_at", "reversal_reason"}
+)
+
+func TestReverseWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	sum := money.FromFloat(100)
+
+	t.Run("status, ledger, balance and audit entry updated in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(reverseWithdrawalQuery).
+			WithArgs("2377225624", "REVERSED", "order cancelled", "COMPLETED").
+			WillReturnRows(sqlmock.NewRows(withdrawalRowColumns).
+				AddRow(1, 1, "2377225624", sum, "REVERSED", now, now, "order cancelled"))
+		expectLedgerPost(mock, "WITHDRAWAL_REVERSAL", 1, "2377225624", sum)
+		mock.ExpectExec(restoreBalanceQuery).
+			WithArgs(sum, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(auditQuery).
+			WithArgs(9, "REVERSE_WITHDRAWAL", sqlmock.AnyArg(), []byte(`{"order":"2377225624","reason":"order cancelled","status":"REVERSED","sum":"100.00"}`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+		mock.ExpectCommit()
+
+		w, err := storage.NewWithdrawalPG(db).Reverse(ctx, "2377225624", "order cancelled", 9)
+		require.NoError(t, err)
+		assert.Equal(t, withdrawal.StatusReversed, w.Status)
+		assert.NotNil(t, w.ReversedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already reversed withdrawal is not refunded twice", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(reverseWithdrawalQuery).
+			WillReturnRows(sqlmock.NewRows(withdrawalRowColumns))
+		mock.ExpectQuery(selectWithdrawalQuery).
+			WithArgs("2377225624").
+			WillReturnRows(sqlmock.NewRows(withdrawalRowColumns).
+				AddRow(1, 1, "2377225624", sum, "REVERSED", now, now, "order cancelled"))
+		mock.ExpectRollback()
+
+		_, err = storage.NewWithdrawalPG(db).Reverse(ctx, "2377225624", "again", 9)
+		assert.ErrorIs(t, err, withdrawal.ErrAlreadyReversed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("audit failure rolls back reversal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(reverseWithdrawalQuery).
+			WillReturnRows(sqlmock.NewRows(withdrawalRowColumns).
+				AddRow(1, 1, "2377225624", sum, "REVERSED", now, now, "order cancelled"))
+		expectLedgerPost(mock, "WITHDRAWAL_REVERSAL", 1, "2377225624", sum)
+		mock.ExpectExec(restoreBalanceQuery).
+			WithArgs(sum, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(auditQuery).WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err = storage.NewWithdrawalPG(db).Reverse(ctx, "2377225624", "order cancelled", 9)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListUserWithdrawals(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	from := now.Add(-24 * time.Hour)
+	cursor := &withdrawal.Cursor{At: now, ID: 9}
+	mock.ExpectQuery(regexp.QuoteMeta(`(processed_at, id) < ($4, $5)) ORDER BY processed_at DESC, id DESC`)).
+		WithArgs(1, from, nil, now, int64(9), 51).
+		WillReturnRows(sqlmock.NewRows(withdrawalRowColumns).
+			AddRow(8, 1, "2377225624", money.FromFloat(10), "COMPLETED", now.Add(-time.Hour), nil, ""))
+
+	items, err := storage.NewWithdrawalPG(db).ListUserWithdrawals(ctx, 1, withdrawal.ListFilter{
+		Params: pagination.Params{From: from, After: cursor, Limit: 51},
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, int64(8), items[0].ID)
+	assert.Nil(t, items[0].ReversedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
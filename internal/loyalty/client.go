@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,6 +43,9 @@ func (c *httpClient) GetAccrual(ctx context.Context, orderNumber string) (*Order
 	if resp.StatusCode == http.StatusNoContent {
 		return nil, nil
 	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("accrual service error: %d", resp.StatusCode)
 	}
@@ -52,3 +57,21 @@ func (c *httpClient) GetAccrual(ctx context.Context, orderNumber string) (*Order
 
 	return &info, nil
 }
+
+// parseRetryAfter разбирает Retry-After в секундах или в формате HTTP-даты
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultRetryAfter
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return DefaultRetryAfter
+}
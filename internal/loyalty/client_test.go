@@ -0,0 +1,125 @@
+package loyalty_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAccrualStub(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestGetAccrual(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("processed order", func(t *testing.T) {
+		srv, _ := newAccrualStub(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/orders/12345678903", r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"order": "12345678903", "status": "PROCESSED", "accrual": 729.98}`))
+		})
+
+		accrual, err := loyalty.NewClient(srv.URL).GetAccrual(ctx, "12345678903")
+		require.NoError(t, err)
+		assert.Equal(t, loyalty.StatusProcessed, accrual.Status)
+		require.NotNil(t, accrual.Accrual)
+		assert.Equal(t, money.FromMinor(72998), *accrual.Accrual)
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		srv, _ := newAccrualStub(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		accrual, err := loyalty.NewClient(srv.URL).GetAccrual(ctx, "12345678903")
+		require.NoError(t, err)
+		assert.Nil(t, accrual)
+	})
+
+	t.Run("rate limited with seconds", func(t *testing.T) {
+		srv, _ := newAccrualStub(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "No more than N requests per minute allowed", http.StatusTooManyRequests)
+		})
+
+		_, err := loyalty.NewClient(srv.URL).GetAccrual(ctx, "12345678903")
+		assert.ErrorIs(t, err, loyalty.ErrRateLimited)
+
+		retryAfter, ok := loyalty.AsRateLimit(err)
+		require.True(t, ok)
+		assert.Equal(t, 60*time.Second, retryAfter)
+	})
+
+	t.Run("rate limited with http date", func(t *testing.T) {
+		srv, _ := newAccrualStub(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", time.Now().Add(2*time.Minute).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		_, err := loyalty.NewClient(srv.URL).GetAccrual(ctx, "12345678903")
+		retryAfter, ok := loyalty.AsRateLimit(err)
+		require.True(t, ok)
+		assert.InDelta(t, 2*time.Minute, retryAfter, float64(2*time.Second))
+	})
+
+	t.Run("rate limited without header", func(t *testing.T) {
+		srv, _ := newAccrualStub(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		_, err := loyalty.NewClient(srv.URL).GetAccrual(ctx, "12345678903")
+		retryAfter, ok := loyalty.AsRateLimit(err)
+		require.True(t, ok)
+		assert.Equal(t, loyalty.DefaultRetryAfter, retryAfter)
+	})
+
+	t.Run("server error is not a rate limit", func(t *testing.T) {
+		srv, _ := newAccrualStub(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		_, err := loyalty.NewClient(srv.URL).GetAccrual(ctx, "12345678903")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, loyalty.ErrRateLimited)
+	})
+}
+
+func TestServicePausesAfterRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	srv, calls := newAccrualStub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	service := loyalty.New(loyalty.NewClient(srv.URL))
+
+	_, err := service.GetOrderAccrual(ctx, "12345678903")
+	assert.ErrorIs(t, err, loyalty.ErrRateLimited)
+
+	// Во время паузы запросы не уходят в систему начислений
+	_, err = service.GetOrderAccrual(ctx, "79927398713")
+	assert.ErrorIs(t, err, loyalty.ErrRateLimited)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Positive(t, service.PauseRemaining())
+
+	// После окончания окна запросы возобновляются
+	assert.Eventually(t, func() bool { return service.PauseRemaining() <= 0 }, 2*time.Second, 50*time.Millisecond)
+	_, err = service.GetOrderAccrual(ctx, "79927398713")
+	assert.ErrorIs(t, err, loyalty.ErrRateLimited)
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -1,5 +1,7 @@
 package loyalty
 
+import "github.com/GarikMirzoyan/gophermart/internal/domain/money"
+
 type AccrualStatus string
 
 const (
@@ -12,5 +14,5 @@ const (
 type OrderAccrual struct {
 	Order   string        `json:"order"`
 	Status  AccrualStatus `json:"status"`
-	Accrual *float64      `json:"accrual,omitempty"`
+	Accrual *money.Amount `json:"accrual,omitempty"`
 }
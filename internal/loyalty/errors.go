@@ -0,0 +1,34 @@
+package loyalty
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultRetryAfter — пауза, если система начислений вернула 429 без корректного Retry-After
+const DefaultRetryAfter = time.Minute
+
+var ErrRateLimited = errors.New("accrual system rate limit exceeded")
+
+// RateLimitError — ответ 429 Too Many Requests с задержкой из заголовка Retry-After
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// AsRateLimit возвращает задержку, если err вызвана ограничением частоты запросов
+func AsRateLimit(err error) (time.Duration, bool) {
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return rl.RetryAfter, true
+	}
+	return 0, false
+}
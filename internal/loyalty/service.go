@@ -2,10 +2,17 @@ package loyalty
 
 import (
 	"context"
+	"log"
+	"sync"
+	"time"
 )
 
 type Service struct {
 	client Client
+
+	// После ответа 429 все запросы к системе начислений приостанавливаются до pausedUntil
+	mu          sync.Mutex
+	pausedUntil time.Time
 }
 
 func New(client Client) *Service {
@@ -14,6 +21,34 @@ func New(client Client) *Service {
 	}
 }
 
+// GetOrderAccrual запрашивает начисление по заказу. Пока действует пауза после 429,
+// запрос не отправляется и сразу возвращается *RateLimitError с оставшимся временем.
 func (s *Service) GetOrderAccrual(ctx context.Context, number string) (*OrderAccrual, error) {
-	return s.client.GetAccrual(ctx, number)
+	if wait := s.PauseRemaining(); wait > 0 {
+		return nil, &RateLimitError{RetryAfter: wait}
+	}
+
+	accrual, err := s.client.GetAccrual(ctx, number)
+	if retryAfter, ok := AsRateLimit(err); ok {
+		s.pause(retryAfter)
+	}
+	return accrual, err
+}
+
+// PauseRemaining возвращает, сколько ещё действует пауза после 429
+func (s *Service) PauseRemaining() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Until(s.pausedUntil)
+}
+
+func (s *Service) pause(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(s.pausedUntil) {
+		s.pausedUntil = until
+		log.Printf("[LOYALTY] rate limited, pausing accrual requests for %s", d)
+	}
 }
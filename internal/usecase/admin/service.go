@@ -0,0 +1,248 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+	domainbalance "github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/balance"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+var ErrCannotBlockSelf = errors.New("administrator cannot block own account")
+
+// SessionRevoker отзывает все сессии пользователя при блокировке
+type SessionRevoker interface {
+	RevokeAll(ctx context.Context, userID int) error
+}
+
+// WithdrawalReverser отменяет списание и возвращает баллы пользователю
+type WithdrawalReverser interface {
+	Reverse(ctx context.Context, orderNumber string, reason string, actorID int) (*withdrawal.Withdrawal, error)
+}
+
+// OrderService отдаёт заказы постранично, отменяет заказ и списывает начисленные по нему баллы
+type OrderService interface {
+	ListOrders(ctx context.Context, userID int, filter order.ListFilter) (*order.Page, error)
+	ReturnOrder(ctx context.Context, number string, reason string, source order.EventSource, actorID int) (*order.ReturnResult, error)
+}
+
+// Service — операции поддержки над чужими аккаунтами. Каждое действие, включая
+// просмотр и чтение самого журнала, записывается в журнал только после успешного выполнения,
+// с результатом в Details: неудавшиеся действия в журнал не попадают. Изменения пишут запись
+// в своей транзакции и без неё откатываются; если не удалась запись о просмотре, данные не отдаются.
+type Service struct {
+	users       user.Repository
+	orders      order.Repository
+	withdrawals withdrawal.Repository
+	balance     balance.IService
+	sessions    SessionRevoker
+	reverser    WithdrawalReverser
+	orderSvc    OrderService
+	audit       audit.Repository
+}
+
+func New(
+	users user.Repository,
+	orders order.Repository,
+	withdrawals withdrawal.Repository,
+	balanceService balance.IService,
+	sessions SessionRevoker,
+	reverser WithdrawalReverser,
+	orderService OrderService,
+	auditRepo audit.Repository,
+) *Service {
+	return &Service{
+		users:       users,
+		orders:      orders,
+		withdrawals: withdrawals,
+		balance:     balanceService,
+		sessions:    sessions,
+		reverser:    reverser,
+		orderSvc:    orderService,
+		audit:       auditRepo,
+	}
+}
+
+func (s *Service) SearchUsers(ctx context.Context, actorID int, query string, limit int) ([]*user.User, error) {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	users, err := s.users.SearchUsers(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actorID, audit.ActionSearchUsers, 0, map[string]string{"query": query}); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, actorID, userID int) (*user.User, error) {
+	u, err := s.users.GetByID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actorID, audit.ActionViewUser, userID, nil); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// GetUserOrders отдаёт страницу заказов пользователя с теми же фильтрами, что и у него самого
+func (s *Service) GetUserOrders(ctx context.Context, actorID, userID int, filter order.ListFilter) (*order.Page, error) {
+	page, err := s.orderSvc.ListOrders(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actorID, audit.ActionViewOrders, userID, nil); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (s *Service) GetUserWithdrawals(ctx context.Context, actorID, userID int) ([]*withdrawal.Withdrawal, error) {
+	withdrawals, err := s.withdrawals.GetUserWithdrawals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actorID, audit.ActionViewWithdrawals, userID, nil); err != nil {
+		return nil, err
+	}
+	return withdrawals, nil
+}
+
+func (s *Service) GetUserBalance(ctx context.Context, actorID, userID int) (*domainbalance.Balance, error) {
+	bal, err := s.balance.GetBalance(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actorID, audit.ActionViewBalance, userID, nil); err != nil {
+		return nil, err
+	}
+	return bal, nil
+}
+
+// BlockUser блокирует аккаунт и в той же транзакции отзывает все его сессии, так что уже
+// выданные токены доступа перестают приниматься сразу
+func (s *Service) BlockUser(ctx context.Context, actorID, userID int, reason string) error {
+	if actorID == userID {
+		return ErrCannotBlockSelf
+	}
+	entry := newEntry(actorID, audit.ActionBlockUser, userID, map[string]string{"reason": reason})
+	return s.users.SetBlocked(ctx, int64(userID), true, entry)
+}
+
+func (s *Service) UnblockUser(ctx context.Context, actorID, userID int, reason string) error {
+	entry := newEntry(actorID, audit.ActionUnblockUser, userID, map[string]string{"reason": reason})
+	return s.users.SetBlocked(ctx, int64(userID), false, entry)
+}
+
+// SyncAdmins приводит роль администратора к списку ids из конфигурации. Понижённые
+// администраторы теряют сессии сразу, а не по истечении токенов.
+func (s *Service) SyncAdmins(ctx context.Context, ids []int64) error {
+	demoted, err := s.users.SyncAdmins(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("sync admin role: %w", err)
+	}
+	for _, id := range demoted {
+		if err := s.sessions.RevokeAll(ctx, int(id)); err != nil {
+			return fmt.Errorf("revoke sessions of demoted admin %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// AdjustBalance начисляет или списывает баллы вручную. Корректировка попадает
+// в выписку пользователя отдельной строкой с причиной; force разрешает уйти в минус.
+// Запись аудита пишется в транзакции проводки, поэтому отклонённая корректировка в журнал не попадает.
+func (s *Service) AdjustBalance(ctx context.Context, actorID, userID int, amount money.Amount, reason string, force bool) (*domainbalance.Balance, error) {
+	adj := &domainbalance.Adjustment{
+		UserID:     userID,
+		OperatorID: actorID,
+		Amount:     amount,
+		Reason:     reason,
+		Force:      force,
+	}
+	if err := adj.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.users.GetByID(ctx, int64(userID)); err != nil {
+		return nil, err
+	}
+	return s.balance.Adjust(ctx, adj)
+}
+
+// ReverseWithdrawal отменяет списание по номеру заказа, например если заказ,
+// оплаченный баллами, был отменён. Запись аудита пишется в транзакции возврата баллов.
+func (s *Service) ReverseWithdrawal(ctx context.Context, actorID int, orderNumber string, reason string) (*withdrawal.Withdrawal, error) {
+	w, err := s.withdrawals.GetByOrder(ctx, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	if w.Status == withdrawal.StatusReversed {
+		return nil, withdrawal.ErrAlreadyReversed
+	}
+	return s.reverser.Reverse(ctx, orderNumber, reason, actorID)
+}
+
+// ReturnOrder отменяет заказ по обращению в поддержку и списывает начисленные по нему баллы.
+// Запись аудита пишется в транзакции возврата.
+func (s *Service) ReturnOrder(ctx context.Context, actorID int, number string, reason string) (*order.ReturnResult, error) {
+	ownerID, err := s.orders.GetOrderOwner(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == 0 {
+		return nil, order.ErrOrderNotFound
+	}
+	return s.orderSvc.ReturnOrder(ctx, number, reason, order.SourceAdmin, actorID)
+}
+
+// GetAuditLog возвращает журнал действий; userID = 0 — по всем пользователям.
+// Чтение журнала тоже записывается в журнал.
+func (s *Service) GetAuditLog(ctx context.Context, actorID, userID int, limit int) ([]*audit.Entry, error) {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	entries, err := s.audit.List(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actorID, audit.ActionViewAuditLog, userID, nil); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *Service) record(ctx context.Context, actorID int, action audit.Action, targetUserID int, details map[string]string) error {
+	if err := s.audit.Record(ctx, newEntry(actorID, action, targetUserID, details)); err != nil {
+		return fmt.Errorf("failed to record audit entry %s for user %d: %w", action, targetUserID, err)
+	}
+	return nil
+}
+
+func newEntry(actorID int, action audit.Action, targetUserID int, details map[string]string) *audit.Entry {
+	return &audit.Entry{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		Details:      details,
+	}
+}
@@ -0,0 +1,284 @@
+package admin_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/audit"
+	domainbalance "github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	domainwithdrawal "github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/admin"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/withdrawal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	auditmocks "github.com/GarikMirzoyan/gophermart/internal/domain/audit/mocks"
+	ordermocks "github.com/GarikMirzoyan/gophermart/internal/domain/order/mocks"
+	usermocks "github.com/GarikMirzoyan/gophermart/internal/domain/user/mocks"
+	withdrawalmocks "github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal/mocks"
+	balancemocks "github.com/GarikMirzoyan/gophermart/internal/usecase/balance/mocks"
+)
+
+type stubRevoker struct {
+	revoked []int
+}
+
+func (s *stubRevoker) RevokeAll(ctx context.Context, userID int) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+func auditEntry(actorID int, action audit.Action, targetUserID int) interface{} {
+	return mock.MatchedBy(func(e *audit.Entry) bool {
+		return e.ActorID == actorID && e.Action == action && e.TargetUserID == targetUserID
+	})
+}
+
+func TestService_BlockUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("blocks user with audit entry", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		service := admin.New(users, nil, nil, nil, &stubRevoker{}, nil, nil, auditmocks.NewRepository(t))
+
+		users.On("SetBlocked", ctx, int64(7), true, mock.MatchedBy(func(e *audit.Entry) bool {
+			return e.ActorID == 1 && e.Action == audit.ActionBlockUser &&
+				e.TargetUserID == 7 && e.Details["reason"] == "fraud"
+		})).Return(nil)
+
+		require.NoError(t, service.BlockUser(ctx, 1, 7, "fraud"))
+	})
+
+	t.Run("cannot block own account", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		auditRepo := auditmocks.NewRepository(t)
+		service := admin.New(users, nil, nil, nil, &stubRevoker{}, nil, nil, auditRepo)
+
+		err := service.BlockUser(ctx, 1, 1, "oops")
+		assert.ErrorIs(t, err, admin.ErrCannotBlockSelf)
+	})
+
+	t.Run("failed block is reported", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		service := admin.New(users, nil, nil, nil, &stubRevoker{}, nil, nil, auditmocks.NewRepository(t))
+
+		users.On("SetBlocked", ctx, int64(7), true, auditEntry(1, audit.ActionBlockUser, 7)).Return(user.ErrUserNotFound)
+
+		err := service.BlockUser(ctx, 1, 7, "fraud")
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+	})
+}
+
+func TestService_SyncAdmins(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("revokes sessions of demoted admins", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		sessions := &stubRevoker{}
+		service := admin.New(users, nil, nil, nil, sessions, nil, nil, nil)
+
+		users.On("SyncAdmins", ctx, []int64{1}).Return([]int64{5, 9}, nil)
+
+		require.NoError(t, service.SyncAdmins(ctx, []int64{1}))
+		assert.Equal(t, []int{5, 9}, sessions.revoked)
+	})
+
+	t.Run("unknown admin id fails", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		sessions := &stubRevoker{}
+		service := admin.New(users, nil, nil, nil, sessions, nil, nil, nil)
+
+		users.On("SyncAdmins", ctx, []int64{42}).Return(nil, user.ErrUserNotFound)
+
+		err := service.SyncAdmins(ctx, []int64{42})
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		assert.Empty(t, sessions.revoked)
+	})
+}
+
+func TestService_SearchUsers(t *testing.T) {
+	ctx := context.Background()
+	users := usermocks.NewRepository(t)
+	auditRepo := auditmocks.NewRepository(t)
+	service := admin.New(users, nil, nil, nil, &stubRevoker{}, nil, nil, auditRepo)
+
+	auditRepo.On("Record", ctx, auditEntry(1, audit.ActionSearchUsers, 0)).Return(nil)
+	users.On("SearchUsers", ctx, "ali", admin.MaxSearchLimit).
+		Return([]*user.User{{ID: 7, Login: "alice"}}, nil)
+
+	found, err := service.SearchUsers(ctx, 1, "ali", 1000)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "alice", found[0].Login)
+}
+
+func TestService_GetUser(t *testing.T) {
+	ctx := context.Background()
+	users := usermocks.NewRepository(t)
+	auditRepo := auditmocks.NewRepository(t)
+	service := admin.New(users, nil, nil, nil, &stubRevoker{}, nil, nil, auditRepo)
+
+	auditRepo.On("Record", ctx, auditEntry(1, audit.ActionViewUser, 7)).Return(nil)
+	users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, Login: "alice"}, nil)
+
+	u, err := service.GetUser(ctx, 1, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", u.Login)
+}
+
+func TestService_AdjustBalance(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("adjusts balance with audit left to the ledger transaction", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		auditRepo := auditmocks.NewRepository(t)
+		balanceService := balancemocks.NewIService(t)
+		service := admin.New(users, nil, nil, balanceService, &stubRevoker{}, nil, nil, auditRepo)
+
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7}, nil)
+		balanceService.On("Adjust", ctx, mock.MatchedBy(func(adj *domainbalance.Adjustment) bool {
+			return adj.UserID == 7 && adj.OperatorID == 1 && adj.Amount == money.FromFloat(25) && !adj.Force
+		})).Return(&domainbalance.Balance{UserID: 7, Current: money.FromFloat(25)}, nil)
+
+		bal, err := service.AdjustBalance(ctx, 1, 7, money.FromFloat(25), "goodwill", false)
+		require.NoError(t, err)
+		assert.Equal(t, money.FromFloat(25), bal.Current)
+		auditRepo.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejected adjustment leaves no audit entry", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		auditRepo := auditmocks.NewRepository(t)
+		balanceService := balancemocks.NewIService(t)
+		service := admin.New(users, nil, nil, balanceService, &stubRevoker{}, nil, nil, auditRepo)
+
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7}, nil)
+		balanceService.On("Adjust", ctx, mock.Anything).Return(nil, domainbalance.ErrNegativeBalance)
+
+		_, err := service.AdjustBalance(ctx, 1, 7, money.FromFloat(-25), "fraud", false)
+		assert.ErrorIs(t, err, domainbalance.ErrNegativeBalance)
+		auditRepo.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid adjustment is not audited", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		auditRepo := auditmocks.NewRepository(t)
+		service := admin.New(users, nil, nil, balancemocks.NewIService(t), &stubRevoker{}, nil, nil, auditRepo)
+
+		_, err := service.AdjustBalance(ctx, 1, 7, money.FromFloat(25), "", false)
+		assert.ErrorIs(t, err, domainbalance.ErrReasonRequired)
+	})
+}
+
+func TestService_ReverseWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	users := usermocks.NewRepository(t)
+	auditRepo := auditmocks.NewRepository(t)
+	withdrawals := withdrawalmocks.NewRepository(t)
+	service := admin.New(users, nil, withdrawals, nil, &stubRevoker{}, withdrawal.New(withdrawals), nil, auditRepo)
+
+	completed := &domainwithdrawal.Withdrawal{Order: "2377225624", UserID: 7, Sum: money.FromFloat(100), Status: domainwithdrawal.StatusCompleted}
+	withdrawals.On("GetByOrder", ctx, "2377225624").Return(completed, nil)
+	withdrawals.On("Reverse", ctx, "2377225624", "order cancelled", 1).Return(&domainwithdrawal.Withdrawal{
+		Order: "2377225624", UserID: 7, Sum: money.FromFloat(100), Status: domainwithdrawal.StatusReversed,
+	}, nil)
+
+	w, err := service.ReverseWithdrawal(ctx, 1, "2377225624", "order cancelled")
+	require.NoError(t, err)
+	assert.Equal(t, domainwithdrawal.StatusReversed, w.Status)
+}
+
+type stubOrderService struct {
+	actorID   int
+	source    order.EventSource
+	filter    order.ListFilter
+	returnErr error
+}
+
+func (s *stubOrderService) ListOrders(ctx context.Context, userID int, filter order.ListFilter) (*order.Page, error) {
+	s.filter = filter
+	return &order.Page{Items: []*order.Order{{Number: "12345678903", UserID: userID}}}, nil
+}
+
+func (s *stubOrderService) ReturnOrder(ctx context.Context, number string, reason string, source order.EventSource, actorID int) (*order.ReturnResult, error) {
+	s.actorID, s.source = actorID, source
+	if s.returnErr != nil {
+		return nil, s.returnErr
+	}
+	return &order.ReturnResult{UserID: 7, FromStatus: order.StatusProcessed, Debited: money.FromFloat(100)}, nil
+}
+
+func TestService_ReturnOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns order on behalf of admin", func(t *testing.T) {
+		orders := ordermocks.NewRepository(t)
+		returner := &stubOrderService{}
+		service := admin.New(nil, orders, nil, nil, &stubRevoker{}, nil, returner, auditmocks.NewRepository(t))
+
+		orders.On("GetOrderOwner", ctx, "12345678903").Return(7, nil)
+
+		result, err := service.ReturnOrder(ctx, 1, "12345678903", "cancelled by store")
+		require.NoError(t, err)
+		assert.Equal(t, money.FromFloat(100), result.Debited)
+		assert.Equal(t, 1, returner.actorID)
+		assert.Equal(t, order.SourceAdmin, returner.source)
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		orders := ordermocks.NewRepository(t)
+		service := admin.New(nil, orders, nil, nil, &stubRevoker{}, nil, &stubOrderService{}, auditmocks.NewRepository(t))
+
+		orders.On("GetOrderOwner", ctx, "12345678903").Return(0, nil)
+
+		_, err := service.ReturnOrder(ctx, 1, "12345678903", "cancelled by store")
+		assert.ErrorIs(t, err, order.ErrOrderNotFound)
+	})
+
+	t.Run("failed return is reported", func(t *testing.T) {
+		orders := ordermocks.NewRepository(t)
+		returner := &stubOrderService{returnErr: order.ErrAlreadyReturned}
+		service := admin.New(nil, orders, nil, nil, &stubRevoker{}, nil, returner, auditmocks.NewRepository(t))
+
+		orders.On("GetOrderOwner", ctx, "12345678903").Return(7, nil)
+
+		_, err := service.ReturnOrder(ctx, 1, "12345678903", "cancelled by store")
+		assert.ErrorIs(t, err, order.ErrAlreadyReturned)
+	})
+}
+
+func TestService_GetUserOrders(t *testing.T) {
+	ctx := context.Background()
+	auditRepo := auditmocks.NewRepository(t)
+	orders := &stubOrderService{}
+	service := admin.New(nil, nil, nil, nil, &stubRevoker{}, nil, orders, auditRepo)
+
+	auditRepo.On("Record", ctx, auditEntry(1, audit.ActionViewOrders, 7)).Return(nil)
+
+	filter := order.ListFilter{Params: pagination.Params{Limit: 10}, Sort: order.SortAsc}
+	page, err := service.GetUserOrders(ctx, 1, 7, filter)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, 7, page.Items[0].UserID)
+	assert.Equal(t, filter, orders.filter)
+}
+
+func TestService_GetAuditLog(t *testing.T) {
+	ctx := context.Background()
+	auditRepo := auditmocks.NewRepository(t)
+	service := admin.New(nil, nil, nil, nil, &stubRevoker{}, nil, nil, auditRepo)
+
+	auditRepo.On("List", ctx, 7, admin.DefaultSearchLimit).
+		Return([]*audit.Entry{{ID: 3, ActorID: 2, Action: audit.ActionBlockUser, TargetUserID: 7}}, nil)
+	auditRepo.On("Record", ctx, auditEntry(1, audit.ActionViewAuditLog, 7)).Return(nil)
+
+	entries, err := service.GetAuditLog(ctx, 1, 7, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, audit.ActionBlockUser, entries[0].Action)
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/token"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+)
+
+// DefaultResetTTL — время жизни токена сброса пароля по умолчанию
+const DefaultResetTTL = time.Hour
+
+// Notifier доставляет пользователю токен сброса пароля
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, u *user.User, resetToken string, expiresAt time.Time) error
+}
+
+// AttemptLimiter ограничивает подбор пароля. Попытка учитывается до проверки пароля
+// и остаётся неудачной, пока не вызван Succeed.
+type AttemptLimiter interface {
+	Attempt(ctx context.Context, login, ip string) error
+	Succeed(ctx context.Context, login, ip string) error
+}
+
+type PasswordService struct {
+	users    user.Repository
+	resets   user.ResetTokenRepository
+	notifier Notifier
+	limiter  AttemptLimiter
+	hasher   PasswordHasher
+	policy   user.PasswordPolicy
+	resetTTL time.Duration
+	now      func() time.Time
+}
+
+// NewPasswordService создаёт сервис смены и сброса пароля. Без notifier сброс пароля отключён:
+// токен некому доставить.
+func NewPasswordService(users user.Repository, resets user.ResetTokenRepository, notifier Notifier, limiter AttemptLimiter, hasher PasswordHasher, policy user.PasswordPolicy, resetTTL time.Duration) *PasswordService {
+	if resetTTL <= 0 {
+		resetTTL = DefaultResetTTL
+	}
+	return &PasswordService{
+		users:    users,
+		resets:   resets,
+		notifier: notifier,
+		limiter:  limiter,
+		hasher:   hasher,
+		policy:   policy,
+		resetTTL: resetTTL,
+		now:      time.Now,
+	}
+}
+
+// ChangePassword меняет пароль после проверки старого и отзывает все сессии пользователя.
+// Проверка старого пароля ограничивается так же, как вход, иначе украденным токеном
+// доступа можно было бы подобрать текущий пароль.
+func (s *PasswordService) ChangePassword(ctx context.Context, userID int, ip, oldPassword, newPassword string) error {
+	u, err := s.users.GetByID(ctx, int64(userID))
+	if err != nil {
+		return err
+	}
+	if err := s.limiter.Attempt(ctx, u.Login, ip); err != nil {
+		return err
+	}
+	ok, _, err := s.hasher.Verify(u.Password, oldPassword)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCredentials
+	}
+	if err := s.limiter.Succeed(ctx, u.Login, ip); err != nil {
+		log.Printf("failed to reset login throttling: %v", err)
+	}
+	if err := s.policy.Validate(newPassword, u.Login); err != nil {
+		return err
+	}
+
+	hashed, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	return s.users.ChangePassword(ctx, u.ID, hashed)
+}
+
+// ResetEnabled сообщает, настроена ли доставка токенов сброса пароля
+func (s *PasswordService) ResetEnabled() bool {
+	return s.notifier != nil
+}
+
+// RequestReset выпускает токен сброса и отправляет его пользователю.
+// Для неизвестного логина ничего не делает, чтобы ответ не выдавал существование аккаунта.
+func (s *PasswordService) RequestReset(ctx context.Context, login string) error {
+	u, err := s.users.GetByLogin(ctx, login)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		log.Printf("password reset requested for unknown login")
+		return nil
+	}
+
+	secret, err := token.NewSecret()
+	if err != nil {
+		return err
+	}
+	rt := &user.ResetToken{
+		UserID:    u.ID,
+		TokenHash: token.Hash(secret),
+		ExpiresAt: s.now().Add(s.resetTTL),
+	}
+	if err := s.resets.CreateResetToken(ctx, rt); err != nil {
+		return err
+	}
+
+	return s.notifier.SendPasswordReset(ctx, u, secret, rt.ExpiresAt)
+}
+
+// ResetPassword устанавливает новый пароль по токену сброса и отзывает все сессии пользователя.
+// Новый пароль проверяется по политике с логином владельца токена, как и при смене пароля.
+func (s *PasswordService) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	tokenHash := token.Hash(resetToken)
+	userID, err := s.resets.GetResetTokenUser(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Validate(newPassword, u.Login); err != nil {
+		return err
+	}
+
+	hashed, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	// Токен гасится здесь: если его успели использовать параллельно, вернётся ErrInvalidResetToken
+	_, err = s.resets.ResetPassword(ctx, tokenHash, hashed)
+	return err
+}
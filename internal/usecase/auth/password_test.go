@@ -0,0 +1,182 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/token"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/password"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	usermocks "github.com/GarikMirzoyan/gophermart/internal/domain/user/mocks"
+)
+
+// Минимальная стоимость bcrypt, чтобы тесты не тратили время на хеширование
+var testHasher = password.NewBcrypt(bcrypt.MinCost, 0)
+
+type notifierStub struct {
+	token string
+}
+
+func (n *notifierStub) SendPasswordReset(_ context.Context, _ *user.User, resetToken string, _ time.Time) error {
+	n.token = resetToken
+	return nil
+}
+
+// limiterStub учитывает попытки и может отклонять их, как throttle.Service
+type limiterStub struct {
+	err       error
+	attempts  int
+	succeeded int
+}
+
+func (l *limiterStub) Attempt(_ context.Context, _, _ string) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.attempts++
+	return nil
+}
+
+func (l *limiterStub) Succeed(_ context.Context, _, _ string) error {
+	l.succeeded++
+	return nil
+}
+
+func hashPassword(t *testing.T, password string) string {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hashed)
+}
+
+func TestPasswordService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("changes hash and revokes sessions in one call", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		limiter := &limiterStub{}
+		service := auth.NewPasswordService(users, usermocks.NewResetTokenRepository(t), &notifierStub{}, limiter, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, Login: "alice", Password: hashPassword(t, "old")}, nil)
+		users.On("ChangePassword", ctx, int64(7), mock.MatchedBy(func(hash string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) == nil
+		})).Return(nil)
+
+		require.NoError(t, service.ChangePassword(ctx, 7, "10.0.0.1", "old", "new-password"))
+		assert.Equal(t, 1, limiter.attempts)
+		assert.Equal(t, 1, limiter.succeeded)
+	})
+
+	t.Run("wrong old password stays counted", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		limiter := &limiterStub{}
+		service := auth.NewPasswordService(users, usermocks.NewResetTokenRepository(t), &notifierStub{}, limiter, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, Login: "alice", Password: hashPassword(t, "old")}, nil)
+
+		assert.ErrorIs(t, service.ChangePassword(ctx, 7, "10.0.0.1", "guess", "new-password"), auth.ErrInvalidCredentials)
+		assert.Equal(t, 1, limiter.attempts)
+		assert.Zero(t, limiter.succeeded)
+		users.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("throttled attempt does not check old password", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		throttled := &throttle.ThrottledError{RetryAfter: time.Minute}
+		service := auth.NewPasswordService(users, usermocks.NewResetTokenRepository(t), &notifierStub{}, &limiterStub{err: throttled}, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, Login: "alice", Password: hashPassword(t, "old")}, nil)
+
+		err := service.ChangePassword(ctx, 7, "10.0.0.1", "old", "new-password")
+		retryAfter, ok := throttle.AsThrottled(err)
+		require.True(t, ok)
+		assert.Equal(t, time.Minute, retryAfter)
+		users.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("weak new password", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		service := auth.NewPasswordService(users, usermocks.NewResetTokenRepository(t), &notifierStub{}, &limiterStub{}, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, Login: "alice", Password: hashPassword(t, "old")}, nil)
+
+		assert.ErrorIs(t, service.ChangePassword(ctx, 7, "10.0.0.1", "old", "short"), user.ErrValidation)
+		users.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPasswordService_Reset(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("token is delivered and stored hashed", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		resets := usermocks.NewResetTokenRepository(t)
+		notifier := &notifierStub{}
+		service := auth.NewPasswordService(users, resets, notifier, &limiterStub{}, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		var stored *user.ResetToken
+		users.On("GetByLogin", ctx, "alice").Return(&user.User{ID: 7, Login: "alice"}, nil)
+		resets.On("CreateResetToken", ctx, mock.AnythingOfType("*user.ResetToken")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*user.ResetToken) }).
+			Return(nil)
+
+		require.NoError(t, service.RequestReset(ctx, "alice"))
+		require.NotEmpty(t, notifier.token)
+		assert.Equal(t, token.Hash(notifier.token), stored.TokenHash)
+		assert.Equal(t, int64(7), stored.UserID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+	})
+
+	t.Run("unknown login is silently ignored", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		notifier := &notifierStub{}
+		service := auth.NewPasswordService(users, usermocks.NewResetTokenRepository(t), notifier, &limiterStub{}, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		users.On("GetByLogin", ctx, "ghost").Return(nil, nil)
+
+		require.NoError(t, service.RequestReset(ctx, "ghost"))
+		assert.Empty(t, notifier.token)
+	})
+
+	t.Run("reset consumes token", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		resets := usermocks.NewResetTokenRepository(t)
+		service := auth.NewPasswordService(users, resets, &notifierStub{}, &limiterStub{}, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		resets.On("GetResetTokenUser", ctx, token.Hash("secret")).Return(int64(7), nil)
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, Login: "alice"}, nil)
+		resets.On("ResetPassword", ctx, token.Hash("secret"), mock.AnythingOfType("string")).Return(int64(7), nil)
+
+		require.NoError(t, service.ResetPassword(ctx, "secret", "new-password"))
+	})
+
+	t.Run("new password equal to login", func(t *testing.T) {
+		users := usermocks.NewRepository(t)
+		resets := usermocks.NewResetTokenRepository(t)
+		service := auth.NewPasswordService(users, resets, &notifierStub{}, &limiterStub{}, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		resets.On("GetResetTokenUser", ctx, token.Hash("secret")).Return(int64(7), nil)
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, Login: "alice-wonder"}, nil)
+
+		assert.ErrorIs(t, service.ResetPassword(ctx, "secret", "Alice-Wonder"), user.ErrValidation)
+		resets.AssertNotCalled(t, "ResetPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("used or expired token", func(t *testing.T) {
+		resets := usermocks.NewResetTokenRepository(t)
+		service := auth.NewPasswordService(usermocks.NewRepository(t), resets, &notifierStub{}, &limiterStub{}, testHasher, user.DefaultPasswordPolicy, time.Hour)
+
+		resets.On("GetResetTokenUser", ctx, token.Hash("secret")).Return(int64(0), user.ErrInvalidResetToken)
+
+		assert.ErrorIs(t, service.ResetPassword(ctx, "secret", "new-password"), user.ErrInvalidResetToken)
+		resets.AssertNotCalled(t, "ResetPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
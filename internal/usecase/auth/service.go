@@ -3,41 +3,50 @@ package auth
 import (
 	"context"
 	"errors"
+	"log"
 
 	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid login or password")
-	ErrLoginTaken         = errors.New("login already taken")
+	ErrLoginTaken         = user.ErrLoginTaken
 )
 
+// PasswordHasher хеширует и проверяет пароли. Закодированный хеш содержит
+// алгоритм и параметры, поэтому старые хеши остаются проверяемыми после смены настроек.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify возвращает needsRehash, если пароль верный, но хеш стоит пересчитать
+	// текущим алгоритмом с текущими параметрами
+	Verify(encoded, password string) (ok bool, needsRehash bool, err error)
+}
+
 type Service struct {
-	repo user.Repository
+	repo   user.Repository
+	hasher PasswordHasher
+	policy user.PasswordPolicy
 }
 
-func New(repo user.Repository) *Service {
-	return &Service{repo: repo}
+func New(repo user.Repository, hasher PasswordHasher, policy user.PasswordPolicy) *Service {
+	return &Service{repo: repo, hasher: hasher, policy: policy}
 }
 
+// Register создаёт пользователя. Занятость логина проверяет уникальный индекс,
+// поэтому при одновременной регистрации одного логина второй запрос получит ErrLoginTaken.
 func (s *Service) Register(ctx context.Context, login, password string) (*user.User, error) {
-	existing, err := s.repo.GetByLogin(ctx, login)
-	if err != nil {
+	if err := s.policy.ValidateRegistration(login, password); err != nil {
 		return nil, err
 	}
-	if existing != nil {
-		return existing, ErrLoginTaken
-	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashed, err := s.hasher.Hash(password)
 	if err != nil {
 		return nil, err
 	}
 
 	return s.repo.CreateUser(ctx, &user.User{
 		Login:    login,
-		Password: string(hashed),
+		Password: hashed,
 	})
 }
 
@@ -50,9 +59,37 @@ func (s *Service) Authenticate(ctx context.Context, login, password string) (*us
 		return nil, ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+	ok, needsRehash, err := s.hasher.Verify(u.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, ErrInvalidCredentials
 	}
+	// Блокировка проверяется после пароля, чтобы по ответу нельзя было подбирать логины
+	if u.Blocked() {
+		return nil, user.ErrAccountBlocked
+	}
+
+	// Открытый пароль доступен только сейчас, поэтому устаревший хеш пересчитывается при входе.
+	// Ошибка пересчёта не мешает войти: попробуем в следующий раз.
+	if needsRehash {
+		if err := s.rehash(ctx, u, password); err != nil {
+			log.Printf("failed to upgrade password hash for user %d: %v", u.ID, err)
+		}
+	}
 
 	return u, nil
 }
+
+func (s *Service) rehash(ctx context.Context, u *user.User, password string) error {
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, u.ID, hashed); err != nil {
+		return err
+	}
+	u.Password = hashed
+	return nil
+}
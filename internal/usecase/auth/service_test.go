@@ -0,0 +1,99 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/password"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	usermocks "github.com/GarikMirzoyan/gophermart/internal/domain/user/mocks"
+)
+
+func TestService_Register(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creates user with hashed password", func(t *testing.T) {
+		repo := usermocks.NewRepository(t)
+		service := auth.New(repo, testHasher, user.DefaultPasswordPolicy)
+
+		repo.On("CreateUser", ctx, mock.MatchedBy(func(u *user.User) bool {
+			return u.Login == "alice" && u.Password != "correct-horse"
+		})).Return(&user.User{ID: 1, Login: "alice"}, nil)
+
+		u, err := service.Register(ctx, "alice", "correct-horse")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), u.ID)
+	})
+
+	t.Run("invalid input is rejected before touching storage", func(t *testing.T) {
+		repo := usermocks.NewRepository(t)
+		service := auth.New(repo, testHasher, user.DefaultPasswordPolicy)
+
+		_, err := service.Register(ctx, "a b", "short")
+		fields, ok := user.AsValidation(err)
+		require.True(t, ok)
+
+		codes := make([]string, 0, len(fields))
+		for _, f := range fields {
+			codes = append(codes, f.Field+":"+f.Code)
+		}
+		assert.ElementsMatch(t, []string{"login:invalid_characters", "password:too_short"}, codes)
+	})
+
+	t.Run("concurrent duplicate maps to ErrLoginTaken", func(t *testing.T) {
+		repo := usermocks.NewRepository(t)
+		service := auth.New(repo, testHasher, user.DefaultPasswordPolicy)
+
+		repo.On("CreateUser", ctx, mock.Anything).Return(nil, user.ErrLoginTaken)
+
+		_, err := service.Register(ctx, "alice", "correct-horse")
+		assert.ErrorIs(t, err, auth.ErrLoginTaken)
+	})
+}
+
+func TestService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("current hash is left as is", func(t *testing.T) {
+		repo := usermocks.NewRepository(t)
+		service := auth.New(repo, testHasher, user.DefaultPasswordPolicy)
+
+		repo.On("GetByLogin", ctx, "alice").Return(&user.User{ID: 1, Login: "alice", Password: hashPassword(t, "correct-horse")}, nil)
+
+		_, err := service.Authenticate(ctx, "alice", "correct-horse")
+		require.NoError(t, err)
+		repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("outdated hash is upgraded on login", func(t *testing.T) {
+		repo := usermocks.NewRepository(t)
+		hasher := password.NewArgon2id(password.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}, 0)
+		service := auth.New(repo, hasher, user.DefaultPasswordPolicy)
+
+		repo.On("GetByLogin", ctx, "alice").Return(&user.User{ID: 1, Login: "alice", Password: hashPassword(t, "correct-horse")}, nil)
+		repo.On("UpdatePassword", ctx, int64(1), mock.MatchedBy(func(encoded string) bool {
+			ok, needsRehash, err := hasher.Verify(encoded, "correct-horse")
+			return strings.HasPrefix(encoded, "$argon2id$") && ok && !needsRehash && err == nil
+		})).Return(nil)
+
+		u, err := service.Authenticate(ctx, "alice", "correct-horse")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(u.Password, "$argon2id$"))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		repo := usermocks.NewRepository(t)
+		service := auth.New(repo, testHasher, user.DefaultPasswordPolicy)
+
+		repo.On("GetByLogin", ctx, "alice").Return(&user.User{ID: 1, Login: "alice", Password: hashPassword(t, "correct-horse")}, nil)
+
+		_, err := service.Authenticate(ctx, "alice", "guess")
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	})
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/token"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+)
+
+// DefaultRefreshTTL — время жизни refresh-токена по умолчанию
+const DefaultRefreshTTL = 30 * 24 * time.Hour
+
+// AccessTokenIssuer выпускает короткоживущий токен доступа для сессии
+type AccessTokenIssuer interface {
+	Generate(userID int, sessionID, role string) (string, error)
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// TokenService управляет сессиями: выдаёт пары токенов, ротирует refresh-токены
+// и отзывает сессии. Повторное предъявление уже обменянного refresh-токена
+// считается утечкой, и вся сессия отзывается.
+type TokenService struct {
+	repo       token.Repository
+	users      user.Repository
+	issuer     AccessTokenIssuer
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewTokenService(repo token.Repository, users user.Repository, issuer AccessTokenIssuer, refreshTTL time.Duration) *TokenService {
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTTL
+	}
+	return &TokenService{repo: repo, users: users, issuer: issuer, refreshTTL: refreshTTL, now: time.Now}
+}
+
+// Issue открывает новую сессию для пользователя
+func (s *TokenService) Issue(ctx context.Context, userID int) (*TokenPair, error) {
+	u, err := s.activeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, err := token.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, rt, err := s.newRefreshToken(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, rt); err != nil {
+		return nil, err
+	}
+
+	return s.pair(u, familyID, secret)
+}
+
+// Refresh обменивает refresh-токен на новую пару. Старый токен после этого недействителен.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	current, err := s.repo.GetByHash(ctx, token.Hash(refreshToken))
+	if errors.Is(err, token.ErrTokenNotFound) {
+		return nil, token.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Spent() {
+		return nil, s.revokeReused(ctx, current)
+	}
+	if !current.Usable(s.now()) {
+		return nil, token.ErrInvalidToken
+	}
+
+	// Роль берётся из БД при каждой ротации, чтобы её изменение вступало в силу
+	// без повторного входа; заблокированный пользователь продлить сессию не может
+	u, err := s.activeUser(ctx, current.UserID)
+	if errors.Is(err, user.ErrAccountBlocked) {
+		if err := s.repo.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, token.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, next, err := s.newRefreshToken(current.UserID, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.Rotate(ctx, current.ID, next)
+	if errors.Is(err, token.ErrTokenReused) {
+		// Токен успели обменять параллельным запросом
+		return nil, s.revokeReused(ctx, current)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pair(u, current.FamilyID, secret)
+}
+
+// Logout отзывает сессию вместе со всеми её токенами
+func (s *TokenService) Logout(ctx context.Context, sessionID string) error {
+	return s.repo.RevokeFamily(ctx, sessionID)
+}
+
+// RevokeAll отзывает все сессии пользователя
+func (s *TokenService) RevokeAll(ctx context.Context, userID int) error {
+	return s.repo.RevokeUser(ctx, userID)
+}
+
+func (s *TokenService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s.repo.IsFamilyActive(ctx, sessionID)
+}
+
+func (s *TokenService) revokeReused(ctx context.Context, t *token.RefreshToken) error {
+	log.Printf("refresh token reuse detected for user %d, revoking session", t.UserID)
+	if err := s.repo.RevokeFamily(ctx, t.FamilyID); err != nil {
+		return err
+	}
+	return token.ErrTokenReused
+}
+
+func (s *TokenService) newRefreshToken(userID int, familyID string) (string, *token.RefreshToken, error) {
+	secret, err := token.NewSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	return secret, &token.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: token.Hash(secret),
+		ExpiresAt: s.now().Add(s.refreshTTL),
+	}, nil
+}
+
+func (s *TokenService) activeUser(ctx context.Context, userID int) (*user.User, error) {
+	u, err := s.users.GetByID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	if u.Blocked() {
+		return nil, user.ErrAccountBlocked
+	}
+	return u, nil
+}
+
+func (s *TokenService) pair(u *user.User, familyID, refreshToken string) (*TokenPair, error) {
+	access, err := s.issuer.Generate(int(u.ID), familyID, string(u.Role))
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refreshToken}, nil
+}
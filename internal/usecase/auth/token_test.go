@@ -0,0 +1,133 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/token"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/user"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	tokenmocks "github.com/GarikMirzoyan/gophermart/internal/domain/token/mocks"
+	usermocks "github.com/GarikMirzoyan/gophermart/internal/domain/user/mocks"
+)
+
+type stubIssuer struct{}
+
+func (stubIssuer) Generate(userID int, sessionID, role string) (string, error) {
+	return "access:" + role + ":" + sessionID, nil
+}
+
+// newTokenService собирает сервис с пользователем 7 и ролью user
+func newTokenService(t *testing.T) (*auth.TokenService, *tokenmocks.Repository, *usermocks.Repository) {
+	repo := tokenmocks.NewRepository(t)
+	users := usermocks.NewRepository(t)
+	users.On("GetByID", mock.Anything, int64(7)).
+		Return(&user.User{ID: 7, Login: "user", Role: user.RoleUser}, nil).Maybe()
+	return auth.NewTokenService(repo, users, stubIssuer{}, time.Hour), repo, users
+}
+
+func TestTokenService_Issue(t *testing.T) {
+	ctx := context.Background()
+	service, repo, _ := newTokenService(t)
+
+	var stored *token.RefreshToken
+	repo.On("Create", ctx, mock.AnythingOfType("*token.RefreshToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*token.RefreshToken) }).
+		Return(nil)
+
+	pair, err := service.Issue(ctx, 7)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+
+	assert.Equal(t, 7, stored.UserID)
+	assert.Equal(t, token.Hash(pair.RefreshToken), stored.TokenHash)
+	assert.NotEqual(t, pair.RefreshToken, stored.TokenHash)
+	assert.Equal(t, "access:user:"+stored.FamilyID, pair.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+}
+
+func TestTokenService_Refresh(t *testing.T) {
+	ctx := context.Background()
+	current := func() *token.RefreshToken {
+		return &token.RefreshToken{ID: 1, UserID: 7, FamilyID: "fam", ExpiresAt: time.Now().Add(time.Hour)}
+	}
+
+	t.Run("rotates token within the same session", func(t *testing.T) {
+		service, repo, _ := newTokenService(t)
+
+		repo.On("GetByHash", ctx, token.Hash("old")).Return(current(), nil)
+		repo.On("Rotate", ctx, int64(1), mock.MatchedBy(func(next *token.RefreshToken) bool {
+			return next.UserID == 7 && next.FamilyID == "fam"
+		})).Return(nil)
+
+		pair, err := service.Refresh(ctx, "old")
+		require.NoError(t, err)
+		assert.Equal(t, "access:user:fam", pair.AccessToken)
+		assert.NotEqual(t, "old", pair.RefreshToken)
+	})
+
+	t.Run("reused token revokes the whole session", func(t *testing.T) {
+		service, repo, _ := newTokenService(t)
+
+		spent := current()
+		rotatedAt := time.Now().Add(-time.Minute)
+		spent.RotatedAt = &rotatedAt
+		repo.On("GetByHash", ctx, token.Hash("old")).Return(spent, nil)
+		repo.On("RevokeFamily", ctx, "fam").Return(nil)
+
+		_, err := service.Refresh(ctx, "old")
+		assert.ErrorIs(t, err, token.ErrTokenReused)
+		repo.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent rotation revokes the whole session", func(t *testing.T) {
+		service, repo, _ := newTokenService(t)
+
+		repo.On("GetByHash", ctx, token.Hash("old")).Return(current(), nil)
+		repo.On("Rotate", ctx, int64(1), mock.Anything).Return(token.ErrTokenReused)
+		repo.On("RevokeFamily", ctx, "fam").Return(nil)
+
+		_, err := service.Refresh(ctx, "old")
+		assert.ErrorIs(t, err, token.ErrTokenReused)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		service, repo, _ := newTokenService(t)
+
+		expired := current()
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		repo.On("GetByHash", ctx, token.Hash("old")).Return(expired, nil)
+
+		_, err := service.Refresh(ctx, "old")
+		assert.ErrorIs(t, err, token.ErrInvalidToken)
+	})
+
+	t.Run("blocked user cannot extend the session", func(t *testing.T) {
+		repo := tokenmocks.NewRepository(t)
+		users := usermocks.NewRepository(t)
+		service := auth.NewTokenService(repo, users, stubIssuer{}, time.Hour)
+
+		blockedAt := time.Now().Add(-time.Minute)
+		repo.On("GetByHash", ctx, token.Hash("old")).Return(current(), nil)
+		users.On("GetByID", ctx, int64(7)).Return(&user.User{ID: 7, BlockedAt: &blockedAt}, nil)
+		repo.On("RevokeFamily", ctx, "fam").Return(nil)
+
+		_, err := service.Refresh(ctx, "old")
+		assert.ErrorIs(t, err, token.ErrInvalidToken)
+		repo.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		service, repo, _ := newTokenService(t)
+
+		repo.On("GetByHash", ctx, token.Hash("nope")).Return(nil, token.ErrTokenNotFound)
+
+		_, err := service.Refresh(ctx, "nope")
+		assert.ErrorIs(t, err, token.ErrInvalidToken)
+	})
+}
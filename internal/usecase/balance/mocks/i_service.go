@@ -6,6 +6,7 @@ import (
 	context "context"
 
 	domainbalance "github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	ledger "github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -14,22 +15,34 @@ type IService struct {
 	mock.Mock
 }
 
-// AddBalance provides a mock function with given fields: ctx, userID, amount
-func (_m *IService) AddBalance(ctx context.Context, userID int, amount float64) error {
-	ret := _m.Called(ctx, userID, amount)
+// Adjust provides a mock function with given fields: ctx, adj
+func (_m *IService) Adjust(ctx context.Context, adj *domainbalance.Adjustment) (*domainbalance.Balance, error) {
+	ret := _m.Called(ctx, adj)
 
 	if len(ret) == 0 {
-		panic("no return value specified for AddBalance")
+		panic("no return value specified for Adjust")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, float64) error); ok {
-		r0 = rf(ctx, userID, amount)
+	var r0 *domainbalance.Balance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domainbalance.Adjustment) (*domainbalance.Balance, error)); ok {
+		return rf(ctx, adj)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *domainbalance.Adjustment) *domainbalance.Balance); ok {
+		r0 = rf(ctx, adj)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domainbalance.Balance)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, *domainbalance.Adjustment) error); ok {
+		r1 = rf(ctx, adj)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetBalance provides a mock function with given fields: ctx, userID
@@ -62,6 +75,82 @@ func (_m *IService) GetBalance(ctx context.Context, userID int) (*domainbalance.
 	return r0, r1
 }
 
+// GetHistory provides a mock function with given fields: ctx, userID, filter
+func (_m *IService) GetHistory(ctx context.Context, userID int, filter ledger.HistoryFilter) (*ledger.HistoryPage, error) {
+	ret := _m.Called(ctx, userID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHistory")
+	}
+
+	var r0 *ledger.HistoryPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, ledger.HistoryFilter) (*ledger.HistoryPage, error)); ok {
+		return rf(ctx, userID, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, ledger.HistoryFilter) *ledger.HistoryPage); ok {
+		r0 = rf(ctx, userID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ledger.HistoryPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, ledger.HistoryFilter) error); ok {
+		r1 = rf(ctx, userID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reconcile provides a mock function with given fields: ctx, userID
+func (_m *IService) Reconcile(ctx context.Context, userID int) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reconcile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReconcileAll provides a mock function with given fields: ctx
+func (_m *IService) ReconcileAll(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReconcileAll")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewIService creates a new instance of IService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewIService(t interface {
@@ -2,27 +2,122 @@ package balance
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 
 	"github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
 )
 
 type IService interface {
 	GetBalance(ctx context.Context, userID int) (*balance.Balance, error)
-	AddBalance(ctx context.Context, userID int, amount float64) error
+	Adjust(ctx context.Context, adj *balance.Adjustment) (*balance.Balance, error)
+	Reconcile(ctx context.Context, userID int) error
+	ReconcileAll(ctx context.Context) (int, error)
+	GetHistory(ctx context.Context, userID int, filter ledger.HistoryFilter) (*ledger.HistoryPage, error)
 }
 
 type Service struct {
-	repo balance.Repository
+	repo       balance.Repository
+	ledgerRepo ledger.Repository
 }
 
-func New(repo balance.Repository) IService {
-	return &Service{repo: repo}
+func New(repo balance.Repository, ledgerRepo ledger.Repository) IService {
+	return &Service{repo: repo, ledgerRepo: ledgerRepo}
 }
 
+// GetBalance рассчитывает баланс по проводкам журнала
 func (s *Service) GetBalance(ctx context.Context, userID int) (*balance.Balance, error) {
-	return s.repo.GetByUserID(ctx, userID)
+	totals, err := s.ledgerRepo.GetUserTotals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &balance.Balance{
+		UserID:    userID,
+		Current:   totals.Current,
+		Withdrawn: totals.Withdrawn,
+	}, nil
+}
+
+// Adjust проводит ручную корректировку баланса оператором; запись аудита пишется
+// в той же транзакции. Корректировку проверяет вызывающий (admin.Service).
+func (s *Service) Adjust(ctx context.Context, adj *balance.Adjustment) (*balance.Balance, error) {
+	return s.repo.Adjust(ctx, adj)
+}
+
+// Reconcile сверяет остатки в user_balances с журналом проводок
+func (s *Service) Reconcile(ctx context.Context, userID int) error {
+	stored, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	derived, err := s.GetBalance(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if stored.Current != derived.Current || stored.Withdrawn != derived.Withdrawn {
+		return fmt.Errorf("%w: user %d stored current=%s withdrawn=%s, ledger current=%s withdrawn=%s",
+			balance.ErrBalanceMismatch, userID, stored.Current, stored.Withdrawn, derived.Current, derived.Withdrawn)
+	}
+	return nil
+}
+
+// ReconcileAll сверяет балансы всех пользователей с журналом и возвращает число расхождений.
+// Расхождение пишется в лог и не прерывает сверку остальных; прерывает её только ошибка чтения.
+func (s *Service) ReconcileAll(ctx context.Context) (int, error) {
+	ids, err := s.repo.ListUserIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	mismatched := 0
+	for _, id := range ids {
+		err := s.Reconcile(ctx, id)
+		if errors.Is(err, balance.ErrBalanceMismatch) {
+			log.Printf("WARNING: %v", err)
+			mismatched++
+			continue
+		}
+		if err != nil {
+			return mismatched, fmt.Errorf("reconcile balance of user %d: %w", id, err)
+		}
+	}
+	return mismatched, nil
 }
 
-func (s *Service) AddBalance(ctx context.Context, userID int, amount float64) error {
-	return s.repo.Add(ctx, userID, amount)
+// GetHistory возвращает страницу выписки по счёту пользователя. Без параметров выборки
+// отдаёт всю выписку, как списки заказов и списаний.
+func (s *Service) GetHistory(ctx context.Context, userID int, filter ledger.HistoryFilter) (*ledger.HistoryPage, error) {
+	unpaged := filter.Params.IsZero()
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if unpaged {
+		filter.Limit = 0
+		items, err := s.ledgerRepo.GetUserHistory(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		return &ledger.HistoryPage{Items: items}, nil
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	items, err := s.ledgerRepo.GetUserHistory(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ledger.HistoryPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		page.Next = &ledger.HistoryCursor{At: last.CreatedAt, ID: last.TransactionID}
+	}
+	return page, nil
 }
@@ -0,0 +1,163 @@
+package balance_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainbalance "github.com/GarikMirzoyan/gophermart/internal/domain/balance"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/ledger"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/balance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	balancerepomocks "github.com/GarikMirzoyan/gophermart/internal/domain/balance/mocks"
+	ledgermocks "github.com/GarikMirzoyan/gophermart/internal/domain/ledger/mocks"
+)
+
+func TestGetBalance_DerivedFromLedger(t *testing.T) {
+	ctx := context.Background()
+	repo := balancerepomocks.NewRepository(t)
+	ledgerRepo := ledgermocks.NewRepository(t)
+	service := balance.New(repo, ledgerRepo)
+
+	ledgerRepo.On("GetUserTotals", ctx, 1).Return(&ledger.Totals{Current: money.FromFloat(500.5), Withdrawn: money.FromFloat(42)}, nil)
+
+	bal, err := service.GetBalance(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, money.FromFloat(500.5), bal.Current)
+	assert.Equal(t, money.FromFloat(42), bal.Withdrawn)
+	repo.AssertNotCalled(t, "GetByUserID", ctx, 1)
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stored balance matches ledger", func(t *testing.T) {
+		repo := balancerepomocks.NewRepository(t)
+		ledgerRepo := ledgermocks.NewRepository(t)
+		service := balance.New(repo, ledgerRepo)
+
+		repo.On("GetByUserID", ctx, 1).Return(&domainbalance.Balance{Current: money.FromMinor(30), Withdrawn: money.FromMinor(1000)}, nil)
+		ledgerRepo.On("GetUserTotals", ctx, 1).Return(&ledger.Totals{Current: money.FromMinor(10) + money.FromMinor(20), Withdrawn: money.FromMinor(1000)}, nil)
+
+		assert.NoError(t, service.Reconcile(ctx, 1))
+	})
+
+	t.Run("stored balance drifted from ledger", func(t *testing.T) {
+		repo := balancerepomocks.NewRepository(t)
+		ledgerRepo := ledgermocks.NewRepository(t)
+		service := balance.New(repo, ledgerRepo)
+
+		repo.On("GetByUserID", ctx, 1).Return(&domainbalance.Balance{Current: money.FromFloat(100)}, nil)
+		ledgerRepo.On("GetUserTotals", ctx, 1).Return(&ledger.Totals{Current: money.FromFloat(90), Withdrawn: money.FromFloat(10)}, nil)
+
+		assert.ErrorIs(t, service.Reconcile(ctx, 1), domainbalance.ErrBalanceMismatch)
+	})
+}
+
+func TestReconcileAll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("mismatch is counted and does not stop the check", func(t *testing.T) {
+		repo := balancerepomocks.NewRepository(t)
+		ledgerRepo := ledgermocks.NewRepository(t)
+		service := balance.New(repo, ledgerRepo)
+
+		repo.On("ListUserIDs", ctx).Return([]int{1, 2}, nil)
+		repo.On("GetByUserID", ctx, 1).Return(&domainbalance.Balance{Current: money.FromFloat(100)}, nil)
+		ledgerRepo.On("GetUserTotals", ctx, 1).Return(&ledger.Totals{Current: money.FromFloat(90)}, nil)
+		repo.On("GetByUserID", ctx, 2).Return(&domainbalance.Balance{Current: money.FromFloat(5)}, nil)
+		ledgerRepo.On("GetUserTotals", ctx, 2).Return(&ledger.Totals{Current: money.FromFloat(5)}, nil)
+
+		mismatched, err := service.ReconcileAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, mismatched)
+	})
+
+	t.Run("read failure stops the check", func(t *testing.T) {
+		repo := balancerepomocks.NewRepository(t)
+		service := balance.New(repo, ledgermocks.NewRepository(t))
+
+		repo.On("ListUserIDs", ctx).Return([]int{1, 2}, nil)
+		repo.On("GetByUserID", ctx, 1).Return(nil, errors.New("db down"))
+
+		_, err := service.ReconcileAll(ctx)
+		assert.Error(t, err)
+	})
+}
+
+func TestGetHistory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	items := []*ledger.HistoryItem{
+		{TransactionID: 1, Kind: ledger.KindAccrual, Amount: money.FromFloat(100), Balance: money.FromFloat(100), CreatedAt: now},
+		{TransactionID: 2, Kind: ledger.KindWithdrawal, Amount: money.FromFloat(-30), Balance: money.FromFloat(70), CreatedAt: now.Add(time.Minute)},
+		{TransactionID: 3, Kind: ledger.KindAccrual, Amount: money.FromFloat(5), Balance: money.FromFloat(75), CreatedAt: now.Add(2 * time.Minute)},
+	}
+
+	t.Run("next cursor points at last returned item", func(t *testing.T) {
+		ledgerRepo := ledgermocks.NewRepository(t)
+		service := balance.New(balancerepomocks.NewRepository(t), ledgerRepo)
+
+		ledgerRepo.On("GetUserHistory", ctx, 1, ledger.HistoryFilter{Params: pagination.Params{Limit: 3}}).Return(items, nil)
+
+		page, err := service.GetHistory(ctx, 1, ledger.HistoryFilter{Params: pagination.Params{Limit: 2}})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 2)
+		require.NotNil(t, page.Next)
+		assert.Equal(t, int64(2), page.Next.ID)
+		assert.Equal(t, ledger.DirectionDebit, page.Items[1].Direction())
+	})
+
+	t.Run("last page has no cursor", func(t *testing.T) {
+		ledgerRepo := ledgermocks.NewRepository(t)
+		service := balance.New(balancerepomocks.NewRepository(t), ledgerRepo)
+
+		ledgerRepo.On("GetUserHistory", ctx, 1, ledger.HistoryFilter{Params: pagination.Params{From: now, Limit: ledger.DefaultHistoryLimit + 1}}).Return(items, nil)
+
+		page, err := service.GetHistory(ctx, 1, ledger.HistoryFilter{Params: pagination.Params{From: now}})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 3)
+		assert.Nil(t, page.Next)
+	})
+
+	t.Run("no page params returns whole history", func(t *testing.T) {
+		ledgerRepo := ledgermocks.NewRepository(t)
+		service := balance.New(balancerepomocks.NewRepository(t), ledgerRepo)
+
+		ledgerRepo.On("GetUserHistory", ctx, 1, ledger.HistoryFilter{Direction: ledger.DirectionCredit}).Return(items, nil)
+
+		page, err := service.GetHistory(ctx, 1, ledger.HistoryFilter{Direction: ledger.DirectionCredit})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 3)
+		assert.Nil(t, page.Next)
+	})
+
+	t.Run("unknown type rejected", func(t *testing.T) {
+		service := balance.New(balancerepomocks.NewRepository(t), ledgermocks.NewRepository(t))
+
+		_, err := service.GetHistory(ctx, 1, ledger.HistoryFilter{Direction: "refund"})
+		assert.ErrorIs(t, err, ledger.ErrInvalidFilter)
+	})
+}
+
+func TestAdjust(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("valid adjustment is passed to repository", func(t *testing.T) {
+		repo := balancerepomocks.NewRepository(t)
+		service := balance.New(repo, ledgermocks.NewRepository(t))
+
+		adj := &domainbalance.Adjustment{UserID: 7, OperatorID: 1, Amount: money.FromFloat(-15), Reason: "fraud"}
+		repo.On("Adjust", ctx, adj).Return(&domainbalance.Balance{UserID: 7, Current: money.FromFloat(5)}, nil)
+
+		bal, err := service.Adjust(ctx, adj)
+		require.NoError(t, err)
+		assert.Equal(t, money.FromFloat(5), bal.Current)
+	})
+}
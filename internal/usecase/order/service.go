@@ -3,13 +3,14 @@ package order
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"regexp"
 	"time"
 
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
 	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
-	"github.com/GarikMirzoyan/gophermart/internal/usecase/balance"
 )
 
 var ErrInvalidOrderNumber = errors.New("invalid order number")
@@ -19,11 +20,19 @@ var ErrOrderBelongsToAnotherUser = errors.New("order belongs to another user")
 type Service struct {
 	repo           order.Repository
 	loyaltyService *loyalty.Service
-	balanceService balance.IService
+	backoff        order.Backoff
+	clawback       order.ClawbackPolicy
+	events         order.Publisher
 }
 
-func New(repo order.Repository, loyaltyService *loyalty.Service, balanceService balance.IService) *Service {
-	return &Service{repo: repo, loyaltyService: loyaltyService, balanceService: balanceService}
+// New создаёт сервис заказов. Нулевой backoff означает задержки по умолчанию,
+// пустая политика списания — ClawbackNegativeBalance. Без events изменения статусов
+// только записываются в историю.
+func New(repo order.Repository, loyaltyService *loyalty.Service, backoff order.Backoff, clawback order.ClawbackPolicy, events order.Publisher) *Service {
+	if clawback == "" {
+		clawback = order.ClawbackNegativeBalance
+	}
+	return &Service{repo: repo, loyaltyService: loyaltyService, backoff: backoff, clawback: clawback, events: events}
 }
 
 // Луна для проверки номера заказа (цифры произвольной длины)
@@ -44,10 +53,15 @@ func ValidateLuhn(number string) bool {
 	return sum%10 == 0
 }
 
+var orderNumberPattern = regexp.MustCompile(`^\d+$`)
+
+func validOrderNumber(number string) bool {
+	return orderNumberPattern.MatchString(number) && ValidateLuhn(number)
+}
+
 func (s *Service) AddOrder(ctx context.Context, userID int, number string) error {
 	// Проверка номера
-	matched, _ := regexp.MatchString(`^\d+$`, number)
-	if !matched || !ValidateLuhn(number) {
+	if !validOrderNumber(number) {
 		return ErrInvalidOrderNumber
 	}
 
@@ -78,47 +92,237 @@ func (s *Service) AddOrder(ctx context.Context, userID int, number string) error
 	return nil
 }
 
+// AddOrders загружает пачку номеров и возвращает результат по каждому в порядке запроса.
+// Неверные номера не мешают принять остальные; повтор номера внутри пачки — duplicate.
+func (s *Service) AddOrders(ctx context.Context, userID int, numbers []string) ([]order.BatchItem, error) {
+	if len(numbers) == 0 {
+		return nil, order.ErrEmptyBatch
+	}
+	if len(numbers) > order.MaxBatchSize {
+		return nil, order.ErrBatchTooLarge
+	}
+
+	items := make([]order.BatchItem, len(numbers))
+	seen := make(map[string]bool, len(numbers))
+	var valid []string
+	for i, number := range numbers {
+		items[i].Number = number
+		switch {
+		case !validOrderNumber(number):
+			items[i].Outcome = order.OutcomeInvalid
+		case seen[number]:
+			items[i].Outcome = order.OutcomeDuplicate
+		default:
+			seen[number] = true
+			valid = append(valid, number)
+		}
+	}
+	if len(valid) == 0 {
+		return items, nil
+	}
+
+	result, err := s.repo.AddOrders(ctx, userID, valid, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := 0
+	for i := range items {
+		if items[i].Outcome != "" {
+			continue
+		}
+		number := items[i].Number
+		if result.Inserted[number] {
+			items[i].Outcome = order.OutcomeAccepted
+			accepted++
+			continue
+		}
+		ownerID, exists := result.Owners[number]
+		switch {
+		case !exists:
+			return nil, fmt.Errorf("order %s was neither inserted nor found", number)
+		case ownerID == userID:
+			items[i].Outcome = order.OutcomeDuplicate
+		default:
+			items[i].Outcome = order.OutcomeConflict
+		}
+	}
+
+	log.Printf("batch of %d orders from user %d: %d accepted", len(numbers), userID, accepted)
+	return items, nil
+}
+
 func (s *Service) GetOrdersByUser(ctx context.Context, userID int) ([]*order.Order, error) {
 	return s.repo.GetOrdersByUser(ctx, userID)
 }
 
-func (s *Service) ProcessPendingOrders(ctx context.Context) {
-	orders, err := s.repo.GetOrdersForProcessing(ctx)
+// ListOrders возвращает страницу заказов пользователя. Без параметров выборки отдаёт
+// все заказы (с учётом статусов и сортировки), как и список списаний.
+func (s *Service) ListOrders(ctx context.Context, userID int, filter order.ListFilter) (*order.Page, error) {
+	unpaged := filter.Params.IsZero()
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if unpaged {
+		filter.Limit = 0
+		orders, err := s.repo.ListOrdersByUser(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		return &order.Page{Items: orders}, nil
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	orders, err := s.repo.ListOrdersByUser(ctx, userID, filter)
 	if err != nil {
-		log.Printf("failed to fetch orders for processing: %v", err)
-		return
+		return nil, err
+	}
+
+	page := &order.Page{Items: orders}
+	if len(orders) > limit {
+		page.Items = orders[:limit]
+		last := page.Items[limit-1]
+		page.Next = &order.Cursor{At: last.UploadedAt, ID: last.ID}
+	}
+	return page, nil
+}
+
+// GetOrder возвращает заказ пользователя с историей обработки. Чужой заказ неотличим
+// от несуществующего: в обоих случаях ErrOrderNotFound, чтобы номера нельзя было перебирать.
+func (s *Service) GetOrder(ctx context.Context, userID int, number string) (*order.Details, error) {
+	o, err := s.repo.GetOrder(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if o.UserID != userID {
+		return nil, order.ErrOrderNotFound
+	}
+	history, err := s.repo.GetOrderEvents(ctx, number)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, o := range orders {
-		log.Printf("[ACCRUAL WORKER] Processing order %s for user %d", o.Number, o.UserID)
+	return &order.Details{Order: o, History: history}, nil
+}
 
-		accrual, err := s.loyaltyService.GetOrderAccrual(ctx, o.Number)
+// ReturnOrder отменяет заказ. Если по нему уже начислены баллы, они списываются;
+// потраченная часть по политике сервиса уводит баланс в минус или записывается в долг.
+// actorID — администратор для ручного возврата, 0 для webhook системы начислений.
+func (s *Service) ReturnOrder(ctx context.Context, number string, reason string, source order.EventSource, actorID int) (*order.ReturnResult, error) {
+	result, err := s.repo.ReturnOrder(ctx, &order.Return{
+		Number:  number,
+		Reason:  reason,
+		Source:  source,
+		ActorID: actorID,
+		Policy:  s.clawback,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("order %s returned by %s: debited=%s debt=%s", number, source, result.Debited, result.Debt)
+	s.publish(ctx, result.Event)
+	return result, nil
+}
+
+// EventsSince возвращает страницу событий по заказам пользователя после afterID —
+// того, что клиент пропустил, пока был отключён
+func (s *Service) EventsSince(ctx context.Context, userID int, afterID int64) ([]*order.Event, error) {
+	return s.repo.ListUserEvents(ctx, userID, afterID, order.ReplayPageSize)
+}
+
+// LateEvents возвращает события с id меньше lastID, зафиксированные, возможно, уже после него.
+// Клиент мог получить часть из них раньше и отбрасывает повторы по id.
+func (s *Service) LateEvents(ctx context.Context, userID int, lastID int64) ([]*order.Event, error) {
+	return s.repo.ListLateUserEvents(ctx, userID, lastID, order.ReplayLookback, order.MaxReplayEvents)
+}
+
+func (s *Service) publish(ctx context.Context, e *order.Event) {
+	if s.events != nil && e != nil {
+		s.events.Publish(ctx, e)
+	}
+}
+
+// ClaimOrders захватывает для экземпляра owner не более limit заказов, время опроса которых наступило
+func (s *Service) ClaimOrders(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error) {
+	return s.repo.ClaimOrdersForProcessing(ctx, owner, limit, lease)
+}
+
+// ExtendLease продлевает аренду заказа перед опросом. ErrLeaseLost означает,
+// что заказ уже забрал другой экземпляр и опрашивать его нельзя.
+func (s *Service) ExtendLease(ctx context.Context, o *order.Order, lease time.Duration) error {
+	return s.repo.ExtendLease(ctx, o.Number, o.ClaimedBy, lease)
+}
+
+// ReleaseOrder возвращает захваченный, но не обработанный заказ в очередь
+func (s *Service) ReleaseOrder(ctx context.Context, o *order.Order) error {
+	return s.repo.ReleaseOrder(ctx, o.Number, o.ClaimedBy)
+}
+
+// ProcessOrder опрашивает систему начислений по одному заказу. Если расчёт ещё
+// не завершён или произошла ошибка, следующий опрос откладывается с экспоненциальной задержкой.
+func (s *Service) ProcessOrder(ctx context.Context, o *order.Order) error {
+	log.Printf("[ACCRUAL WORKER] Processing order %s for user %d", o.Number, o.UserID)
+
+	accrual, err := s.loyaltyService.GetOrderAccrual(ctx, o.Number)
+	if errors.Is(err, loyalty.ErrRateLimited) {
+		// Заказ не виноват в ограничении: попытка не засчитывается,
+		// заказ остаётся в очереди до окончания паузы
+		return err
+	}
+	if err != nil {
+		s.scheduleRetry(ctx, o, o.Status)
+		return fmt.Errorf("failed to get accrual: %w", err)
+	}
+	if accrual == nil {
+		// Заказ ещё не зарегистрирован в системе начислений
+		s.scheduleRetry(ctx, o, o.Status)
+		return nil
+	}
+
+	log.Printf("[ACCRUAL WORKER] Got accrual for order %s: status=%s", o.Number, accrual.Status)
+
+	switch accrual.Status {
+	case loyalty.StatusProcessed:
+		var amount money.Amount
+		if accrual.Accrual != nil {
+			amount = *accrual.Accrual
+		}
+		// Статус заказа и баланс меняются в одной транзакции: при ошибке заказ
+		// останется в очереди и будет обработан при следующей попытке
+		event, err := s.repo.CreditAccrual(ctx, o.Number, o.UserID, o.ClaimedBy, amount)
+		if errors.Is(err, order.ErrAlreadyProcessed) {
+			log.Printf("[ACCRUAL WORKER] order %s already credited or claimed by another instance, skipping", o.Number)
+			return nil
+		}
 		if err != nil {
-			log.Printf("[ACCRUAL WORKER] failed to get accrual for order %s: %v", o.Number, err)
-			continue
+			s.scheduleRetry(ctx, o, o.Status)
+			return fmt.Errorf("failed to credit accrual: %w", err)
 		}
-		if accrual == nil {
-			log.Printf("[ACCRUAL WORKER] accrual for order %s is nil", o.Number)
-			continue
+		s.publish(ctx, event)
+	case loyalty.StatusInvalid:
+		event, err := s.repo.UpdateStatus(ctx, o.Number, o.ClaimedBy, string(order.StatusInvalid))
+		if err != nil {
+			s.scheduleRetry(ctx, o, o.Status)
+			return fmt.Errorf("failed to update status: %w", err)
 		}
+		s.publish(ctx, event)
+	default:
+		// REGISTERED и PROCESSING — расчёт ещё идёт
+		s.scheduleRetry(ctx, o, order.StatusProcessing)
+	}
 
-		log.Printf("[ACCRUAL WORKER] Got accrual for order %s: status=%s, accrual=%v", o.Number, accrual.Status, accrual.Accrual)
+	return nil
+}
 
-		if accrual.Status == loyalty.StatusProcessed && accrual.Accrual != nil {
-			err = s.repo.UpdateAccrual(ctx, o.Number, string(accrual.Status), *accrual.Accrual)
-			if err != nil {
-				log.Printf("failed to update accrual for order %s: %v", o.Number, err)
-				continue
-			}
-			err = s.balanceService.AddBalance(ctx, o.UserID, *accrual.Accrual)
-			if err != nil {
-				log.Printf("failed to update balance for user %d: %v", o.UserID, err)
-			}
-		} else {
-			err = s.repo.UpdateStatus(ctx, o.Number, string(accrual.Status))
-			if err != nil {
-				log.Printf("failed to update status for order %s: %v", o.Number, err)
-			}
-		}
+func (s *Service) scheduleRetry(ctx context.Context, o *order.Order, status order.Status) {
+	next := time.Now().Add(s.backoff.Delay(o.Attempts))
+	event, err := s.repo.ScheduleRetry(ctx, o.Number, o.ClaimedBy, string(status), next)
+	if err != nil {
+		log.Printf("[ACCRUAL WORKER] failed to schedule retry for order %s: %v", o.Number, err)
+		return
 	}
+	s.publish(ctx, event)
 }
@@ -2,11 +2,17 @@ package order_test
 
 import (
 	"context"
+	"errors"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
 	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
-	"github.com/GarikMirzoyan/gophermart/internal/usecase/balance"
 	orderUC "github.com/GarikMirzoyan/gophermart/internal/usecase/order"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -14,7 +20,6 @@ import (
 
 	orderrepomocks "github.com/GarikMirzoyan/gophermart/internal/domain/order/mocks"
 	loyaltymocks "github.com/GarikMirzoyan/gophermart/internal/loyalty/mocks"
-	balancemocks "github.com/GarikMirzoyan/gophermart/internal/usecase/balance/mocks"
 )
 
 // ===== MOCKS =====
@@ -33,30 +38,77 @@ func (m *MockRepo) AddOrder(ctx context.Context, o *order.Order) error {
 	return args.Error(0)
 }
 
+func (m *MockRepo) AddOrders(ctx context.Context, userID int, numbers []string, uploadedAt time.Time) (*order.BatchInsert, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) GetOrder(ctx context.Context, number string) (*order.Order, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) GetOrderEvents(ctx context.Context, number string) ([]*order.Event, error) {
+	return nil, nil
+}
+
 func (m *MockRepo) GetOrdersByUser(ctx context.Context, userID int) ([]*order.Order, error) {
 	return nil, nil
 }
 
-func (m *MockRepo) GetOrdersForProcessing(ctx context.Context) ([]*order.Order, error) {
+func (m *MockRepo) ListOrdersByUser(ctx context.Context, userID int, filter order.ListFilter) ([]*order.Order, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) ClaimOrdersForProcessing(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error) {
 	return nil, nil
 }
 
-func (m *MockRepo) UpdateAccrual(ctx context.Context, number string, status string, accrual float64) error {
+func (m *MockRepo) ExtendLease(ctx context.Context, number string, owner string, lease time.Duration) error {
 	return nil
 }
 
-func (m *MockRepo) UpdateStatus(ctx context.Context, number string, status string) error {
+func (m *MockRepo) ReleaseOrder(ctx context.Context, number string, owner string) error {
 	return nil
 }
 
+func (m *MockRepo) ScheduleRetry(ctx context.Context, number string, owner string, status string, nextAttemptAt time.Time) (*order.Event, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) CreditAccrual(ctx context.Context, number string, userID int, owner string, accrual money.Amount) (*order.Event, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) UpdateStatus(ctx context.Context, number string, owner string, status string) (*order.Event, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) ListUserEvents(ctx context.Context, userID int, afterID int64, limit int) ([]*order.Event, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) ListLateUserEvents(ctx context.Context, userID int, lastID int64, lookback time.Duration, limit int) ([]*order.Event, error) {
+	return nil, nil
+}
+
+func (m *MockRepo) ReturnOrder(ctx context.Context, ret *order.Return) (*order.ReturnResult, error) {
+	return nil, nil
+}
+
+type recordingPublisher struct {
+	events []*order.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e *order.Event) {
+	p.events = append(p.events, e)
+}
+
 // ===== TEST =====
 
 func TestAddOrder(t *testing.T) {
 	ctx := context.Background()
 	mockRepo := new(MockRepo)
 	loyaltySvc := &loyalty.Service{} // заглушка, не используется здесь
-	balanceSvc := &balance.Service{} // заглушка, не используется здесь
-	service := orderUC.New(mockRepo, loyaltySvc, balanceSvc)
+	service := orderUC.New(mockRepo, loyaltySvc, order.Backoff{}, order.ClawbackNegativeBalance, nil)
 
 	t.Run("invalid number format", func(t *testing.T) {
 		err := service.AddOrder(ctx, 1, "abc123")
@@ -97,10 +149,64 @@ func TestAddOrder(t *testing.T) {
 	})
 }
 
+func TestAddOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("per-number outcome in request order", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		mockRepo.On("AddOrders", ctx, 1, []string{"12345678903", "79927398713", "2377225624"}, mock.AnythingOfType("time.Time")).
+			Return(&order.BatchInsert{
+				Inserted: map[string]bool{"12345678903": true},
+				Owners:   map[string]int{"79927398713": 1, "2377225624": 2},
+			}, nil)
+
+		items, err := service.AddOrders(ctx, 1, []string{"12345678903", "123", "79927398713", "2377225624", "12345678903"})
+		require.NoError(t, err)
+		assert.Equal(t, []order.BatchItem{
+			{Number: "12345678903", Outcome: order.OutcomeAccepted},
+			{Number: "123", Outcome: order.OutcomeInvalid},
+			{Number: "79927398713", Outcome: order.OutcomeDuplicate},
+			{Number: "2377225624", Outcome: order.OutcomeConflict},
+			{Number: "12345678903", Outcome: order.OutcomeDuplicate},
+		}, items)
+	})
+
+	t.Run("number missing from repository result is an error", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		mockRepo.On("AddOrders", ctx, 1, []string{"12345678903"}, mock.AnythingOfType("time.Time")).
+			Return(&order.BatchInsert{}, nil)
+
+		_, err := service.AddOrders(ctx, 1, []string{"12345678903"})
+		assert.Error(t, err)
+	})
+
+	t.Run("only invalid numbers do not reach repository", func(t *testing.T) {
+		service := orderUC.New(orderrepomocks.NewRepository(t), nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		items, err := service.AddOrders(ctx, 1, []string{"abc"})
+		require.NoError(t, err)
+		assert.Equal(t, order.OutcomeInvalid, items[0].Outcome)
+	})
+
+	t.Run("batch size limits", func(t *testing.T) {
+		service := orderUC.New(orderrepomocks.NewRepository(t), nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		_, err := service.AddOrders(ctx, 1, nil)
+		assert.ErrorIs(t, err, order.ErrEmptyBatch)
+
+		_, err = service.AddOrders(ctx, 1, make([]string, order.MaxBatchSize+1))
+		assert.ErrorIs(t, err, order.ErrBatchTooLarge)
+	})
+}
+
 func TestGetOrdersByUser(t *testing.T) {
 	ctx := context.Background()
 	mockRepo := new(orderrepomocks.Repository)
-	service := orderUC.New(mockRepo, nil, nil)
+	service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
 
 	expected := []*order.Order{
 		{Number: "123", Status: "NEW", UserID: 1},
@@ -113,37 +219,336 @@ func TestGetOrdersByUser(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestProcessPendingOrders_ProcessedOrder(t *testing.T) {
+func TestGetOrder(t *testing.T) {
 	ctx := context.Background()
 
-	mockRepo := new(orderrepomocks.Repository)
-	mockBalance := new(balancemocks.IService)
-	mockLoyaltyClient := new(loyaltymocks.Client)
+	t.Run("owner gets order with history", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
 
-	loyaltySvc := loyalty.New(mockLoyaltyClient)
-	orderSvc := orderUC.New(mockRepo, loyaltySvc, mockBalance)
+		o := &order.Order{Number: "12345678903", Status: order.StatusProcessed, UserID: 1}
+		history := []*order.Event{{FromStatus: order.StatusNew, ToStatus: order.StatusProcessed, Source: order.SourceWorker}}
+		mockRepo.On("GetOrder", ctx, "12345678903").Return(o, nil)
+		mockRepo.On("GetOrderEvents", ctx, "12345678903").Return(history, nil)
+
+		details, err := service.GetOrder(ctx, 1, "12345678903")
+		require.NoError(t, err)
+		assert.Equal(t, o, details.Order)
+		assert.Equal(t, history, details.History)
+	})
+
+	t.Run("other user's order looks missing", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		mockRepo.On("GetOrder", ctx, "12345678903").Return(&order.Order{Number: "12345678903", UserID: 2}, nil)
+
+		_, err := service.GetOrder(ctx, 1, "12345678903")
+		assert.ErrorIs(t, err, order.ErrOrderNotFound)
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		mockRepo.On("GetOrder", ctx, "79927398713").Return(nil, order.ErrOrderNotFound)
+
+		_, err := service.GetOrder(ctx, 1, "79927398713")
+		assert.ErrorIs(t, err, order.ErrOrderNotFound)
+	})
+}
 
+func TestListOrders(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
 	orders := []*order.Order{
-		{Number: "12345678903", UserID: 1, Status: order.StatusNew},
+		{ID: 3, Number: "3", UploadedAt: now},
+		{ID: 2, Number: "2", UploadedAt: now.Add(-time.Minute)},
+		{ID: 1, Number: "1", UploadedAt: now.Add(-2 * time.Minute)},
 	}
 
-	accrualVal := 42.5
+	t.Run("extra row turns into next cursor", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		mockRepo.On("ListOrdersByUser", ctx, 1, order.ListFilter{Params: pagination.Params{Limit: 3}, Sort: order.SortDesc}).Return(orders, nil)
+
+		page, err := service.ListOrders(ctx, 1, order.ListFilter{Params: pagination.Params{Limit: 2}})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 2)
+		require.NotNil(t, page.Next)
+		assert.Equal(t, order.Cursor{At: orders[1].UploadedAt, ID: 2}, *page.Next)
+	})
+
+	t.Run("last page has no cursor", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		mockRepo.On("ListOrdersByUser", ctx, 1, mock.Anything).Return(orders, nil)
+
+		page, err := service.ListOrders(ctx, 1, order.ListFilter{Params: pagination.Params{Limit: 5}})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 3)
+		assert.Nil(t, page.Next)
+	})
+
+	t.Run("no page params returns all orders", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		statuses := []order.Status{order.StatusProcessed}
+		mockRepo.On("ListOrdersByUser", ctx, 1, order.ListFilter{Statuses: statuses, Sort: order.SortDesc}).Return(orders, nil)
+
+		page, err := service.ListOrders(ctx, 1, order.ListFilter{Statuses: statuses})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 3)
+		assert.Nil(t, page.Next)
+	})
+
+	t.Run("invalid filter does not reach repository", func(t *testing.T) {
+		mockRepo := orderrepomocks.NewRepository(t)
+		service := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+		_, err := service.ListOrders(ctx, 1, order.ListFilter{Statuses: []order.Status{"DONE"}})
+		assert.ErrorIs(t, err, order.ErrInvalidFilter)
+	})
+}
+
+func TestProcessOrder_ProcessedOrder(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := orderrepomocks.NewRepository(t)
+	mockLoyaltyClient := loyaltymocks.NewClient(t)
+
+	loyaltySvc := loyalty.New(mockLoyaltyClient)
+	orderSvc := orderUC.New(mockRepo, loyaltySvc, order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+	o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusNew, ClaimedBy: "test"}
+
+	accrualVal := money.FromFloat(42.5)
 	accrual := &loyalty.OrderAccrual{
 		Order:   "12345678903",
 		Status:  loyalty.StatusProcessed,
 		Accrual: &accrualVal,
 	}
 
-	mockRepo.On("GetOrdersForProcessing", mock.Anything).Return(orders, nil)
 	mockLoyaltyClient.On("GetAccrual", mock.Anything, "12345678903").Return(accrual, nil)
-	mockRepo.On("UpdateAccrual", mock.Anything, "12345678903", string(loyalty.StatusProcessed), accrualVal).Return(nil)
-	mockBalance.On("AddBalance", mock.Anything, 1, accrualVal).Return(nil)
+	mockRepo.On("CreditAccrual", mock.Anything, "12345678903", 1, "test", accrualVal).Return(nil, nil)
+
+	assert.NoError(t, orderSvc.ProcessOrder(ctx, o))
+}
 
-	orderSvc.ProcessPendingOrders(ctx)
+func TestProcessOrder_CreditFailureRetriedOnNextPass(t *testing.T) {
+	ctx := context.Background()
 
-	mockRepo.AssertExpectations(t)
-	mockBalance.AssertExpectations(t)
-	mockLoyaltyClient.AssertExpectations(t)
+	mockRepo := orderrepomocks.NewRepository(t)
+	mockLoyaltyClient := loyaltymocks.NewClient(t)
+	orderSvc := orderUC.New(mockRepo, loyalty.New(mockLoyaltyClient), order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+	accrualVal := money.FromFloat(100)
+	o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusProcessing, ClaimedBy: "test"}
+	mockLoyaltyClient.On("GetAccrual", mock.Anything, "12345678903").
+		Return(&loyalty.OrderAccrual{Order: "12345678903", Status: loyalty.StatusProcessed, Accrual: &accrualVal}, nil)
+
+	// Первый проход: транзакция откатилась между обновлением заказа и начислением —
+	// заказ остаётся в очереди со следующей попыткой по backoff
+	mockRepo.On("CreditAccrual", mock.Anything, "12345678903", 1, "test", accrualVal).
+		Return(nil, errors.New("balance insert failed")).Once()
+	mockRepo.On("ScheduleRetry", mock.Anything, "12345678903", "test", string(order.StatusProcessing), mock.AnythingOfType("time.Time")).
+		Return(nil, nil).Once()
+	assert.Error(t, orderSvc.ProcessOrder(ctx, o))
+
+	// Второй проход, когда пул снова захватил заказ: начисление проходит
+	mockRepo.On("CreditAccrual", mock.Anything, "12345678903", 1, "test", accrualVal).Return(nil, nil).Once()
+	assert.NoError(t, orderSvc.ProcessOrder(ctx, o))
+
+	mockRepo.AssertNumberOfCalls(t, "CreditAccrual", 2)
+	mockRepo.AssertNumberOfCalls(t, "ScheduleRetry", 1)
+	mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessOrder_AlreadyCreditedIsSkipped(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := orderrepomocks.NewRepository(t)
+	mockLoyaltyClient := loyaltymocks.NewClient(t)
+	orderSvc := orderUC.New(mockRepo, loyalty.New(mockLoyaltyClient), order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+	accrualVal := money.FromFloat(10)
+	o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusProcessing, ClaimedBy: "test"}
+	mockLoyaltyClient.On("GetAccrual", mock.Anything, "12345678903").
+		Return(&loyalty.OrderAccrual{Order: "12345678903", Status: loyalty.StatusProcessed, Accrual: &accrualVal}, nil)
+	mockRepo.On("CreditAccrual", mock.Anything, "12345678903", 1, "test", accrualVal).Return(nil, order.ErrAlreadyProcessed)
 
-	require.True(t, true)
+	assert.NoError(t, orderSvc.ProcessOrder(ctx, o))
+
+	mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "ScheduleRetry", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessOrder_NotFinalStatusIsRescheduled(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := orderrepomocks.NewRepository(t)
+	mockLoyaltyClient := loyaltymocks.NewClient(t)
+	backoff := order.Backoff{Base: time.Minute, Max: time.Hour}
+	orderSvc := orderUC.New(mockRepo, loyalty.New(mockLoyaltyClient), backoff, order.ClawbackNegativeBalance, nil)
+
+	o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusNew, Attempts: 2}
+	mockLoyaltyClient.On("GetAccrual", mock.Anything, o.Number).
+		Return(&loyalty.OrderAccrual{Order: o.Number, Status: loyalty.StatusRegistered}, nil)
+
+	before := time.Now()
+	mockRepo.On("ScheduleRetry", mock.Anything, o.Number, o.ClaimedBy, string(order.StatusProcessing), mock.MatchedBy(func(next time.Time) bool {
+		// третья попытка: Base * 2^2
+		return !next.Before(before.Add(4*time.Minute)) && next.Before(time.Now().Add(4*time.Minute+time.Second))
+	})).Return(nil, nil).Once()
+
+	assert.NoError(t, orderSvc.ProcessOrder(ctx, o))
+}
+
+func TestProcessOrder_UnknownOrderKeepsStatus(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := orderrepomocks.NewRepository(t)
+	mockLoyaltyClient := loyaltymocks.NewClient(t)
+	orderSvc := orderUC.New(mockRepo, loyalty.New(mockLoyaltyClient), order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+	o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusNew}
+	mockLoyaltyClient.On("GetAccrual", mock.Anything, o.Number).Return(nil, nil)
+	mockRepo.On("ScheduleRetry", mock.Anything, o.Number, o.ClaimedBy, string(order.StatusNew), mock.AnythingOfType("time.Time")).Return(nil, nil).Once()
+
+	assert.NoError(t, orderSvc.ProcessOrder(ctx, o))
+}
+
+func TestProcessOrder_RateLimitDoesNotCountAttempt(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := orderrepomocks.NewRepository(t)
+	mockLoyaltyClient := loyaltymocks.NewClient(t)
+	orderSvc := orderUC.New(mockRepo, loyalty.New(mockLoyaltyClient), order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+	o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusNew}
+	mockLoyaltyClient.On("GetAccrual", mock.Anything, o.Number).
+		Return(nil, &loyalty.RateLimitError{RetryAfter: time.Minute}).Once()
+
+	err := orderSvc.ProcessOrder(ctx, o)
+	assert.ErrorIs(t, err, loyalty.ErrRateLimited)
+	mockRepo.AssertNotCalled(t, "ScheduleRetry", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReturnOrder_UsesConfiguredPolicy(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := orderrepomocks.NewRepository(t)
+	orderSvc := orderUC.New(mockRepo, nil, order.Backoff{}, order.ClawbackDebt, nil)
+
+	mockRepo.On("ReturnOrder", ctx, &order.Return{
+		Number:  "12345678903",
+		Reason:  "cancelled",
+		Source:  order.SourceAdmin,
+		ActorID: 9,
+		Policy:  order.ClawbackDebt,
+	}).Return(&order.ReturnResult{UserID: 1, Debited: money.FromFloat(40), Debt: money.FromFloat(60)}, nil)
+
+	result, err := orderSvc.ReturnOrder(ctx, "12345678903", "cancelled", order.SourceAdmin, 9)
+	require.NoError(t, err)
+	assert.Equal(t, money.FromFloat(60), result.Debt)
+}
+
+func TestProcessOrder_PublishesStatusChanges(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := orderrepomocks.NewRepository(t)
+	mockLoyaltyClient := loyaltymocks.NewClient(t)
+	publisher := &recordingPublisher{}
+	orderSvc := orderUC.New(mockRepo, loyalty.New(mockLoyaltyClient), order.Backoff{}, order.ClawbackNegativeBalance, publisher)
+
+	accrualVal := money.FromFloat(100)
+	o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusProcessing}
+	credited := &order.Event{ID: 7, OrderNumber: o.Number, UserID: 1, ToStatus: order.StatusProcessed, Accrual: &accrualVal}
+
+	mockLoyaltyClient.On("GetAccrual", mock.Anything, o.Number).
+		Return(&loyalty.OrderAccrual{Order: o.Number, Status: loyalty.StatusProcessed, Accrual: &accrualVal}, nil).Once()
+	mockRepo.On("CreditAccrual", mock.Anything, o.Number, 1, o.ClaimedBy, accrualVal).Return(credited, nil).Once()
+	require.NoError(t, orderSvc.ProcessOrder(ctx, o))
+
+	// Повторный опрос без смены статуса ничего не публикует
+	mockLoyaltyClient.On("GetAccrual", mock.Anything, o.Number).
+		Return(&loyalty.OrderAccrual{Order: o.Number, Status: loyalty.StatusProcessing}, nil).Once()
+	mockRepo.On("ScheduleRetry", mock.Anything, o.Number, o.ClaimedBy, string(order.StatusProcessing), mock.AnythingOfType("time.Time")).Return(nil, nil).Once()
+	require.NoError(t, orderSvc.ProcessOrder(ctx, o))
+
+	assert.Equal(t, []*order.Event{credited}, publisher.events)
+}
+
+// Обработка заказа на настоящем OrderPG: переход статуса берётся из RETURNING prev.status
+// и попадает в историю и в поток событий
+func TestProcessOrder_RecordsTransitions(t *testing.T) {
+	zero := money.Amount(0)
+	tests := []struct {
+		name    string
+		accrual *loyalty.OrderAccrual
+		expect  func(m sqlmock.Sqlmock)
+		to      order.Status
+	}{
+		{
+			name:    "registered order is rescheduled as processing",
+			accrual: &loyalty.OrderAccrual{Order: "12345678903", Status: loyalty.StatusRegistered},
+			expect: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta(`SET status = $1, attempts = o.attempts + 1, next_attempt_at = $2`)).
+					WithArgs("PROCESSING", sqlmock.AnyArg(), "12345678903", "RETURNED", "instance-a").
+					WillReturnRows(sqlmock.NewRows([]string{"status", "user_id"}).AddRow("NEW", 1))
+			},
+			to: order.StatusProcessing,
+		},
+		{
+			name:    "invalid order is closed",
+			accrual: &loyalty.OrderAccrual{Order: "12345678903", Status: loyalty.StatusInvalid},
+			expect: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta(`SET status = $1, claimed_by = NULL, lease_until = NULL`)).
+					WithArgs("INVALID", "12345678903", "RETURNED", "instance-a").
+					WillReturnRows(sqlmock.NewRows([]string{"status", "user_id"}).AddRow("NEW", 1))
+			},
+			to: order.StatusInvalid,
+		},
+		{
+			name:    "processed order without accrual is closed",
+			accrual: &loyalty.OrderAccrual{Order: "12345678903", Status: loyalty.StatusProcessed, Accrual: &zero},
+			expect: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta(`SET status = $1, accrual = $2, claimed_by = NULL, lease_until = NULL`)).
+					WithArgs("PROCESSED", zero, "12345678903", 1, "NEW", "PROCESSING", "instance-a").
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("NEW"))
+			},
+			to: order.StatusProcessed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, m, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			client := loyaltymocks.NewClient(t)
+			client.On("GetAccrual", mock.Anything, "12345678903").Return(tt.accrual, nil)
+			events := &recordingPublisher{}
+			service := orderUC.New(storage.NewOrderPG(db), loyalty.New(client), order.Backoff{Base: time.Second, Max: time.Minute}, "", events)
+
+			m.ExpectBegin()
+			tt.expect(m)
+			m.ExpectQuery(regexp.QuoteMeta(`INSERT INTO order_events`)).
+				WithArgs("12345678903", "NEW", string(tt.to), string(order.SourceWorker), nil, "").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+			m.ExpectCommit()
+
+			o := &order.Order{Number: "12345678903", UserID: 1, Status: order.StatusNew, ClaimedBy: "instance-a"}
+			require.NoError(t, service.ProcessOrder(context.Background(), o))
+
+			require.NoError(t, m.ExpectationsWereMet())
+			require.Len(t, events.events, 1)
+			assert.Equal(t, int64(7), events.events[0].ID)
+			assert.Equal(t, order.StatusNew, events.events[0].FromStatus)
+			assert.Equal(t, tt.to, events.events[0].ToStatus)
+			assert.Equal(t, 1, events.events[0].UserID)
+		})
+	}
 }
@@ -0,0 +1,135 @@
+package throttle
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+)
+
+// Service ограничивает подбор пароля: неудачные попытки считаются отдельно
+// по логину и по IP-адресу, и каждая из них может замедлить или заблокировать вход.
+type Service struct {
+	store         throttle.Store
+	loginPolicy   throttle.Policy
+	ipPolicy      throttle.Policy
+	resetPolicy   throttle.Policy
+	resetIPPolicy throttle.Policy
+	now           func() time.Time
+}
+
+type Config struct {
+	LoginPolicy throttle.Policy
+	IPPolicy    throttle.Policy
+	// ResetPolicy ограничивает запросы сброса пароля по логину
+	ResetPolicy throttle.Policy
+	// ResetIPPolicy ограничивает запросы сброса пароля по IP-адресу
+	ResetIPPolicy throttle.Policy
+	// Now — источник времени; по умолчанию time.Now
+	Now func() time.Time
+}
+
+func New(store throttle.Store, cfg Config) *Service {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Service{
+		store:         store,
+		loginPolicy:   cfg.LoginPolicy,
+		ipPolicy:      cfg.IPPolicy,
+		resetPolicy:   cfg.ResetPolicy,
+		resetIPPolicy: cfg.ResetIPPolicy,
+		now:           cfg.Now,
+	}
+}
+
+// Attempt учитывает попытку входа до проверки пароля: параллельные запросы
+// проверяются по очереди и не проходят по одному и тому же счётчику. Если попытка
+// сейчас запрещена, возвращает *throttle.ThrottledError и ничего не учитывает.
+func (s *Service) Attempt(ctx context.Context, login, ip string) error {
+	return s.reserve(ctx, s.counters(login, ip))
+}
+
+// AttemptReset учитывает запрос сброса пароля по логину и IP-адресу так же, как Attempt.
+// Счётчики отдельные от входа, поэтому частые запросы сброса с адреса не блокируют с него вход.
+func (s *Service) AttemptReset(ctx context.Context, login, ip string) error {
+	counters := []counter{{key: throttle.ResetKey(login), policy: s.resetPolicy}}
+	if ip != "" {
+		counters = append(counters, counter{key: throttle.ResetIPKey(ip), policy: s.resetIPPolicy})
+	}
+	return s.reserve(ctx, counters)
+}
+
+func (s *Service) reserve(ctx context.Context, counters []counter) error {
+	now := s.now()
+	var reserved []counter
+	for _, c := range counters {
+		res, err := s.store.Reserve(ctx, c.key, c.policy, now)
+		if err != nil {
+			return err
+		}
+		if res.RetryAfter > 0 {
+			// Попытка не состоится: уже учтённое по другим ключам возвращается
+			for _, r := range reserved {
+				if err := s.store.Refund(ctx, r.key); err != nil {
+					log.Printf("login throttling: failed to refund %s: %v", r.key, err)
+				}
+			}
+			return &throttle.ThrottledError{RetryAfter: res.RetryAfter}
+		}
+		reserved = append(reserved, c)
+		if !res.Locked {
+			continue
+		}
+
+		if err := s.store.RecordLockout(ctx, throttle.LockoutEvent{
+			Key:         c.key,
+			Failures:    res.Attempts.Failures,
+			LockedUntil: res.Attempts.LockedUntil,
+			CreatedAt:   now,
+		}); err != nil {
+			return err
+		}
+		log.Printf("login throttling: %s locked until %s after %d failures", c.key, res.Attempts.LockedUntil.Format(time.RFC3339), res.Attempts.Failures)
+	}
+	return nil
+}
+
+// Succeed сбрасывает счётчик логина после успешного входа. По IP возвращается только
+// сама успешная попытка: иначе перебор с одного адреса можно было бы обнулять входом
+// в свой аккаунт.
+func (s *Service) Succeed(ctx context.Context, login, ip string) error {
+	if err := s.store.Reset(ctx, throttle.LoginKey(login)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return s.store.Refund(ctx, throttle.IPKey(ip))
+}
+
+// Purge удаляет счётчики, окно которых закончилось, а блокировка истекла
+func (s *Service) Purge(ctx context.Context) (int64, error) {
+	window := s.loginPolicy.Window
+	for _, p := range []throttle.Policy{s.ipPolicy, s.resetPolicy, s.resetIPPolicy} {
+		if p.Window > window {
+			window = p.Window
+		}
+	}
+	now := s.now()
+	return s.store.Purge(ctx, now.Add(-window), now)
+}
+
+type counter struct {
+	key    string
+	policy throttle.Policy
+}
+
+func (s *Service) counters(login, ip string) []counter {
+	counters := []counter{{key: throttle.LoginKey(login), policy: s.loginPolicy}}
+	if ip != "" {
+		counters = append(counters, counter{key: throttle.IPKey(ip), policy: s.ipPolicy})
+	}
+	return counters
+}
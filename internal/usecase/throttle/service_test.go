@@ -0,0 +1,216 @@
+package throttle_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/throttle"
+	"github.com/GarikMirzoyan/gophermart/internal/infrastructure/storage"
+	throttleusecase "github.com/GarikMirzoyan/gophermart/internal/usecase/throttle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+var testPolicy = throttle.Policy{
+	Window:           10 * time.Minute,
+	FreeAttempts:     2,
+	BaseDelay:        time.Second,
+	MaxDelay:         8 * time.Second,
+	LockoutThreshold: 6,
+	LockoutDuration:  5 * time.Minute,
+}
+
+func newService(store throttle.Store, clock *fakeClock) *throttleusecase.Service {
+	return throttleusecase.New(store, throttleusecase.Config{
+		LoginPolicy: testPolicy,
+		IPPolicy:    throttle.Policy{Window: 10 * time.Minute, FreeAttempts: 100},
+		Now:         clock.Now,
+	})
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	d, ok := throttle.AsThrottled(err)
+	require.True(t, ok, "expected throttled error, got %v", err)
+	return d
+}
+
+func TestService_ProgressiveDelay(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	service := newService(storage.NewThrottleMemory(), clock)
+
+	// Первые попытки и ещё одна после них проходят без задержки
+	for i := 0; i <= testPolicy.FreeAttempts; i++ {
+		require.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+	}
+
+	// Дальше задержка удваивается; отклонённая попытка её не увеличивает
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		assert.Equal(t, want, retryAfter(t, service.Attempt(ctx, "alice", "10.0.0.1")))
+		assert.Equal(t, want, retryAfter(t, service.Attempt(ctx, "alice", "10.0.0.1")))
+
+		clock.Advance(want)
+		require.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+	}
+
+	// Другой логин с того же адреса не затронут
+	assert.NoError(t, service.Attempt(ctx, "bob", "10.0.0.1"))
+}
+
+func TestService_ConcurrentAttempts(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	service := newService(storage.NewThrottleMemory(), clock)
+
+	// Одновременные попытки не проходят проверку по одному и тому же счётчику
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if service.Attempt(ctx, "alice", "10.0.0.1") == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(testPolicy.FreeAttempts+1), allowed.Load())
+}
+
+func TestService_Lockout(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	store := storage.NewThrottleMemory()
+	service := newService(store, clock)
+
+	for i := 0; i < testPolicy.LockoutThreshold; i++ {
+		require.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+		clock.Advance(testPolicy.MaxDelay)
+	}
+
+	assert.Equal(t, testPolicy.LockoutDuration-testPolicy.MaxDelay, retryAfter(t, service.Attempt(ctx, "alice", "10.0.0.1")))
+
+	lockouts := store.Lockouts()
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, throttle.LoginKey("alice"), lockouts[0].Key)
+	assert.Equal(t, testPolicy.LockoutThreshold, lockouts[0].Failures)
+
+	clock.Advance(testPolicy.LockoutDuration)
+	assert.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+}
+
+func TestService_WindowExpires(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	service := newService(storage.NewThrottleMemory(), clock)
+
+	for i := 0; i < testPolicy.FreeAttempts+1; i++ {
+		require.NoError(t, service.Attempt(ctx, "alice", ""))
+	}
+	require.Error(t, service.Attempt(ctx, "alice", ""))
+
+	clock.Advance(testPolicy.Window + time.Second)
+	require.NoError(t, service.Attempt(ctx, "alice", ""))
+
+	// После окна счётчик начинается заново
+	assert.NoError(t, service.Attempt(ctx, "alice", ""))
+}
+
+func TestService_SucceedResetsLoginOnly(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	store := storage.NewThrottleMemory()
+	service := throttleusecase.New(store, throttleusecase.Config{
+		LoginPolicy: testPolicy,
+		IPPolicy:    testPolicy,
+		Now:         clock.Now,
+	})
+
+	for i := 0; i < testPolicy.FreeAttempts+1; i++ {
+		require.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+	}
+	clock.Advance(time.Second)
+	require.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, service.Succeed(ctx, "alice", "10.0.0.1"))
+
+	a, err := store.Get(ctx, throttle.LoginKey("alice"))
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	// Ограничение по адресу продолжает действовать: возвращена только успешная попытка
+	assert.Equal(t, time.Second, retryAfter(t, service.Attempt(ctx, "alice", "10.0.0.1")))
+}
+
+func TestService_Purge(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	store := storage.NewThrottleMemory()
+	service := newService(store, clock)
+
+	require.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+	n, err := service.Purge(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	clock.Advance(testPolicy.Window + time.Second)
+	n, err = service.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestService_AttemptReset(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	service := throttleusecase.New(storage.NewThrottleMemory(), throttleusecase.Config{
+		LoginPolicy: testPolicy,
+		IPPolicy:    throttle.Policy{Window: 10 * time.Minute, FreeAttempts: 100},
+		ResetPolicy: throttle.Policy{Window: time.Hour, FreeAttempts: 1, BaseDelay: time.Minute},
+		Now:         clock.Now,
+	})
+
+	require.NoError(t, service.AttemptReset(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, service.AttemptReset(ctx, "alice", "10.0.0.1"))
+	assert.Equal(t, time.Minute, retryAfter(t, service.AttemptReset(ctx, "alice", "10.0.0.1")))
+
+	// Запросы сброса не мешают входу
+	assert.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+}
+
+func TestService_AttemptResetFromOneIP(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)}
+	service := throttleusecase.New(storage.NewThrottleMemory(), throttleusecase.Config{
+		LoginPolicy:   testPolicy,
+		IPPolicy:      throttle.Policy{Window: 10 * time.Minute, FreeAttempts: 1, LockoutThreshold: 3, LockoutDuration: time.Hour},
+		ResetPolicy:   throttle.Policy{Window: time.Hour, FreeAttempts: 100},
+		ResetIPPolicy: throttle.Policy{Window: time.Hour, FreeAttempts: 2, BaseDelay: time.Minute},
+		Now:           clock.Now,
+	})
+
+	// Перебор логинов с одного адреса упирается в счётчик сброса по адресу
+	for _, login := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, service.AttemptReset(ctx, login, "10.0.0.1"))
+	}
+	assert.Equal(t, time.Minute, retryAfter(t, service.AttemptReset(ctx, "dave", "10.0.0.1")))
+
+	// Счётчик входа по этому адресу не тронут
+	assert.NoError(t, service.Attempt(ctx, "alice", "10.0.0.1"))
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
 
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
 	"github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
 	"github.com/GarikMirzoyan/gophermart/internal/usecase/order"
 )
@@ -17,18 +19,24 @@ func New(repo withdrawal.Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) Withdraw(ctx context.Context, userID int, orderNumber string, sum float64) error {
+func (s *Service) Withdraw(ctx context.Context, userID int, orderNumber string, sum money.Amount) error {
 	// Валидация номера заказа
 	if !order.ValidateLuhn(orderNumber) {
 		return withdrawal.ErrInvalidOrderNumber
 	}
+	if !sum.IsPositive() {
+		return withdrawal.ErrInvalidSum
+	}
 
 	err := s.repo.Withdraw(ctx, userID, orderNumber, sum)
 	if err != nil {
 		if errors.Is(err, withdrawal.ErrInsufficientFunds) {
 			return withdrawal.ErrInsufficientFunds
 		}
-		log.Printf("Withdraw failed: userID=%d order=%s sum=%.2f error=%v", userID, orderNumber, sum, err)
+		if errors.Is(err, withdrawal.ErrDuplicateOrder) {
+			return withdrawal.ErrDuplicateOrder
+		}
+		log.Printf("Withdraw failed: userID=%d order=%s sum=%s error=%v", userID, orderNumber, sum, err)
 		return withdrawal.ErrWithdrawSaveFailed
 	}
 
@@ -38,3 +46,45 @@ func (s *Service) Withdraw(ctx context.Context, userID int, orderNumber string,
 func (s *Service) GetUserWithdrawals(ctx context.Context, userID int) ([]*withdrawal.Withdrawal, error) {
 	return s.repo.GetUserWithdrawals(ctx, userID)
 }
+
+// ListWithdrawals возвращает страницу списаний пользователя. Без параметров выборки
+// отдаёт весь список одной страницей, как до появления пагинации.
+func (s *Service) ListWithdrawals(ctx context.Context, userID int, filter withdrawal.ListFilter) (*withdrawal.Page, error) {
+	if filter.Params.IsZero() {
+		items, err := s.repo.GetUserWithdrawals(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &withdrawal.Page{Items: items}, nil
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	items, err := s.repo.ListUserWithdrawals(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &withdrawal.Page{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		page.Next = &withdrawal.Cursor{At: last.ProcessedAt, ID: last.ID}
+	}
+	return page, nil
+}
+
+// Reverse отменяет списание по заказу: баллы возвращаются на счёт, а списание
+// остаётся в истории со статусом REVERSED. Отмена записывается в журнал аудита от имени actorID.
+func (s *Service) Reverse(ctx context.Context, orderNumber string, reason string, actorID int) (*withdrawal.Withdrawal, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, withdrawal.ErrReasonRequired
+	}
+	return s.repo.Reverse(ctx, orderNumber, reason, actorID)
+}
@@ -0,0 +1,94 @@
+package withdrawal_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/pagination"
+	domainwithdrawal "github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal"
+	"github.com/GarikMirzoyan/gophermart/internal/usecase/withdrawal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	withdrawalmocks "github.com/GarikMirzoyan/gophermart/internal/domain/withdrawal/mocks"
+)
+
+func TestReverse(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reverses withdrawal", func(t *testing.T) {
+		repo := withdrawalmocks.NewRepository(t)
+		service := withdrawal.New(repo)
+
+		repo.On("Reverse", ctx, "2377225624", "order cancelled", 1).Return(&domainwithdrawal.Withdrawal{
+			Order:  "2377225624",
+			Sum:    money.FromFloat(100),
+			Status: domainwithdrawal.StatusReversed,
+		}, nil)
+
+		w, err := service.Reverse(ctx, "2377225624", " order cancelled ", 1)
+		require.NoError(t, err)
+		assert.Equal(t, domainwithdrawal.StatusReversed, w.Status)
+	})
+
+	t.Run("reason is required", func(t *testing.T) {
+		service := withdrawal.New(withdrawalmocks.NewRepository(t))
+
+		_, err := service.Reverse(ctx, "2377225624", "", 1)
+		assert.ErrorIs(t, err, domainwithdrawal.ErrReasonRequired)
+	})
+
+	t.Run("second reversal rejected", func(t *testing.T) {
+		repo := withdrawalmocks.NewRepository(t)
+		service := withdrawal.New(repo)
+
+		repo.On("Reverse", ctx, "2377225624", "again", 1).Return(nil, domainwithdrawal.ErrAlreadyReversed)
+
+		_, err := service.Reverse(ctx, "2377225624", "again", 1)
+		assert.ErrorIs(t, err, domainwithdrawal.ErrAlreadyReversed)
+	})
+}
+
+func TestWithdraw_DuplicateOrder(t *testing.T) {
+	ctx := context.Background()
+	repo := withdrawalmocks.NewRepository(t)
+	service := withdrawal.New(repo)
+
+	repo.On("Withdraw", ctx, 7, "2377225624", money.FromFloat(10)).Return(domainwithdrawal.ErrDuplicateOrder)
+
+	err := service.Withdraw(ctx, 7, "2377225624", money.FromFloat(10))
+	assert.ErrorIs(t, err, domainwithdrawal.ErrDuplicateOrder)
+}
+
+func TestListWithdrawals(t *testing.T) {
+	ctx := context.Background()
+	repo := withdrawalmocks.NewRepository(t)
+	service := withdrawal.New(repo)
+
+	now := time.Now()
+	items := []*domainwithdrawal.Withdrawal{
+		{ID: 3, Order: "3", ProcessedAt: now},
+		{ID: 2, Order: "2", ProcessedAt: now.Add(-time.Minute)},
+	}
+
+	t.Run("page with next cursor", func(t *testing.T) {
+		repo.On("ListUserWithdrawals", ctx, 7, domainwithdrawal.ListFilter{Params: pagination.Params{Limit: 2}}).Return(items, nil).Once()
+
+		page, err := service.ListWithdrawals(ctx, 7, domainwithdrawal.ListFilter{Params: pagination.Params{Limit: 1}})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1)
+		require.NotNil(t, page.Next)
+		assert.Equal(t, domainwithdrawal.Cursor{At: now, ID: 3}, *page.Next)
+	})
+
+	t.Run("full list without parameters", func(t *testing.T) {
+		repo.On("GetUserWithdrawals", ctx, 7).Return(items, nil).Once()
+
+		page, err := service.ListWithdrawals(ctx, 7, domainwithdrawal.ListFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, items, page.Items)
+		assert.Nil(t, page.Next)
+	})
+}
@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
+)
+
+// OrderProcessor — источник заказов и обработчик одного заказа (usecase/order.Service)
+type OrderProcessor interface {
+	ClaimOrders(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error)
+	ExtendLease(ctx context.Context, o *order.Order, lease time.Duration) error
+	ProcessOrder(ctx context.Context, o *order.Order) error
+	ReleaseOrder(ctx context.Context, o *order.Order) error
+}
+
+type Config struct {
+	InstanceID   string        // идентификатор экземпляра, за которым закрепляются заказы
+	Workers      int           // количество параллельных обработчиков
+	BatchSize    int           // максимум заказов в очереди и в работе одновременно
+	PollInterval time.Duration // как часто дозаполнять очередь
+	JobTimeout   time.Duration // таймаут обработки одного заказа
+	Lease        time.Duration // аренда заказа; продлевается, когда воркер берёт заказ из очереди
+}
+
+// AccrualPool опрашивает систему начислений пулом воркеров.
+// Диспетчер периодически дозаполняет очередь заказами, захваченными в аренду для этого
+// экземпляра, поэтому несколько реплик могут разбирать одну очередь без дублей.
+type AccrualPool struct {
+	processor OrderProcessor
+	cfg       Config
+
+	mu          sync.Mutex
+	inFlight    map[string]struct{}
+	pausedUntil time.Time // пауза после 429 от системы начислений
+}
+
+func NewAccrualPool(processor OrderProcessor, cfg Config) *AccrualPool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = cfg.Workers
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.JobTimeout <= 0 {
+		cfg.JobTimeout = 10 * time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = DefaultInstanceID()
+	}
+
+	return &AccrualPool{
+		processor: processor,
+		cfg:       cfg,
+		inFlight:  make(map[string]struct{}),
+	}
+}
+
+// Run работает до отмены ctx. Начатые заказы обрабатываются до конца,
+// заказы, оставшиеся в очереди, возвращаются в БД нетронутыми.
+func (p *AccrualPool) Run(ctx context.Context) {
+	jobs := make(chan *order.Order, p.cfg.BatchSize)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx, jobs)
+		}()
+	}
+
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	log.Printf("[ACCRUAL WORKER] %s started %d workers, batch size %d", p.cfg.InstanceID, p.cfg.Workers, p.cfg.BatchSize)
+
+	p.dispatch(ctx, jobs)
+	for {
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			log.Printf("[ACCRUAL WORKER] stopped")
+			return
+		case <-ticker.C:
+			p.dispatch(ctx, jobs)
+		}
+	}
+}
+
+func (p *AccrualPool) dispatch(ctx context.Context, jobs chan<- *order.Order) {
+	if p.free() == 0 || p.pauseRemaining() > 0 {
+		return
+	}
+
+	orders, err := p.processor.ClaimOrders(ctx, p.cfg.InstanceID, p.free(), p.cfg.Lease)
+	if err != nil {
+		log.Printf("[ACCRUAL WORKER] failed to claim orders for processing: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, o := range orders {
+		if _, busy := p.inFlight[o.Number]; busy {
+			continue
+		}
+		p.inFlight[o.Number] = struct{}{}
+		// Захвачено не больше свободных мест, ёмкость очереди равна BatchSize — отправка не блокируется
+		jobs <- o
+	}
+}
+
+func (p *AccrualPool) work(ctx context.Context, jobs <-chan *order.Order) {
+	for o := range jobs {
+		if p.waitPause(ctx) {
+			p.process(ctx, o)
+		} else {
+			p.release(o)
+		}
+		p.done(o.Number)
+	}
+}
+
+// waitPause ждёт окончания паузы после 429. Возвращает false, если ctx отменён.
+func (p *AccrualPool) waitPause(ctx context.Context) bool {
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+		wait := p.pauseRemaining()
+		if wait <= 0 {
+			return true
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *AccrualPool) process(ctx context.Context, o *order.Order) {
+	// Отмена ctx не прерывает начатый заказ: он завершается в пределах JobTimeout
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.cfg.JobTimeout)
+	defer cancel()
+
+	// Заказ мог ждать в очереди и в паузе после 429 дольше аренды и уже уйти другому
+	// экземпляру: тогда повторно его не опрашиваем
+	if err := p.processor.ExtendLease(jobCtx, o, p.cfg.Lease); err != nil {
+		if errors.Is(err, order.ErrLeaseLost) {
+			log.Printf("[ACCRUAL WORKER] order %s: lease lost while queued, skipping", o.Number)
+			return
+		}
+		log.Printf("[ACCRUAL WORKER] failed to extend lease of order %s: %v", o.Number, err)
+		p.release(o)
+		return
+	}
+
+	err := p.processor.ProcessOrder(jobCtx, o)
+	if retryAfter, ok := loyalty.AsRateLimit(err); ok {
+		p.pause(retryAfter)
+		p.release(o)
+		return
+	}
+	if err != nil {
+		log.Printf("[ACCRUAL WORKER] order %s: %v", o.Number, err)
+	}
+}
+
+// release возвращает необработанный заказ в общую очередь, не дожидаясь истечения аренды
+func (p *AccrualPool) release(o *order.Order) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.JobTimeout)
+	defer cancel()
+
+	if err := p.processor.ReleaseOrder(ctx, o); err != nil {
+		log.Printf("[ACCRUAL WORKER] failed to release order %s: %v", o.Number, err)
+	}
+}
+
+func (p *AccrualPool) pause(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(p.pausedUntil) {
+		p.pausedUntil = until
+		log.Printf("[ACCRUAL WORKER] accrual system rate limit, pausing for %s", d)
+	}
+}
+
+func (p *AccrualPool) pauseRemaining() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Until(p.pausedUntil)
+}
+
+func (p *AccrualPool) free() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cfg.BatchSize - len(p.inFlight)
+}
+
+func (p *AccrualPool) done(number string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.inFlight, number)
+}
+
+// DefaultInstanceID строит идентификатор экземпляра из имени хоста и PID
+func DefaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "gophermart"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
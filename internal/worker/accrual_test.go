@@ -0,0 +1,237 @@
+package worker_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
+	"github.com/GarikMirzoyan/gophermart/internal/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProcessor захватывает незавершённые заказы так же, как ClaimOrdersForProcessing
+type fakeProcessor struct {
+	mu        sync.Mutex
+	pending   map[string]bool
+	claimed   map[string]string
+	processed map[string]int
+	delay     time.Duration
+
+	running    atomic.Int32
+	maxRunning atomic.Int32
+}
+
+func newFakeProcessor(n int, delay time.Duration) *fakeProcessor {
+	p := &fakeProcessor{
+		pending:   make(map[string]bool),
+		claimed:   make(map[string]string),
+		processed: make(map[string]int),
+		delay:     delay,
+	}
+	for i := 0; i < n; i++ {
+		p.pending[fmt.Sprintf("order-%d", i)] = true
+	}
+	return p
+}
+
+func (p *fakeProcessor) ClaimOrders(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var orders []*order.Order
+	for number := range p.pending {
+		if len(orders) == limit {
+			break
+		}
+		if p.claimed[number] != "" {
+			continue
+		}
+		p.claimed[number] = owner
+		orders = append(orders, &order.Order{Number: number, ClaimedBy: owner})
+	}
+	return orders, nil
+}
+
+func (p *fakeProcessor) ExtendLease(ctx context.Context, o *order.Order, lease time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.claimed[o.Number] != o.ClaimedBy {
+		return order.ErrLeaseLost
+	}
+	return nil
+}
+
+func (p *fakeProcessor) ReleaseOrder(ctx context.Context, o *order.Order) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.claimed[o.Number] == o.ClaimedBy {
+		delete(p.claimed, o.Number)
+	}
+	return nil
+}
+
+func (p *fakeProcessor) ProcessOrder(ctx context.Context, o *order.Order) error {
+	running := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		max := p.maxRunning.Load()
+		if running <= max || p.maxRunning.CompareAndSwap(max, running) {
+			break
+		}
+	}
+
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, o.Number)
+	delete(p.claimed, o.Number)
+	p.processed[o.Number]++
+	return nil
+}
+
+func (p *fakeProcessor) remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending)
+}
+
+func TestAccrualPool_ProcessesBacklogConcurrently(t *testing.T) {
+	processor := newFakeProcessor(50, 10*time.Millisecond)
+	pool := worker.NewAccrualPool(processor, worker.Config{
+		Workers:      5,
+		BatchSize:    10,
+		PollInterval: 5 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return processor.remaining() == 0 }, 2*time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Len(t, processor.processed, 50)
+	for number, n := range processor.processed {
+		assert.Equal(t, 1, n, "order %s processed more than once", number)
+	}
+	assert.Equal(t, int32(5), processor.maxRunning.Load())
+}
+
+func TestAccrualPool_StopsOnCancel(t *testing.T) {
+	processor := newFakeProcessor(100, 20*time.Millisecond)
+	pool := worker.NewAccrualPool(processor, worker.Config{
+		Workers:      2,
+		BatchSize:    20,
+		PollInterval: time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pool did not stop after cancel")
+	}
+	assert.Positive(t, processor.remaining(), "queued orders must not be drained after cancel")
+}
+
+// rateLimitedProcessor отвечает 429 на первый заказ и фиксирует время последующих вызовов
+type rateLimitedProcessor struct {
+	*fakeProcessor
+	limited atomic.Bool
+	calls   atomic.Int32
+	resumed atomic.Int64
+}
+
+func (p *rateLimitedProcessor) ProcessOrder(ctx context.Context, o *order.Order) error {
+	p.calls.Add(1)
+	if p.limited.CompareAndSwap(false, true) {
+		return &loyalty.RateLimitError{RetryAfter: 100 * time.Millisecond}
+	}
+	p.resumed.CompareAndSwap(0, time.Now().UnixNano())
+	return p.fakeProcessor.ProcessOrder(ctx, o)
+}
+
+func TestAccrualPool_PausesOnRateLimit(t *testing.T) {
+	processor := &rateLimitedProcessor{fakeProcessor: newFakeProcessor(10, 0)}
+	pool := worker.NewAccrualPool(processor, worker.Config{
+		Workers:      1,
+		BatchSize:    1,
+		PollInterval: time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := time.Now()
+	go pool.Run(ctx)
+
+	assert.Eventually(t, func() bool { return processor.remaining() == 0 }, 2*time.Second, 5*time.Millisecond)
+	resumedAfter := time.Duration(processor.resumed.Load() - started.UnixNano())
+	assert.GreaterOrEqual(t, resumedAfter, 100*time.Millisecond)
+	// Один вызов получил 429, остальные пошли только после паузы
+	assert.Equal(t, int32(11), processor.calls.Load())
+}
+
+// stolenLeaseProcessor отдаёт заказы другому экземпляру, пока они ждут в очереди
+type stolenLeaseProcessor struct {
+	*fakeProcessor
+	calls atomic.Int32
+}
+
+func (p *stolenLeaseProcessor) ClaimOrders(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error) {
+	orders, err := p.fakeProcessor.ClaimOrders(ctx, owner, limit, lease)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, o := range orders {
+		p.claimed[o.Number] = "replica-b"
+	}
+	return orders, err
+}
+
+func (p *stolenLeaseProcessor) ProcessOrder(ctx context.Context, o *order.Order) error {
+	p.calls.Add(1)
+	return p.fakeProcessor.ProcessOrder(ctx, o)
+}
+
+func TestAccrualPool_SkipsOrdersWithLostLease(t *testing.T) {
+	processor := &stolenLeaseProcessor{fakeProcessor: newFakeProcessor(10, 0)}
+	pool := worker.NewAccrualPool(processor, worker.Config{
+		Workers:      2,
+		BatchSize:    10,
+		PollInterval: time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Zero(t, processor.calls.Load(), "orders claimed by another instance must not be polled")
+	assert.Equal(t, 10, processor.remaining())
+}
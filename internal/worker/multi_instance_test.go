@@ -0,0 +1,256 @@
+package worker_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GarikMirzoyan/gophermart/internal/domain/money"
+	"github.com/GarikMirzoyan/gophermart/internal/domain/order"
+	"github.com/GarikMirzoyan/gophermart/internal/loyalty"
+	orderUC "github.com/GarikMirzoyan/gophermart/internal/usecase/order"
+	"github.com/GarikMirzoyan/gophermart/internal/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+// memOrderRepo — общая для двух экземпляров «таблица» orders с семантикой аренды из OrderPG.
+// Сами условия запросов OrderPG проверяются в storage: TestClaimOrdersForProcessing
+// и TestWorkerWritesFencedByLeaseOwner
+type memOrderRepo struct {
+	mu      sync.Mutex
+	orders  map[string]*memOrder
+	credits map[string]int
+}
+
+type memOrder struct {
+	order.Order
+	leaseUntil time.Time
+}
+
+func newMemOrderRepo(n int) *memOrderRepo {
+	r := &memOrderRepo{orders: make(map[string]*memOrder), credits: make(map[string]int)}
+	for i := 0; i < n; i++ {
+		number := fmt.Sprintf("%d", 1000+i)
+		r.orders[number] = &memOrder{Order: order.Order{Number: number, UserID: 1, Status: order.StatusNew}}
+	}
+	return r
+}
+
+func (r *memOrderRepo) AddOrder(ctx context.Context, o *order.Order) error { return nil }
+
+func (r *memOrderRepo) GetOrdersByUser(ctx context.Context, userID int) ([]*order.Order, error) {
+	return nil, nil
+}
+
+func (r *memOrderRepo) ListOrdersByUser(ctx context.Context, userID int, filter order.ListFilter) ([]*order.Order, error) {
+	return nil, nil
+}
+
+func (r *memOrderRepo) GetOrderOwner(ctx context.Context, number string) (int, error) { return 0, nil }
+
+func (r *memOrderRepo) AddOrders(ctx context.Context, userID int, numbers []string, uploadedAt time.Time) (*order.BatchInsert, error) {
+	return nil, nil
+}
+
+func (r *memOrderRepo) GetOrder(ctx context.Context, number string) (*order.Order, error) {
+	return nil, order.ErrOrderNotFound
+}
+
+func (r *memOrderRepo) GetOrderEvents(ctx context.Context, number string) ([]*order.Event, error) {
+	return nil, nil
+}
+
+func (r *memOrderRepo) CreditAccrual(ctx context.Context, number string, userID int, owner string, accrual money.Amount) (*order.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.orders[number]
+	if o.ClaimedBy != owner || (o.Status != order.StatusNew && o.Status != order.StatusProcessing) {
+		return nil, order.ErrAlreadyProcessed
+	}
+	o.Status = order.StatusProcessed
+	o.Accrual = &accrual
+	o.ClaimedBy, o.leaseUntil = "", time.Time{}
+	r.credits[number]++
+	return nil, nil
+}
+
+func (r *memOrderRepo) UpdateStatus(ctx context.Context, number string, owner string, status string) (*order.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.orders[number]
+	if o.ClaimedBy != owner {
+		return nil, nil
+	}
+	o.Status = order.Status(status)
+	o.ClaimedBy, o.leaseUntil = "", time.Time{}
+	return nil, nil
+}
+
+func (r *memOrderRepo) ScheduleRetry(ctx context.Context, number string, owner string, status string, next time.Time) (*order.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.orders[number]
+	if o.ClaimedBy != owner {
+		return nil, nil
+	}
+	o.Status = order.Status(status)
+	o.Attempts++
+	o.NextAttemptAt = next
+	o.ClaimedBy, o.leaseUntil = "", time.Time{}
+	return nil, nil
+}
+
+func (r *memOrderRepo) ListUserEvents(ctx context.Context, userID int, afterID int64, limit int) ([]*order.Event, error) {
+	return nil, nil
+}
+
+func (r *memOrderRepo) ListLateUserEvents(ctx context.Context, userID int, lastID int64, lookback time.Duration, limit int) ([]*order.Event, error) {
+	return nil, nil
+}
+
+func (r *memOrderRepo) ClaimOrdersForProcessing(ctx context.Context, owner string, limit int, lease time.Duration) ([]*order.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var claimed []*order.Order
+	for _, o := range r.orders {
+		if len(claimed) == limit {
+			break
+		}
+		pending := o.Status == order.StatusNew || o.Status == order.StatusProcessing
+		if !pending || o.NextAttemptAt.After(now) || o.leaseUntil.After(now) {
+			continue
+		}
+		o.ClaimedBy, o.leaseUntil = owner, now.Add(lease)
+		c := o.Order
+		claimed = append(claimed, &c)
+	}
+	return claimed, nil
+}
+
+func (r *memOrderRepo) ExtendLease(ctx context.Context, number string, owner string, lease time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.orders[number]
+	pending := o.Status == order.StatusNew || o.Status == order.StatusProcessing
+	if o.ClaimedBy != owner || !pending {
+		return order.ErrLeaseLost
+	}
+	o.leaseUntil = time.Now().Add(lease)
+	return nil
+}
+
+func (r *memOrderRepo) ReleaseOrder(ctx context.Context, number string, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o := r.orders[number]; o.ClaimedBy == owner {
+		o.ClaimedBy, o.leaseUntil = "", time.Time{}
+	}
+	return nil
+}
+
+func (r *memOrderRepo) ReturnOrder(ctx context.Context, ret *order.Return) (*order.ReturnResult, error) {
+	return nil, order.ErrOrderNotFound
+}
+
+func (r *memOrderRepo) pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, o := range r.orders {
+		if o.Status != order.StatusProcessed {
+			n++
+		}
+	}
+	return n
+}
+
+// countingAccrualClient сразу отвечает PROCESSED и считает запросы по каждому заказу
+type countingAccrualClient struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *countingAccrualClient) GetAccrual(ctx context.Context, number string) (*loyalty.OrderAccrual, error) {
+	time.Sleep(time.Millisecond)
+
+	c.mu.Lock()
+	c.calls[number]++
+	c.mu.Unlock()
+
+	accrual := money.FromFloat(10)
+	return &loyalty.OrderAccrual{Order: number, Status: loyalty.StatusProcessed, Accrual: &accrual}, nil
+}
+
+func TestAccrualPool_TwoInstancesShareQueue(t *testing.T) {
+	repo := newMemOrderRepo(200)
+	client := &countingAccrualClient{calls: make(map[string]int)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, instance := range []string{"replica-a", "replica-b"} {
+		// Каждая реплика со своим сервисом, общая только «база»
+		service := orderUC.New(repo, loyalty.New(client), order.Backoff{}, order.ClawbackNegativeBalance, nil)
+		pool := worker.NewAccrualPool(service, worker.Config{
+			InstanceID:   instance,
+			Workers:      4,
+			BatchSize:    16,
+			PollInterval: time.Millisecond,
+			Lease:        time.Minute,
+		})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.Run(ctx)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return repo.pending() == 0 }, 5*time.Second, 5*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	assert.Len(t, client.calls, 200)
+	for number, n := range client.calls {
+		assert.Equal(t, 1, n, "order %s requested from accrual system %d times", number, n)
+	}
+	for number, n := range repo.credits {
+		assert.Equal(t, 1, n, "order %s credited %d times", number, n)
+	}
+}
+
+// Экземпляр с истёкшей арендой не начисляет баллы по заказу, который другой экземпляр
+// уже забрал и закрыл как INVALID
+func TestProcessOrder_StaleLeaseDoesNotCredit(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemOrderRepo(1)
+	client := &countingAccrualClient{calls: make(map[string]int)}
+	service := orderUC.New(repo, loyalty.New(client), order.Backoff{}, order.ClawbackNegativeBalance, nil)
+
+	stale, err := repo.ClaimOrdersForProcessing(ctx, "replica-a", 1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, stale, 1)
+
+	// Аренда replica-a истекла, заказ забрала replica-b и закрыла его
+	claimed, err := repo.ClaimOrdersForProcessing(ctx, "replica-b", 1, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 1)
+	_, err = repo.UpdateStatus(ctx, claimed[0].Number, "replica-b", string(order.StatusInvalid))
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.ProcessOrder(ctx, stale[0]))
+
+	o := repo.orders[stale[0].Number]
+	assert.Equal(t, order.StatusInvalid, o.Status)
+	assert.Nil(t, o.Accrual)
+	assert.Zero(t, repo.credits[o.Number])
+}
@@ -0,0 +1,100 @@
+-- +goose Up
+CREATE TABLE ledger_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    reference TEXT,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Одно начисление на заказ и одно списание на номер заказа
+CREATE UNIQUE INDEX ux_ledger_transactions_kind_reference ON ledger_transactions(kind, reference) WHERE reference IS NOT NULL;
+CREATE INDEX idx_ledger_transactions_user_id_created_at ON ledger_transactions(user_id, created_at);
+
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+    account VARCHAR(64) NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_entries_account ON ledger_entries(account);
+CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+
+-- +goose StatementBegin
+CREATE FUNCTION ledger_forbid_mutation() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger records are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER ledger_transactions_immutable BEFORE UPDATE OR DELETE ON ledger_transactions
+    FOR EACH ROW EXECUTE FUNCTION ledger_forbid_mutation();
+CREATE TRIGGER ledger_entries_immutable BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_forbid_mutation();
+
+-- Перенос истории: начисления по обработанным заказам
+INSERT INTO ledger_transactions (kind, user_id, reference, description, created_at)
+SELECT 'ACCRUAL', user_id, number, 'migrated', uploaded_at
+FROM orders
+WHERE status = 'PROCESSED' AND accrual > 0;
+
+-- Перенос истории: списания
+INSERT INTO ledger_transactions (kind, user_id, reference, description, created_at)
+SELECT 'WITHDRAWAL', user_id, order_number, 'migrated', processed_at
+FROM withdrawals
+WHERE sum > 0;
+
+INSERT INTO ledger_entries (transaction_id, account, amount, created_at)
+SELECT t.id, 'system:accrual', -o.accrual::NUMERIC(18, 2), t.created_at
+FROM ledger_transactions t JOIN orders o ON o.number = t.reference
+WHERE t.kind = 'ACCRUAL'
+UNION ALL
+SELECT t.id, 'user:' || t.user_id, o.accrual::NUMERIC(18, 2), t.created_at
+FROM ledger_transactions t JOIN orders o ON o.number = t.reference
+WHERE t.kind = 'ACCRUAL';
+
+INSERT INTO ledger_entries (transaction_id, account, amount, created_at)
+SELECT t.id, 'user:' || t.user_id, -w.sum, t.created_at
+FROM ledger_transactions t JOIN withdrawals w ON w.order_number = t.reference
+WHERE t.kind = 'WITHDRAWAL'
+UNION ALL
+SELECT t.id, 'system:withdrawal', w.sum, t.created_at
+FROM ledger_transactions t JOIN withdrawals w ON w.order_number = t.reference
+WHERE t.kind = 'WITHDRAWAL';
+
+-- Остаток, который не объясняется заказами и списаниями, фиксируем корректировкой
+INSERT INTO ledger_transactions (kind, user_id, description)
+SELECT 'ADJUSTMENT', b.user_id, 'opening balance'
+FROM user_balances b
+LEFT JOIN (
+    SELECT SUBSTRING(e.account FROM 6)::INTEGER AS user_id, SUM(e.amount) AS amount
+    FROM ledger_entries e
+    WHERE e.account LIKE 'user:%'
+    GROUP BY e.account
+) l ON l.user_id = b.user_id
+WHERE ROUND(b.current_balance::NUMERIC, 2) <> COALESCE(l.amount, 0);
+
+INSERT INTO ledger_entries (transaction_id, account, amount)
+SELECT t.id, a.account, a.amount
+FROM ledger_transactions t
+JOIN user_balances b ON b.user_id = t.user_id
+LEFT JOIN (
+    SELECT e.account, SUM(e.amount) AS amount
+    FROM ledger_entries e
+    WHERE e.account LIKE 'user:%'
+    GROUP BY e.account
+) l ON l.account = 'user:' || t.user_id
+CROSS JOIN LATERAL (VALUES
+    ('system:adjustment', -(ROUND(b.current_balance::NUMERIC, 2) - COALESCE(l.amount, 0))),
+    ('user:' || t.user_id, ROUND(b.current_balance::NUMERIC, 2) - COALESCE(l.amount, 0))
+) AS a(account, amount)
+WHERE t.kind = 'ADJUSTMENT' AND t.description = 'opening balance';
+
+-- +goose Down
+DROP TABLE ledger_entries;
+DROP TABLE ledger_transactions;
+DROP FUNCTION ledger_forbid_mutation();
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE orders ALTER COLUMN accrual TYPE NUMERIC(18, 2) USING ROUND(accrual::NUMERIC, 2);
+ALTER TABLE user_balances
+    ALTER COLUMN current_balance TYPE NUMERIC(18, 2) USING ROUND(current_balance::NUMERIC, 2),
+    ALTER COLUMN total_withdrawn TYPE NUMERIC(18, 2) USING ROUND(total_withdrawn::NUMERIC, 2);
+
+-- +goose Down
+ALTER TABLE orders ALTER COLUMN accrual TYPE DOUBLE PRECISION;
+ALTER TABLE user_balances
+    ALTER COLUMN current_balance TYPE DOUBLE PRECISION,
+    ALTER COLUMN total_withdrawn TYPE DOUBLE PRECISION;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE orders
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+-- Заказы, застрявшие в статусе REGISTERED, возвращаем в очередь
+UPDATE orders SET status = 'PROCESSING' WHERE status = 'REGISTERED';
+
+CREATE INDEX idx_orders_pending_next_attempt_at ON orders(next_attempt_at) WHERE status IN ('NEW', 'PROCESSING');
+
+-- +goose Down
+DROP INDEX idx_orders_pending_next_attempt_at;
+ALTER TABLE orders
+    DROP COLUMN attempts,
+    DROP COLUMN next_attempt_at;
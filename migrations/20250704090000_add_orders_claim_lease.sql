@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE orders
+    ADD COLUMN claimed_by TEXT,
+    ADD COLUMN lease_until TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE orders
+    DROP COLUMN claimed_by,
+    DROP COLUMN lease_until;
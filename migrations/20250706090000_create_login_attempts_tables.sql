@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
+
+CREATE TABLE login_lockouts (
+    id BIGSERIAL PRIMARY KEY,
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX login_lockouts_key_idx ON login_lockouts (key, created_at);
+
+-- +goose Down
+DROP TABLE login_lockouts;
+DROP TABLE login_attempts;
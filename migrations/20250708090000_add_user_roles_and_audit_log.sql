@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+    ADD COLUMN blocked_at TIMESTAMPTZ;
+
+CREATE TABLE admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES users(id),
+    action TEXT NOT NULL,
+    target_user_id INTEGER REFERENCES users(id),
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX admin_audit_log_target_idx ON admin_audit_log (target_user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE admin_audit_log;
+
+ALTER TABLE users
+    DROP COLUMN role,
+    DROP COLUMN blocked_at;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Оператор, проводивший ручную корректировку баланса
+ALTER TABLE ledger_transactions ADD COLUMN operator_id INTEGER REFERENCES users(id);
+
+CREATE INDEX idx_ledger_transactions_operator_id ON ledger_transactions(operator_id) WHERE operator_id IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE ledger_transactions DROP COLUMN operator_id;
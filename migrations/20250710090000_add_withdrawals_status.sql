@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE withdrawals
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'COMPLETED' CHECK (status IN ('COMPLETED', 'REVERSED')),
+    ADD COLUMN reversed_at TIMESTAMPTZ,
+    ADD COLUMN reversal_reason TEXT;
+
+-- +goose Down
+ALTER TABLE withdrawals
+    DROP COLUMN status,
+    DROP COLUMN reversed_at,
+    DROP COLUMN reversal_reason;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE order_events (
+    id BIGSERIAL PRIMARY KEY,
+    order_number VARCHAR(255) NOT NULL REFERENCES orders(number),
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    source TEXT NOT NULL,
+    actor_id INTEGER REFERENCES users(id),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_events_order_number ON order_events(order_number, created_at);
+
+-- Недостача при списании начислений по возвращённым заказам
+CREATE TABLE user_debts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    order_number VARCHAR(255) NOT NULL UNIQUE REFERENCES orders(number),
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    outstanding NUMERIC(18, 2) NOT NULL CHECK (outstanding >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_debts_outstanding ON user_debts(user_id, created_at) WHERE outstanding > 0;
+
+-- +goose Down
+DROP TABLE user_debts;
+DROP TABLE order_events;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Постраничная выдача заказов пользователя по (uploaded_at, id)
+CREATE INDEX idx_orders_user_id_uploaded_at_id ON orders(user_id, uploaded_at, id);
+
+-- +goose Down
+DROP INDEX idx_orders_user_id_uploaded_at_id;
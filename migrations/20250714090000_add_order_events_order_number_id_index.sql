@@ -0,0 +1,6 @@
+-- +goose Up
+-- Дочитывание событий пользователя по id при переподключении к потоку
+CREATE INDEX idx_order_events_order_number_id ON order_events(order_number, id);
+
+-- +goose Down
+DROP INDEX idx_order_events_order_number_id;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Периодическое удаление счётчиков с закончившимся окном
+CREATE INDEX login_attempts_window_start_idx ON login_attempts (window_start);
+
+-- +goose Down
+DROP INDEX login_attempts_window_start_idx;